@@ -0,0 +1,75 @@
+package boundary
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Boundary conditions of the bounded grids: what a cell next to an edge sees
+// beyond it. Every model used to wrap around (torus), the other modes stop the
+// patterns at the edges instead of bringing them back on the other side.
+
+type Mode int
+
+const (
+	Torus   Mode = iota // the opposite edge, the grid wraps around
+	Dead                // dead cells (zero values)
+	Reflect             // the grid mirrored on its edge, the edge cell included
+	Fixed               // cells of a fixed value, see Boundary.Value
+)
+
+// Names are the names of the modes, by Mode.
+var Names = []string{"torus", "dead", "reflect", "fixed"}
+
+func (m Mode) String() string {
+	if m < 0 || int(m) >= len(Names) {
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+	return Names[m]
+}
+
+// Boundary is a mode and, for Fixed, the value of the cells beyond the edges.
+type Boundary struct {
+	Mode  Mode
+	Value float64 // in [0,1], game_of_life cells are alive from 0.5
+}
+
+// Parse returns the boundary called name, value is only used by fixed.
+func Parse(name string, value float64) (Boundary, error) {
+	for m, n := range Names {
+		if n == strings.ToLower(strings.TrimSpace(name)) {
+			if value < 0 || value > 1 {
+				return Boundary{}, fmt.Errorf("boundary value %g is not in [0,1]", value)
+			}
+			return Boundary{Mode: Mode(m), Value: value}, nil
+		}
+	}
+	return Boundary{}, fmt.Errorf("unknown boundary %q (available: %v)", name, Names)
+}
+
+func (b Boundary) String() string {
+	if b.Mode == Fixed {
+		return fmt.Sprintf("fixed %g", b.Value)
+	}
+	return b.Mode.String()
+}
+
+// Index returns the cell of a line of n cells seen at i, which can be out of the
+// line, and false when it is beyond a dead or fixed edge.
+func (b Boundary) Index(i, n int) (int, bool) {
+	if i >= 0 && i < n {
+		return i, true
+	}
+	switch b.Mode {
+	case Torus:
+		return (i%n + n) % n, true
+	case Reflect:
+		// the line and its mirror repeat every 2n cells: 0 .. n-1, n-1 .. 0
+		i = (i%(2*n) + 2*n) % (2 * n)
+		if i >= n {
+			i = 2*n - 1 - i
+		}
+		return i, true
+	}
+	return 0, false
+}
@@ -0,0 +1,59 @@
+package boundary
+
+import "testing"
+
+func TestIndex(t *testing.T) {
+	const n = 5
+	tests := []struct {
+		mode   Mode
+		i      int
+		want   int
+		inside bool
+	}{
+		{Torus, 0, 0, true},
+		{Torus, 4, 4, true},
+		{Torus, -1, 4, true},
+		{Torus, n, 0, true},
+		{Torus, 2 * n, 0, true},
+		{Torus, -n - 1, 4, true},
+		{Reflect, -1, 0, true},
+		{Reflect, -2, 1, true},
+		{Reflect, n, 4, true},
+		{Reflect, n + 1, 3, true},
+		{Reflect, 2 * n, 0, true},
+		{Reflect, 2*n - 1, 0, true},
+		{Reflect, -2 * n, 0, true},
+		{Dead, 2, 2, true},
+		{Dead, -1, 0, false},
+		{Dead, n, 0, false},
+		{Dead, 2 * n, 0, false},
+		{Fixed, -1, 0, false},
+		{Fixed, n, 0, false},
+	}
+	for _, test := range tests {
+		got, inside := Boundary{Mode: test.mode}.Index(test.i, n)
+		if inside != test.inside || (inside && got != test.want) {
+			t.Errorf("%s.Index(%d, %d) = %d, %v, want %d, %v", test.mode, test.i, n, got, inside, test.want, test.inside)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for m, name := range Names {
+		b, err := Parse(name, 0.5)
+		if err != nil || b.Mode != Mode(m) || b.Value != 0.5 {
+			t.Errorf("Parse(%q) = %v, %v", name, b, err)
+		}
+	}
+	if b, err := Parse(" Dead ", 0); err != nil || b.Mode != Dead {
+		t.Errorf("Parse(\" Dead \") = %v, %v", b, err)
+	}
+	for _, test := range []struct {
+		name  string
+		value float64
+	}{{"wrap", 0}, {"", 0}, {"fixed", 1.5}, {"fixed", -0.1}} {
+		if _, err := Parse(test.name, test.value); err == nil {
+			t.Errorf("Parse(%q, %g) succeeded, want an error", test.name, test.value)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"main/model"
+	"main/smoothlife3d"
+)
+
+const benchUsage = `usage: smoothlife bench [flags]
+
+Measures the duration of the steps of a random grid, without display, and prints
+the mean duration of a step (and of each of its phases for smoothlife3d).
+
+`
+
+func cmdBench(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	name := fs.String("model", "smoothlife3d", "benchmarked model: "+strings.Join(model.Names(), ", "))
+	engine := fs.String("engine", model.DefaultLifeEngine, "implementation of game_of_life: "+strings.Join(model.LifeEngineNames(), ", "))
+	lifeStep := fs.Int("life-step", 0, "game_of_life advances 2^k generations per step")
+	width := fs.Int("w", 512, "grid width (power of two for smoothlife3d)")
+	height := fs.Int("h", 512, "grid height (power of two for smoothlife3d)")
+	radius := fs.Float64("ra", 11, "radius of the outer kernel, for the models having one")
+	threshold := fs.Float64("t", 0.5, "share of the random cells")
+	steps := fs.Int("steps", 20, "number of measured steps")
+	warmup := fs.Int("warmup", 2, "steps run before measuring")
+	seed := fs.Int64("seed", 1, "seed of the random grid")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), benchUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return usageError("bench", err)
+	}
+	if *steps <= 0 {
+		return usageError("bench", errors.New("-steps must be positive"))
+	}
+	m, err := model.New(*name)
+	if err != nil {
+		return usageError("bench", err)
+	}
+	if life, ok := m.(*model.GameOfLife); ok {
+		if err := life.SetEngine(*engine); err != nil {
+			return usageError("bench", err)
+		}
+		if err := life.SetStep(*lifeStep); err != nil {
+			return usageError("bench", err)
+		}
+		*name += " (" + *engine + ")"
+	}
+	if tunable, ok := m.(model.Tunable); ok {
+		if err := tunable.SetParam("ra", *radius); err != nil {
+			return usageError("bench", err)
+		}
+	}
+	if err := m.Init(model.Options{Width: *width, Height: *height, Seed: *seed, Threshold: *threshold}); err != nil {
+		return usageError("bench", err)
+	}
+	for i := 0; i < *warmup; i++ {
+		m.Step()
+	}
+	_, phases := m.(*model.SmoothLife3D)
+
+	var total smoothlife3d.Timings
+	start := time.Now()
+	for i := 0; i < *steps; i++ {
+		m.Step()
+		timings := smoothlife3d.LastTimings
+		total.Precomputation += timings.Precomputation
+		total.Convolutions += timings.Convolutions
+		total.NewState += timings.NewState
+		total.Copy += timings.Copy
+	}
+	elapsed := time.Since(start)
+
+	n := time.Duration(*steps)
+	fmt.Printf("%s %d x %d: %d steps in %v\n", *name, *width, *height, *steps, elapsed.Round(time.Millisecond))
+	fmt.Printf("  %.2f steps/s, %v per step\n", float64(*steps)/elapsed.Seconds(), (elapsed / n).Round(time.Microsecond))
+	if !phases {
+		return exitOK
+	}
+	fmt.Printf("  precomputation %v, convolutions %v, new state %v, copy %v\n",
+		(total.Precomputation / n).Round(time.Microsecond), (total.Convolutions / n).Round(time.Microsecond),
+		(total.NewState / n).Round(time.Microsecond), (total.Copy / n).Round(time.Microsecond))
+	return exitOK
+}
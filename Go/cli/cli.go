@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"main/config"
+	"main/game_of_life"
+	"main/image_utils"
+	"main/model"
+	"main/opengl_utils"
+	"main/renderer"
+	"main/smoothlife3d"
+)
+
+// Exit codes of the subcommands.
+const (
+	exitOK    = 0
+	exitError = 1 // the command failed
+	exitUsage = 2 // invalid arguments
+)
+
+type subcommand struct {
+	run     func(args []string) int
+	summary string
+}
+
+var subcommands = map[string]subcommand{
+	"run":     {cmdRun, "run a simulation and show it (window, terminal or images)"},
+	"render":  {cmdRender, "run a simulation without display and write its frames as images"},
+	"sweep":   {cmdSweep, "run a simulation for each value of a parameter and compare the results"},
+	"bench":   {cmdBench, "measure the time of the steps"},
+	"convert": {cmdConvert, "convert a state between snapshot, npy, image and pattern formats"},
+	"presets": {cmdPresets, "list the presets, or print one as a configuration file"},
+}
+
+func usage(w io.Writer) {
+	fmt.Fprint(w, "usage: smoothlife <command> [flags]\n\ncommands:\n")
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, subcommands[name].summary)
+	}
+	fmt.Fprint(w, "\nWithout a command the flags are the ones of run. \"smoothlife <command> -help\" shows the flags of a command.\n")
+}
+
+// The choices of the flags of config come from the packages using cgo.
+func init() {
+	config.Register(config.Registry{
+		Models:              model.Names(),
+		LifeEngines:         model.LifeEngineNames(),
+		UnboundedLifeEngine: model.IsUnboundedLifeEngine,
+		LifeStepLimit:       model.LifeStepLimit,
+		MaxLifeZoom:         model.MaxLifeZoom,
+		Rules:               smoothlife3d.RuleNames,
+		Fields:              smoothlife3d.FieldNames,
+		Generators:          smoothlife3d.InitNames(),
+		Renderers:           renderer.Names(),
+		Colormaps:           opengl_utils.ColormapNames(),
+	})
+}
+
+// Main runs the command of args, the arguments of the program without its name,
+// and returns its exit code.
+func Main(args []string) int {
+	return dispatch(args)
+}
+
+// dispatch runs the subcommand named by the first argument and returns its exit code.
+func dispatch(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return exitUsage
+	}
+	switch name := args[0]; {
+	case name == "help" || name == "-help" || name == "--help":
+		if len(args) > 1 {
+			return dispatch([]string{args[1], "-help"})
+		}
+		usage(os.Stdout)
+		return exitOK
+	case strings.HasPrefix(name, "-"):
+		return cmdRun(args) // the flags of before the subcommands still work
+	}
+	command, ok := subcommands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		usage(os.Stderr)
+		return exitUsage
+	}
+	return command.run(args[1:])
+}
+
+// isPowerOfTwo returns true if n is a power of two.
+func isPowerOfTwo(n int) bool {
+	return n > 0 && (n&(n-1)) == 0
+}
+
+// loadImage loads an image from path as float channels. The image is resampled to
+// width x height when they are given. With powerOfTwo, the sizes must be powers of two.
+func loadImage(path string, width, height int, powerOfTwo bool, opts image_utils.LoadOptions) (*image_utils.Fields, error) {
+	img, err := image_utils.Decode(path, opts.Frame)
+	if err != nil {
+		return nil, err
+	}
+	fields := image_utils.ToFields(img)
+
+	opts.Width, opts.Height = width, height
+	if opts.Width == 0 || opts.Height == 0 {
+		opts.Width, opts.Height = fields.Width, fields.Height
+		if powerOfTwo && (!isPowerOfTwo(fields.Width) || !isPowerOfTwo(fields.Height)) {
+			return nil, fmt.Errorf("image dimensions (%d x %d) are not powers of two, use -w and -h to resample it", fields.Width, fields.Height)
+		}
+	}
+	if powerOfTwo && (!isPowerOfTwo(opts.Width) || !isPowerOfTwo(opts.Height)) {
+		return nil, fmt.Errorf("provided dimensions (%d x %d) are not powers of two", opts.Width, opts.Height)
+	}
+	return image_utils.Convert(fields, opts)
+}
+
+// saveImage writes the grid of m as an image. The worlds of smoothlife3d do not go
+// through the 8 bits pixels so a 16 bits image keeps the precision of the floats.
+// The cells of game_of_life can also be saved as a pattern (.rle or .cells).
+func saveImage(m model.Model, path string, bits int) error {
+	if game_of_life.IsPatternFile(path) {
+		life, ok := m.(*model.GameOfLife)
+		if !ok {
+			return fmt.Errorf("%s: only game_of_life is saved as a pattern", path)
+		}
+		if universe := life.Universe(); universe != nil && strings.EqualFold(filepath.Ext(path), ".mc") {
+			// The quadtree of hashlife is written as it is, without listing its cells
+			return game_of_life.WriteMacrocellFile(path, &game_of_life.Macrocell{Rule: life.Rule(), Universe: universe})
+		}
+		return game_of_life.WritePattern(path, life.Pattern())
+	}
+	width, height := m.Size()
+	fields := &image_utils.Fields{Width: width, Height: height}
+	if _, ok := m.(*model.SmoothLife3D); ok {
+		fields.R, _ = smoothlife3d.Field("world1")
+		fields.G, _ = smoothlife3d.Field("world2")
+		fields.B, _ = smoothlife3d.Field("world3")
+		return image_utils.Save(path, fields, bits)
+	}
+	pixels := m.Render(nil)
+	fields.R, fields.G, fields.B = make([]float64, width*height), make([]float64, width*height), make([]float64, width*height)
+	for i := range fields.R {
+		fields.R[i] = float64(pixels[i*3]) / 255
+		fields.G[i] = float64(pixels[i*3+1]) / 255
+		fields.B[i] = float64(pixels[i*3+2]) / 255
+	}
+	return image_utils.Save(path, fields, bits)
+}
+
+// means returns the mean value of each channel of m, the share of the grid that is alive.
+func means(m model.Model) [3]float64 {
+	if _, ok := m.(*model.SmoothLife3D); ok {
+		return smoothlife3d.Means()
+	}
+	var sums [3]float64
+	pixels := m.Render(nil)
+	for i, v := range pixels {
+		sums[i%3] += float64(v) / 255
+	}
+	if len(pixels) > 0 {
+		for c := range sums {
+			sums[c] /= float64(len(pixels) / 3)
+		}
+	}
+	return sums
+}
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"main/config"
+	"main/model"
+	"main/smoothlife3d"
+)
+
+// quiet discards what the test writes to the standard error, the usage of the commands.
+func quiet(t *testing.T) {
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := os.Stderr
+	os.Stderr = null
+	t.Cleanup(func() {
+		os.Stderr = before
+		null.Close()
+	})
+}
+
+// stdout runs f and returns what it writes to the standard output.
+func stdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := os.Stdout
+	os.Stdout = w
+	written := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		written <- string(data)
+	}()
+	f()
+	os.Stdout = before
+	w.Close()
+	return <-written
+}
+
+func TestDispatch(t *testing.T) {
+	quiet(t)
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{nil, exitUsage},
+		{[]string{"frobnicate"}, exitUsage},
+		{[]string{"help"}, exitOK},
+		{[]string{"help", "render"}, exitOK},
+		{[]string{"-help"}, exitOK},
+		{[]string{"render", "-help"}, exitOK},
+		{[]string{"render", "-r", "-w", "64", "-h", "64"}, exitUsage}, // without -steps
+		{[]string{"sweep", "-r", "-steps", "1"}, exitUsage},           // without -values
+		{[]string{"run", "-r", "extra"}, exitUsage},
+		{[]string{"run", "-unknown-flag"}, exitUsage},
+		{[]string{"run", "-rules", "dx=0.1"}, exitUsage},
+		{[]string{"run", "-model", "game_of_life", "-life-step", "11"}, exitUsage},
+		{[]string{"presets"}, exitOK},
+	}
+	for _, test := range tests {
+		var got int
+		stdout(t, func() { got = dispatch(test.args) })
+		if got != test.want {
+			t.Errorf("dispatch(%q) = %d, want %d", test.args, got, test.want)
+		}
+	}
+}
+
+// TestLegacyFlags checks that the flags without a command are the ones of run, as
+// before the commands.
+func TestLegacyFlags(t *testing.T) {
+	quiet(t)
+	var code int
+	out := stdout(t, func() { code = dispatch([]string{"-r", "-w", "512", "-dump-config"}) })
+	if code != exitOK {
+		t.Fatalf("exit code %d", code)
+	}
+	var cfg config.Config
+	if err := json.Unmarshal([]byte(out), &cfg); err != nil {
+		t.Fatalf("invalid configuration %q: %v", out, err)
+	}
+	if !cfg.Init.Random || cfg.Grid.Width != 512 || cfg.Model != "smoothlife3d" {
+		t.Errorf("configuration %+v, want a random smoothlife3d grid of width 512", cfg)
+	}
+}
+
+// TestPrecedence checks that the flags override the file, which overrides the preset.
+func TestPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	file := `{"grid": {"width": 256, "height": 256}, "kernel": {"radius": 9}, "rules": {"dt": 0.2}}`
+	if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the terminal preset is a random 128 x 128 grid of radius 7 in the terminal
+	cfg, dump, err := parseRunFlags("run", runUsage, []string{"-preset", "terminal", "-config", path, "-w", "64", "-rules", "b1=0.3"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dump {
+		t.Errorf("dump without -dump-config")
+	}
+	tests := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"width (flag)", cfg.Grid.Width, 64},
+		{"height (file)", cfg.Grid.Height, 256},
+		{"radius (file)", cfg.Kernel.Radius, 9.0},
+		{"rules (file and flag)", cfg.Rules, map[string]float64{"dt": 0.2, "b1": 0.3}},
+		{"renderer (preset)", cfg.Renderer.Name, "terminal"},
+		{"random (preset)", cfg.Init.Random, true},
+		{"model (default)", cfg.Model, "smoothlife3d"},
+	}
+	for _, test := range tests {
+		if !reflect.DeepEqual(test.got, test.want) {
+			t.Errorf("%s: %v, want %v", test.name, test.got, test.want)
+		}
+	}
+
+	if _, _, err := parseRunFlags("run", runUsage, []string{"-help"}, nil); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("-help: error %v, want flag.ErrHelp", err)
+	}
+	if _, _, err := parseRunFlags("run", runUsage, []string{"-config", filepath.Join(t.TempDir(), "missing.json")}, nil); err == nil {
+		t.Errorf("missing configuration file: no error")
+	}
+}
+
+func TestPatternModel(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-pattern", "glider.rle"}, "game_of_life"},
+		{[]string{"-pattern", "glider.rle", "-model", "smoothlife"}, "smoothlife"},
+		{[]string{"-pattern", "glider.rle", "-model", "smoothlife3d"}, "smoothlife3d"},
+		{[]string{"-r"}, "smoothlife3d"},
+	}
+	for _, test := range tests {
+		cfg, _, err := parseRunFlags("run", runUsage, test.args, nil)
+		if err != nil {
+			t.Fatalf("%q: %v", test.args, err)
+		}
+		if cfg.Model != test.want {
+			t.Errorf("%q: model %s, want %s", test.args, cfg.Model, test.want)
+		}
+	}
+}
+
+func TestSeedFlag(t *testing.T) {
+	cfg, _, err := parseRunFlags("run", runUsage, []string{"-r", "-seed", "0"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Seed == nil || *cfg.Seed != 0 {
+		t.Errorf("-seed 0 gives the seed %v", cfg.Seed)
+	}
+	if cfg, _, _ = parseRunFlags("run", runUsage, []string{"-r"}, nil); cfg.Seed == nil {
+		t.Errorf("no seed picked without -seed")
+	}
+}
+
+// TestConfigDefaults checks that the defaults written in config, which does not
+// import the models, are the ones of the models.
+func TestConfigDefaults(t *testing.T) {
+	c := config.Default()
+	if c.Engine != model.DefaultLifeEngine {
+		t.Errorf("engine %q, want %q", c.Engine, model.DefaultLifeEngine)
+	}
+	if c.Kernel.InnerRatio != smoothlife3d.InnerRatio() {
+		t.Errorf("inner ratio %g, want %g", c.Kernel.InnerRatio, smoothlife3d.InnerRatio())
+	}
+	brush := smoothlife3d.DefaultBrush
+	if c.Brush.Hardness != brush.Hardness || c.Brush.Value != brush.Value || c.Brush.Flow != brush.Flow {
+		t.Errorf("brush %+v, want the values of %+v", c.Brush, brush)
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("the default configuration is invalid: %v", err)
+	}
+}
+
+// TestServerError checks that a server that can not listen stops the run with its
+// error, after the last state is saved.
+func TestServerError(t *testing.T) {
+	dir := t.TempDir()
+	save := filepath.Join(dir, "last.png")
+	cfg, _, err := parseRunFlags("render", renderUsage, []string{"-model", "game_of_life", "-r", "-w", "32", "-h", "32",
+		"-renderer", "image", "-render-dir", dir, "-render-every", "1000000", "-serve", "256.0.0.1:-1", "-save", save}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := simulate(cfg); err == nil || !strings.Contains(err.Error(), "HTTP server") {
+		t.Errorf("simulate: %v, want the error of the server", err)
+	}
+	if _, err := os.Stat(save); err != nil {
+		t.Errorf("last state not saved: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"main/game_of_life"
+	"main/image_utils"
+	"main/npy"
+)
+
+const convertUsage = `usage: smoothlife convert [flags] input output
+
+Converts a state between formats, chosen by the extensions:
+  .npz       snapshot (world1, world2 and world3, as written by the S key or -npz)
+  .npy       one array, of shape (height, width) in gray or (height, width, 3)
+  .png .jpg .gif .pgm .ppm
+                 image (16 bits for png and netpbm, see -bits)
+  .rle .cells .mc
+                 pattern of game_of_life, the cells at or above 0.5 of luminance are alive
+
+`
+
+func cmdConvert(args []string) int {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	bits := fs.Int("bits", 16, "bits per channel of the images written (8 or 16)")
+	fieldsFlag := fs.String("fields", "world1,world2,world3", "arrays of a .npz input used as red, green and blue (one for gray)")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), convertUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return usageError("convert", err)
+	}
+	if fs.NArg() != 2 {
+		return usageError("convert", fmt.Errorf("an input and an output are needed"))
+	}
+	names := strings.Split(*fieldsFlag, ",")
+	if len(names) != 1 && len(names) != 3 {
+		return usageError("convert", fmt.Errorf("-fields needs 1 or 3 names"))
+	}
+
+	state, err := readState(fs.Arg(0), names)
+	if err == nil {
+		err = writeState(fs.Arg(1), state, *bits)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "convert: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// readState reads the channels of a snapshot, an array or an image. Gray inputs
+// have the same slice in their three channels.
+func readState(path string, names []string) (*image_utils.Fields, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".npz":
+		arrays, err := npy.ReadNPZ(path)
+		if err != nil {
+			return nil, err
+		}
+		byName := make(map[string]npy.Array)
+		for _, a := range arrays {
+			byName[a.Name] = a
+		}
+		var channels [][]float64
+		var shape []int
+		for _, name := range names {
+			a, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("%s has no array %s", path, name)
+			}
+			if len(a.Shape) != 2 || (shape != nil && (a.Shape[0] != shape[0] || a.Shape[1] != shape[1])) {
+				return nil, fmt.Errorf("array %s has shape %v", name, a.Shape)
+			}
+			shape = a.Shape
+			channels = append(channels, a.Float64s())
+		}
+		if len(channels) == 1 {
+			channels = append(channels, channels[0], channels[0])
+		}
+		return &image_utils.Fields{Width: shape[1], Height: shape[0], R: channels[0], G: channels[1], B: channels[2]}, nil
+
+	case ".npy":
+		a, err := npy.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		values := a.Float64s()
+		switch {
+		case len(a.Shape) == 2:
+			return &image_utils.Fields{Width: a.Shape[1], Height: a.Shape[0], R: values, G: values, B: values}, nil
+		case len(a.Shape) == 3 && a.Shape[2] == 3:
+			f := &image_utils.Fields{Width: a.Shape[1], Height: a.Shape[0]}
+			for c, channel := range []*[]float64{&f.R, &f.G, &f.B} {
+				*channel = make([]float64, f.Width*f.Height)
+				for i := range *channel {
+					(*channel)[i] = values[i*3+c]
+				}
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("%s has shape %v, (height, width) or (height, width, 3) is needed", path, a.Shape)
+
+	case ".rle", ".cells", ".mc":
+		p, err := game_of_life.ReadPattern(path)
+		if err != nil {
+			return nil, err
+		}
+		values := game_of_life.CellValues(p.Cells, p.Width, p.Height)
+		return &image_utils.Fields{Width: p.Width, Height: p.Height, R: values, G: values, B: values}, nil
+	}
+
+	img, err := image_utils.Decode(path, 0)
+	if err != nil {
+		return nil, err
+	}
+	return image_utils.ToFields(img), nil
+}
+
+// writeState writes the channels as a snapshot, an array or an image.
+func writeState(path string, f *image_utils.Fields, bits int) error {
+	shape := []int{f.Height, f.Width}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".npz":
+		return npy.WriteNPZ(path, []npy.Array{
+			{Name: "world1", Data: f.R, Shape: shape},
+			{Name: "world2", Data: f.G, Shape: shape},
+			{Name: "world3", Data: f.B, Shape: shape},
+		})
+	case ".npy":
+		if &f.R[0] == &f.G[0] && &f.R[0] == &f.B[0] {
+			return npy.WriteFile(path, f.R, shape) // gray
+		}
+		values := make([]float64, len(f.R)*3)
+		for i := range f.R {
+			values[i*3], values[i*3+1], values[i*3+2] = f.R[i], f.G[i], f.B[i]
+		}
+		return npy.WriteFile(path, values, []int{f.Height, f.Width, 3})
+	case ".rle", ".cells", ".mc":
+		return game_of_life.WritePattern(path, game_of_life.LoadPattern(f.Luminance(), f.Width, f.Height, 0.5))
+	}
+	return image_utils.Save(path, f, bits)
+}
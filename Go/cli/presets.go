@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"main/config"
+)
+
+const presetsUsage = `usage: smoothlife presets [name]
+
+Lists the presets, or prints the configuration of the preset name. It can be
+saved, edited and given to -config, or used directly with run -preset name.
+
+`
+
+func cmdPresets(args []string) int {
+	fs := flag.NewFlagSet("presets", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), presetsUsage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return usageError("presets", err)
+	}
+
+	switch fs.NArg() {
+	case 0:
+		for _, name := range config.PresetNames() {
+			fmt.Printf("%-10s %s\n", name, config.Presets[name].Description)
+		}
+		return exitOK
+	case 1:
+		cfg := config.Default()
+		if err := config.ApplyPreset(fs.Arg(0), &cfg); err != nil {
+			return usageError("presets", err)
+		}
+		if err := cfg.Write(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "presets: %v\n", err)
+			return exitError
+		}
+		return exitOK
+	}
+	return usageError("presets", fmt.Errorf("too many arguments"))
+}
@@ -0,0 +1,811 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"main/commands"
+	"main/config"
+	"main/game_of_life"
+	"main/image_utils"
+	"main/model"
+	"main/npy"
+	"main/opengl_utils"
+	"main/pipeline"
+	"main/renderer"
+	"main/server"
+	"main/smoothlife3d"
+)
+
+// The run and render subcommands: a simulation described by a configuration,
+// shown by a renderer (a window by default for run, image files for render).
+
+const runUsage = `usage: smoothlife run [flags]
+
+Runs a simulation and shows it, by default in a window. The run is described by
+the flags, a preset (-preset) and a configuration file (-config), applied in the
+order preset, file, flags. Without any flag, "run" is implied:
+	smoothlife -r -w 512 -h 512
+
+`
+
+const renderUsage = `usage: smoothlife render -steps N [flags]
+
+Runs a simulation without display and writes its frames as images in -render-dir,
+every -render-every steps. It takes the flags of run.
+
+`
+
+// parseRunFlags parses the flags of run and render into a configuration, extra
+// defines the flags of other commands using a configuration (nil for none). It
+// returns flag.ErrHelp when the help was asked, and whether -dump-config was given.
+func parseRunFlags(name, usage string, args []string, extra func(fs *flag.FlagSet)) (config.Config, bool, error) {
+	cfg := config.Default()
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	preset := fs.String("preset", "", "start from a named configuration: "+strings.Join(config.PresetNames(), ", "))
+	configPath := fs.String("config", "", "JSON file describing the run, the flags given override its fields")
+	dumpConfig := fs.Bool("dump-config", false, "print the effective configuration as JSON and exit")
+	cfg.Flags(fs)
+	if extra != nil {
+		extra(fs)
+	}
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, false, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, false, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	// The flags are bound to the fields of cfg: they are parsed again over the preset and the file
+	if *preset != "" || *configPath != "" {
+		cfg = config.Default()
+		if *preset != "" {
+			if err := config.ApplyPreset(*preset, &cfg); err != nil {
+				return cfg, false, err
+			}
+		}
+		if *configPath != "" {
+			if err := config.Load(*configPath, &cfg); err != nil {
+				return cfg, false, err
+			}
+		}
+		fs.Parse(args)
+	}
+	// A pattern is a start grid of game_of_life unless another model is given
+	modelGiven := cfg.Model != config.Default().Model
+	fs.Visit(func(f *flag.Flag) { modelGiven = modelGiven || f.Name == "model" })
+	if cfg.Init.Pattern != "" && !modelGiven {
+		cfg.Model = "game_of_life"
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, false, err
+	}
+
+	// Every run has a seed, printed so that it can be replayed with -seed.
+	if cfg.Seed == nil {
+		seed := time.Now().UnixNano()
+		cfg.Seed = &seed
+	}
+	return cfg, *dumpConfig, nil
+}
+
+// usageError reports an error of the arguments and returns exitUsage, or exitOK for -help.
+func usageError(name string, err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+	return exitUsage
+}
+
+func cmdRun(args []string) int {
+	cfg, dump, err := parseRunFlags("run", runUsage, args, nil)
+	if err != nil {
+		return usageError("run", err)
+	}
+	return runConfig(cfg, dump)
+}
+
+func cmdRender(args []string) int {
+	cfg, dump, err := parseRunFlags("render", renderUsage, args, nil)
+	if err != nil {
+		return usageError("render", err)
+	}
+	if cfg.Output.Steps <= 0 {
+		return usageError("render", errors.New("-steps is needed, the run would never end"))
+	}
+	cfg.Renderer.Name = "image"
+	return runConfig(cfg, dump)
+}
+
+func runConfig(cfg config.Config, dump bool) int {
+	if dump {
+		if err := cfg.Write(os.Stdout); err != nil {
+			log.Printf("Error writing configuration: %v", err)
+			return exitError
+		}
+		return exitOK
+	}
+	if err := simulate(cfg); err != nil {
+		log.Printf("Error: %v", err)
+		return exitError
+	}
+	return exitOK
+}
+
+var errNoInit = errors.New("no initial condition: give an image (-i /path/to/image.png), a pattern (-pattern /path/to/pattern.rle), random mode (-r with -w (width) and -h (height), optionnaly -t (threshold value)), a generator (-init name with -w and -h, optionnaly -init-params), a -preset or a -config file")
+
+// setup returns the model of cfg with its rules and kernel radius, and the options
+// of its initial condition, used again on reset with another seed.
+func setup(cfg config.Config) (model.Model, model.Options, error) {
+	m, err := model.New(cfg.Model)
+	if err != nil {
+		return nil, model.Options{}, err
+	}
+	smoothlife3d.SetInnerRatio(cfg.Kernel.InnerRatio)
+	// The pattern file is read once, for its rule and its cells
+	var pattern *lifePattern
+	if cfg.Init.Image == "" && cfg.Init.Pattern != "" {
+		if pattern, err = readPattern(cfg); err != nil {
+			return nil, model.Options{}, fmt.Errorf("loading pattern: %w", err)
+		}
+	}
+	if life, ok := m.(*model.GameOfLife); ok {
+		if err := life.SetEngine(cfg.Engine); err != nil {
+			return nil, model.Options{}, err
+		}
+		if err := life.SetStep(cfg.LifeStep); err != nil {
+			return nil, model.Options{}, err
+		}
+		if err := life.SetZoom(cfg.LifeZoom); err != nil {
+			return nil, model.Options{}, err
+		}
+		if err := life.SetFollow(cfg.LifeFollow); err != nil {
+			return nil, model.Options{}, err
+		}
+	}
+	if ruled, ok := m.(model.Ruled); ok {
+		// The rule of the pattern file is used unless a rule is given
+		rule := cfg.Rule
+		if rule == "" && pattern != nil {
+			rule = pattern.rule
+		}
+		if rule == "" {
+			rule = game_of_life.Conway.String()
+		}
+		if err := ruled.SetRule(rule); err != nil {
+			return nil, model.Options{}, err
+		}
+	}
+	if bounded, ok := m.(model.Bounded); ok {
+		b, err := cfg.Boundary.Parse()
+		if err != nil {
+			return nil, model.Options{}, err
+		}
+		if err := bounded.SetBoundary(b); err != nil {
+			return nil, model.Options{}, err
+		}
+	}
+	if tunable, ok := m.(model.Tunable); ok {
+		// The rules and the radius given apply to the models having them, the others
+		// keep the defaults of the model
+		values := maps.Clone(cfg.Rules)
+		if cfg.Kernel.Radius > 0 {
+			if values == nil {
+				values = make(map[string]float64)
+			}
+			values["ra"] = cfg.Kernel.Radius
+		}
+		params := tunable.Params()
+		for name, value := range values {
+			if _, ok := params[name]; !ok {
+				continue
+			}
+			if err := tunable.SetParam(name, value); err != nil {
+				return nil, model.Options{}, err
+			}
+		}
+	}
+	opts := model.Options{Seed: *cfg.Seed, Threshold: model.DefaultThreshold(cfg.Model)}
+	if cfg.Init.Threshold != nil {
+		opts.Threshold = *cfg.Init.Threshold
+	}
+	opts.Width, opts.Height = cfg.Size()
+
+	switch {
+	case cfg.Init.Image != "":
+		// The image is only resampled when the grid size is asked explicitly.
+		targetWidth, targetHeight := 0, 0
+		if cfg.Grid.Width != 0 || cfg.Grid.Height != 0 {
+			targetWidth, targetHeight = cfg.Size()
+		}
+		loadOpts := image_utils.LoadOptions{
+			Resample:  cfg.Init.Resample,
+			Fit:       cfg.Init.Fit,
+			Grayscale: cfg.Init.Gray,
+			Invert:    cfg.Init.Invert,
+			Contrast:  cfg.Init.Contrast,
+			Gamma:     cfg.Init.Gamma,
+			Binarize:  cfg.Init.ImageThreshold >= 0,
+			Threshold: cfg.Init.ImageThreshold,
+			AlphaMask: cfg.Init.AlphaMask,
+			Frame:     cfg.Init.Frame,
+		}
+		_, fft := m.(*model.SmoothLife3D)
+		fields, err := loadImage(cfg.Init.Image, targetWidth, targetHeight, fft, loadOpts)
+		if err != nil {
+			return nil, model.Options{}, fmt.Errorf("loading image: %w", err)
+		}
+		opts.Image = fields
+		opts.Width, opts.Height = fields.Width, fields.Height
+
+	case cfg.Init.Pattern != "":
+		// The pattern is centred on the cells shown, more than the grid when hashlife zooms out
+		viewWidth, viewHeight := opts.Width, opts.Height
+		if life, ok := m.(*model.GameOfLife); ok {
+			viewWidth, viewHeight = viewWidth<<life.Zoom(), viewHeight<<life.Zoom()
+		}
+		cells, universe, err := placePattern(pattern, cfg.Init.PatternAt, viewWidth, viewHeight)
+		if err != nil {
+			return nil, model.Options{}, fmt.Errorf("loading pattern: %w", err)
+		}
+		if _, ok := m.(*model.GameOfLife); ok {
+			opts.Pattern, opts.Universe = cells, universe
+		} else {
+			if universe != nil {
+				cells = universe.Cells()
+			}
+			// The other models start from the alive cells at 1 in the 3 worlds
+			values := game_of_life.CellValues(cells, opts.Width, opts.Height)
+			opts.Image = &image_utils.Fields{Width: opts.Width, Height: opts.Height, R: values, G: values, B: values}
+		}
+
+	case cfg.Init.Generator != "":
+		opts.Generator, opts.Params = cfg.Init.Generator, cfg.Init.Params
+
+	case !cfg.Init.Random:
+		return nil, model.Options{}, errNoInit
+	}
+	return m, opts, nil
+}
+
+// lifePattern is a pattern file read by readPattern: its cells, or its quadtree
+// for the macrocell files, and its rule.
+type lifePattern struct {
+	rule     string // "" when the file has none
+	pattern  *game_of_life.Pattern
+	universe *game_of_life.Universe
+}
+
+// readPattern reads the pattern file of cfg and transforms it.
+func readPattern(cfg config.Config) (*lifePattern, error) {
+	if strings.EqualFold(filepath.Ext(cfg.Init.Pattern), ".mc") {
+		// Without listing the cells of the quadtree
+		m, err := game_of_life.ReadMacrocellFile(cfg.Init.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.Universe.Transform(cfg.Init.Transform); err != nil {
+			return nil, err
+		}
+		return &lifePattern{rule: m.Rule, universe: m.Universe}, nil
+	}
+	p, err := game_of_life.ReadPattern(cfg.Init.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	if p, err = p.Transform(cfg.Init.Transform); err != nil {
+		return nil, err
+	}
+	return &lifePattern{rule: p.Rule, pattern: p}, nil
+}
+
+// placePattern returns the cells of p on a width x height grid, with its top left
+// cell at the point "x,y" of at or centred. The macrocell files are returned as a
+// quadtree instead of cells.
+func placePattern(p *lifePattern, at string, width, height int) ([]game_of_life.Cell, *game_of_life.Universe, error) {
+	position := func(patternWidth, patternHeight int) (int, int, error) {
+		if at != "" {
+			return config.ParsePoint(at)
+		}
+		return (width - patternWidth) / 2, (height - patternHeight) / 2, nil
+	}
+	if u := p.universe; u != nil {
+		boxX, boxY, boxWidth, boxHeight := u.Box()
+		x, y, err := position(int(boxWidth), int(boxHeight))
+		if err != nil {
+			return nil, nil, err
+		}
+		u.Move(int64(x)-boxX, int64(y)-boxY)
+		return nil, u, nil
+	}
+	x, y, err := position(p.pattern.Width, p.pattern.Height)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p.pattern.Place(x, y), nil, nil
+}
+
+// errNoFields is returned by the features that use the float fields of smoothlife3d.
+var errNoFields = errors.New("only available for the smoothlife3d model")
+
+// simulate runs the simulation described by cfg until the renderer is closed,
+// the quit command or the last of cfg.Output.Steps.
+func simulate(cfg config.Config) error {
+	seed := *cfg.Seed
+	log.Printf("Seed: %d", seed)
+	m, opts, err := setup(cfg)
+	if err != nil {
+		return err
+	}
+	if err := m.Init(opts); err != nil {
+		return err
+	}
+	gridWidth, gridHeight := m.Size()
+	tunable, _ := m.(model.Tunable)
+	// The exports, snapshots, float textures and brush work on the float fields of smoothlife3d
+	_, floats := m.(*model.SmoothLife3D)
+	if !floats && (cfg.Output.NpyDir != "" || cfg.Renderer.FloatTexture) {
+		return fmt.Errorf("-npy and -float-texture: %w", errNoFields)
+	}
+
+	var fields []string
+	if cfg.Output.NpyDir != "" {
+		fields = cfg.Output.NpyFields
+		if err := os.MkdirAll(cfg.Output.NpyDir, 0755); err != nil {
+			return fmt.Errorf("creating export directory: %w", err)
+		}
+	}
+
+	if err := opengl_utils.SetDisplay(cfg.Renderer.Colormap, cfg.Renderer.ColormapChannel, cfg.Renderer.Gamma, cfg.Renderer.Contrast); err != nil {
+		return fmt.Errorf("display options: %w", err)
+	}
+
+	// The frames are shown by the backend chosen with -renderer.
+	output, err := renderer.New(cfg.Renderer.Name, renderer.Options{Dir: cfg.Renderer.Dir, Format: cfg.Renderer.Format, Every: cfg.Renderer.Every})
+	if err != nil {
+		return fmt.Errorf("renderer: %w", err)
+	}
+
+	step := 0
+	paused := false
+	stepOnce := false
+	quit := false
+
+	brush := smoothlife3d.DefaultBrush
+	if tunable, ok := m.(model.Tunable); ok {
+		brush.Radius = tunable.Params()["ra"] // the kernel radius unless one is given
+	}
+	if cfg.Brush.Radius > 0 {
+		brush.Radius = cfg.Brush.Radius
+	}
+	brush.Hardness = cfg.Brush.Hardness
+	brush.Value = cfg.Brush.Value
+	brush.Flow = cfg.Brush.Flow
+	for c, name := range "rgb" {
+		brush.Channels[c] = strings.ContainsRune(cfg.Brush.Channels, name)
+	}
+	brushChannels := brush.Channels // restored by the 0 key
+
+	// Commands of the renderer (keyboard and mouse, see commands.KeyBindings and
+	// opengl_utils.BindMouse), Ctrl-C also quits so the last state is still exported.
+	queue := &commands.Queue{}
+	if err := output.Init(gridWidth, gridHeight, queue); err != nil {
+		return fmt.Errorf("opening the renderer: %w", err)
+	}
+	defer output.Close()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		for range interrupt {
+			queue.Push(commands.Command{Kind: commands.Quit})
+		}
+	}()
+
+	// restart sets the initial condition again with seed
+	restart := func() error {
+		opts.Seed = seed
+		if err := m.Init(opts); err != nil {
+			return err
+		}
+		step = 0
+		return nil
+	}
+	// setParam changes a parameter of the model, when it has it
+	setParam := func(name string, value float64) error {
+		if tunable == nil {
+			return fmt.Errorf("the %s model has no parameters", cfg.Model)
+		}
+		if err := tunable.SetParam(name, value); err != nil {
+			return err
+		}
+		log.Printf("%s: %.4g", name, value)
+		return nil
+	}
+	// setParams changes several parameters at once, none of them when one fails
+	setParams := func(params map[string]float64) error {
+		if tunable == nil {
+			return fmt.Errorf("the %s model has no parameters", cfg.Model)
+		}
+		if err := model.SetParams(tunable, params); err != nil {
+			return err
+		}
+		log.Printf("Parameters: %v", params)
+		return nil
+	}
+	param := func(name string) float64 {
+		if tunable == nil {
+			return 0
+		}
+		return tunable.Params()[name]
+	}
+
+	dispatcher := commands.NewDispatcher()
+	dispatcher.Handle(commands.Pause, func(commands.Command) error {
+		paused = !paused
+		return nil
+	})
+	dispatcher.Handle(commands.Step, func(commands.Command) error {
+		paused = true
+		stepOnce = true
+		return nil
+	})
+	dispatcher.Handle(commands.Reset, func(commands.Command) error {
+		return restart()
+	})
+	dispatcher.Handle(commands.Reseed, func(commands.Command) error {
+		seed = time.Now().UnixNano()
+		log.Printf("Seed: %d", seed)
+		return restart()
+	})
+	dispatcher.Handle(commands.Snapshot, func(c commands.Command) error {
+		if !floats {
+			return fmt.Errorf("snapshots: %w", errNoFields)
+		}
+		// c.Name is the file name in the snapshot directory, by default step_NNNNNN.npz
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("step_%06d.npz", step)
+		}
+		path, err := snapshotPath(cfg.Output.SnapshotDir, name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(cfg.Output.SnapshotDir, 0755); err != nil {
+			return fmt.Errorf("saving snapshot: %w", err)
+		}
+		arrays, err := snapshotArrays([]string{"world1", "world2", "world3"}, step, seed)
+		if err == nil {
+			err = npy.WriteNPZ(path, arrays)
+		}
+		if err != nil {
+			return fmt.Errorf("saving snapshot: %w", err)
+		}
+		log.Printf("Snapshot of step %d saved in %s", step, path)
+		return nil
+	})
+	dispatcher.Handle(commands.Load, func(c commands.Command) error {
+		if !floats {
+			return fmt.Errorf("snapshots: %w", errNoFields)
+		}
+		path, err := snapshotPath(cfg.Output.SnapshotDir, c.Name)
+		if err != nil {
+			return err
+		}
+		loaded, err := loadSnapshot(path, gridWidth, gridHeight, param("ra"))
+		if err != nil {
+			return fmt.Errorf("loading snapshot: %w", err)
+		}
+		step = loaded.step
+		if loaded.hasSeed {
+			seed = loaded.seed
+			smoothlife3d.SetSeed(seed)
+		}
+		log.Printf("Snapshot %s loaded (step %d, seed %d)", path, step, seed)
+		return nil
+	})
+	dispatcher.Handle(commands.SetPaused, func(c commands.Command) error {
+		paused = c.Value != 0
+		return nil
+	})
+	dispatcher.Handle(commands.SetParam, func(c commands.Command) error {
+		return setParam(c.Name, c.Value)
+	})
+	dispatcher.Handle(commands.SetParams, func(c commands.Command) error {
+		return setParams(c.Params)
+	})
+	dispatcher.Handle(commands.DtUp, func(commands.Command) error {
+		return setParam("dt", param("dt")*1.1)
+	})
+	dispatcher.Handle(commands.DtDown, func(commands.Command) error {
+		return setParam("dt", param("dt")/1.1)
+	})
+	dispatcher.Handle(commands.RadiusUp, func(commands.Command) error {
+		return setParam("ra", param("ra")+1)
+	})
+	dispatcher.Handle(commands.RadiusDown, func(commands.Command) error {
+		if radius := param("ra"); radius > 4 {
+			return setParam("ra", radius-1)
+		}
+		return nil
+	})
+	dispatcher.Handle(commands.Quit, func(commands.Command) error {
+		quit = true
+		return nil
+	})
+	dispatcher.Handle(commands.StrokeBegin, func(c commands.Command) error {
+		if !floats {
+			return fmt.Errorf("brush: %w", errNoFields)
+		}
+		smoothlife3d.BeginStroke(c.Erase)
+		return nil
+	})
+	dispatcher.Handle(commands.Paint, func(c commands.Command) error {
+		if floats {
+			smoothlife3d.PaintTo(c.X, c.Y, brush)
+		}
+		return nil
+	})
+	dispatcher.Handle(commands.StrokeEnd, func(commands.Command) error {
+		if floats {
+			smoothlife3d.EndStroke()
+		}
+		return nil
+	})
+	dispatcher.Handle(commands.Undo, func(commands.Command) error {
+		if floats {
+			smoothlife3d.Undo()
+		}
+		return nil
+	})
+	dispatcher.Handle(commands.BrushBigger, func(commands.Command) error {
+		brush.Radius *= 1.2
+		log.Printf("Brush radius: %.1f", brush.Radius)
+		return nil
+	})
+	dispatcher.Handle(commands.BrushSmaller, func(commands.Command) error {
+		brush.Radius = math.Max(1, brush.Radius/1.2)
+		log.Printf("Brush radius: %.1f", brush.Radius)
+		return nil
+	})
+	dispatcher.Handle(commands.BrushChannel, func(c commands.Command) error {
+		if c.Value < 0 {
+			brush.Channels = brushChannels
+		} else {
+			brush.Channels = [3]bool{}
+			brush.Channels[int(c.Value)] = true
+		}
+		log.Printf("Brush channels: %v", brush.Channels)
+		return nil
+	})
+
+	// Frame timings go to an optional log instead of flooding the terminal
+	var timingsLog *log.Logger
+	if cfg.Output.Timings == "-" {
+		timingsLog = log.New(os.Stderr, "", log.LstdFlags)
+	} else if cfg.Output.Timings != "" {
+		file, err := os.Create(cfg.Output.Timings)
+		if err != nil {
+			return fmt.Errorf("creating timings log: %w", err)
+		}
+		defer file.Close()
+		timingsLog = log.New(file, "", log.LstdFlags)
+	}
+	opengl_utils.SetHUDVisible(cfg.Renderer.HUD)
+
+	// The simulation runs in its own goroutine and publishes each state in a triple
+	// buffer, this thread (locked for OpenGL) draws the latest one and polls the events
+	// at the rate of the renderer, so the window stays responsive during slow steps.
+	// Only the simulation goroutine touches smoothlife3d, the input reaches it through the queue.
+	frames := pipeline.NewFrameBuffer()
+	limiter := pipeline.NewLimiter(cfg.Renderer.StepsPerSecond)
+	var frameTime time.Duration // time between the last two steps, for the steps per second
+
+	// The optional HTTP server gets the frames too, it never slows the simulation down
+	// and an error stops the run like closing the window, the state is still exported.
+	var frameServer *server.Server
+	var serverErr chan error // nil without server, never ready
+	if cfg.Output.Serve != "" {
+		frameServer = server.New(gridWidth, gridHeight)
+		if cfg.Output.API {
+			frameServer.Control(queue)
+		}
+		serverErr = make(chan error, 1)
+		go func() {
+			serverErr <- frameServer.ListenAndServe(cfg.Output.Serve)
+		}()
+		log.Printf("Serving the frames on http://%s/", cfg.Output.Serve)
+	}
+
+	// publish copies the current state in the back frame and makes it the latest one
+	publish := func() {
+		frame := frames.Back()
+		if cfg.Renderer.FloatTexture {
+			frame.Floats = smoothlife3d.FloatPixels(frame.Floats)
+		} else {
+			frame.Pixels = m.Render(frame.Pixels)
+		}
+		frame.Step = step
+		frame.HUD = hudLines(m, step, frameTime, paused)
+		if frameServer != nil {
+			frameServer.Offer(frame)
+			if cfg.Output.API {
+				frameServer.SetStats(stats(m, step, frameTime, paused, seed))
+			}
+		}
+		frames.Publish()
+	}
+	publish()
+
+	stop := make(chan struct{}) // closed when the window is closed
+	done := make(chan struct{}) // closed when the simulation has stopped
+	go func() {
+		defer close(done)
+		lastStep := time.Now()
+		for !quit {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err := dispatcher.DispatchAll(queue); err != nil {
+				log.Printf("Error: %v", err)
+			}
+			if paused && !stepOnce {
+				publish() // painting changes the state while paused
+				time.Sleep(time.Second / 60)
+				continue
+			}
+			stepOnce = false
+
+			m.Step()
+			step++
+			if cfg.Output.Steps > 0 && step >= cfg.Output.Steps {
+				quit = true
+			}
+
+			if cfg.Output.NpyDir != "" && cfg.Output.NpyEvery > 0 && step%cfg.Output.NpyEvery == 0 {
+				if err := exportFields(cfg.Output.NpyDir, fields, cfg.Output.Npz, step, seed); err != nil {
+					log.Printf("Error exporting fields: %v", err)
+				}
+			}
+
+			now := time.Now()
+			frameTime, lastStep = now.Sub(lastStep), now
+			if timingsLog != nil && !floats {
+				timingsLog.Printf("step %d: frame %v (%.2f fps)", step, frameTime, 1/frameTime.Seconds())
+			} else if timingsLog != nil {
+				timings := smoothlife3d.LastTimings
+				timingsLog.Printf("step %d: frame %v (%.2f fps), precomputation %v, convolutions %v, new state %v, copy %v",
+					step, frameTime, 1/frameTime.Seconds(), timings.Precomputation, timings.Convolutions, timings.NewState, timings.Copy)
+			}
+			publish()
+			limiter.Wait()
+		}
+	}()
+
+	var serveErr error
+	for output.PollInput() {
+		stopped := false
+		select {
+		case <-done:
+			stopped = true // quit command or -steps, the last frame is still presented
+		case serveErr = <-serverErr:
+			log.Printf("Error in the HTTP server: %v", serveErr)
+			stopped = true
+		default:
+		}
+		frame, fresh := frames.Latest()
+		if err := output.Present(frame, fresh); err != nil {
+			log.Printf("Error presenting frame: %v", err)
+		}
+		if stopped {
+			break
+		}
+	}
+	close(stop)
+	<-done
+
+	// Always export the state we stopped at so the last frame is never lost.
+	if cfg.Output.NpyDir != "" && (cfg.Output.NpyEvery <= 0 || step%cfg.Output.NpyEvery != 0) {
+		if err := exportFields(cfg.Output.NpyDir, fields, cfg.Output.Npz, step, seed); err != nil {
+			return fmt.Errorf("exporting fields: %w", err)
+		}
+	}
+	if cfg.Output.Save != "" {
+		if err := saveImage(m, cfg.Output.Save, cfg.Output.SaveBits); err != nil {
+			return fmt.Errorf("saving image: %w", err)
+		}
+	}
+	if serveErr != nil {
+		return fmt.Errorf("HTTP server: %w", serveErr)
+	}
+	return nil
+}
+
+// hudLines returns the text of the overlay of the window.
+func hudLines(m model.Model, step int, frameTime time.Duration, paused bool) []string {
+	sps := 0.0
+	if frameTime > 0 {
+		sps = 1 / frameTime.Seconds()
+	}
+	state := "running"
+	if paused {
+		state = "PAUSED"
+	}
+	lines := []string{fmt.Sprintf("step %d  %.1f steps/s  %s", step, sps, state)}
+	if _, ok := m.(*model.SmoothLife3D); ok {
+		timings := smoothlife3d.LastTimings
+		ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+		lines = append(lines, fmt.Sprintf("fft %.0fms  conv %.0fms  state %.0fms  copy %.0fms",
+			ms(timings.Precomputation), ms(timings.Convolutions), ms(timings.NewState), ms(timings.Copy)))
+	}
+	if life, ok := m.(*model.GameOfLife); ok {
+		line := fmt.Sprintf("generation %d  population %d  %s", life.Generation(), life.Population(), life.Engine())
+		if _, _, width, height, ok := life.Box(); ok {
+			line += fmt.Sprintf("  box %d x %d", width, height)
+		}
+		lines = append(lines, line)
+	}
+	if ruled, ok := m.(model.Ruled); ok {
+		lines = append(lines, "rule "+ruled.Rule())
+	}
+	if tunable, ok := m.(model.Tunable); ok {
+		params := tunable.Params()
+		lines = append(lines,
+			fmt.Sprintf("dt %.3f  ra %.1f  alpha %.3f", params["dt"], params["ra"], params["alpha"]),
+			fmt.Sprintf("b1 %.3f  b2 %.3f  d1 %.3f  d2 %.3f", params["b1"], params["b2"], params["d1"], params["d2"]))
+	}
+	return lines
+}
+
+// stats returns the statistics of the simulation served by the HTTP API.
+func stats(m model.Model, step int, frameTime time.Duration, paused bool, seed int64) server.Stats {
+	width, height := m.Size()
+	sps := 0.0
+	if frameTime > 0 {
+		sps = 1 / frameTime.Seconds()
+	}
+	stats := server.Stats{
+		Step:           step,
+		Paused:         paused,
+		StepsPerSecond: sps,
+		Seed:           seed,
+		Width:          width,
+		Height:         height,
+		Means:          means(m),
+	}
+	if tunable, ok := m.(model.Tunable); ok {
+		stats.Params = tunable.Params()
+	}
+	if ruled, ok := m.(model.Ruled); ok {
+		stats.Rule = ruled.Rule()
+	}
+	if life, ok := m.(*model.GameOfLife); ok {
+		stats.Generation, stats.Population = life.Generation(), life.Population()
+	}
+	if _, ok := m.(*model.SmoothLife3D); ok {
+		timings := smoothlife3d.LastTimings
+		ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+		stats.Timings = map[string]float64{
+			"precomputation": ms(timings.Precomputation),
+			"convolutions":   ms(timings.Convolutions),
+			"new_state":      ms(timings.NewState),
+			"copy":           ms(timings.Copy),
+		}
+	}
+	return stats
+}
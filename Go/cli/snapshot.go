@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"main/npy"
+	"main/smoothlife3d"
+)
+
+// Snapshots of the simulation: the .npy / .npz exports and the snapshots taken
+// with the S key or the HTTP API, which can be loaded back.
+
+// snapshotArrays returns the requested simulation fields of the current step, with
+// the step and the seed of the run so the snapshot can be reproduced.
+func snapshotArrays(fields []string, step int, seed int64) ([]npy.Array, error) {
+	width, height := smoothlife3d.Size()
+	shape := []int{height, width}
+	arrays := make([]npy.Array, 0, len(fields)+2)
+	for _, name := range fields {
+		data, err := smoothlife3d.Field(name)
+		if err != nil {
+			return nil, err
+		}
+		arrays = append(arrays, npy.Array{Name: name, Data: data, Shape: shape})
+	}
+	arrays = append(arrays,
+		npy.Array{Name: "step", Data: []int64{int64(step)}, Shape: []int{}},
+		npy.Array{Name: "seed", Data: []int64{seed}, Shape: []int{}})
+	return arrays, nil
+}
+
+// exportFields writes the requested simulation fields of the current step in dir,
+// either as one .npy file per field or bundled in a single .npz archive.
+// The seed of the run is stored alongside the fields so the snapshot can be reproduced.
+func exportFields(dir string, fields []string, bundle bool, step int, seed int64) error {
+	if bundle {
+		arrays, err := snapshotArrays(fields, step, seed)
+		if err != nil {
+			return err
+		}
+		return npy.WriteNPZ(filepath.Join(dir, fmt.Sprintf("step_%06d.npz", step)), arrays)
+	}
+
+	width, height := smoothlife3d.Size()
+	shape := []int{height, width}
+	for _, name := range fields {
+		data, err := smoothlife3d.Field(name)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, fmt.Sprintf("%s_%06d.npy", name, step))
+		if err := npy.WriteFile(path, data, shape); err != nil {
+			return err
+		}
+	}
+	return npy.WriteFile(filepath.Join(dir, "seed.npy"), []int64{seed}, []int{})
+}
+
+// snapshotPath returns the path of the snapshot name in dir. Names come from the HTTP
+// API too, so they can not leave the directory.
+func snapshotPath(dir, name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid snapshot name %q", name)
+	}
+	if filepath.Ext(name) != ".npz" {
+		name += ".npz"
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// snapshot is the state read from a snapshot file.
+type snapshot struct {
+	step    int
+	seed    int64
+	hasSeed bool // the snapshots written before -seed have no seed
+}
+
+// loadSnapshot sets the worlds from a .npz snapshot, which must have the size of the grid.
+func loadSnapshot(path string, width, height int, kernelRadius float64) (snapshot, error) {
+	arrays, err := npy.ReadNPZ(path)
+	if err != nil {
+		return snapshot{}, err
+	}
+	var loaded snapshot
+	worlds := make(map[string][]float64)
+	for _, a := range arrays {
+		switch a.Name {
+		case "world1", "world2", "world3":
+			if len(a.Shape) != 2 || a.Shape[0] != height || a.Shape[1] != width {
+				return snapshot{}, fmt.Errorf("%s has shape %v, the grid is %d x %d", a.Name, a.Shape, width, height)
+			}
+			worlds[a.Name] = a.Float64s()
+		case "step":
+			if values := a.Float64s(); len(values) == 1 {
+				loaded.step = int(values[0])
+			}
+		case "seed":
+			if values, ok := a.Data.([]int64); ok && len(values) == 1 {
+				loaded.seed, loaded.hasSeed = values[0], true
+			}
+		}
+	}
+	if len(worlds) != 3 {
+		return snapshot{}, fmt.Errorf("world1, world2 and world3 are needed")
+	}
+	smoothlife3d.LoadImageFields(worlds["world1"], worlds["world2"], worlds["world3"], width, height, kernelRadius)
+	return loaded, nil
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"main/config"
+	"main/model"
+	"main/smoothlife3d"
+)
+
+const sweepUsage = `usage: smoothlife sweep -param name -values v1,v2,... -steps N [flags]
+
+Runs the same simulation (same initial condition and seed) once for each value of
+a parameter, without display. The last state of each run is saved as an image in
+-out, and sweep.csv gathers the mean of each world and the duration of the runs.
+It takes the flags of run for the other settings.
+
+`
+
+func cmdSweep(args []string) int {
+	var param, list, out string
+	cfg, dump, err := parseRunFlags("sweep", sweepUsage, args, func(fs *flag.FlagSet) {
+		fs.StringVar(&param, "param", "dt", "parameter changed: ra or a rule ("+strings.Join(smoothlife3d.RuleNames, ", ")+"), for the models having them")
+		fs.StringVar(&list, "values", "", "comma separated values of the parameter")
+		fs.StringVar(&out, "out", "sweep", "directory of the results")
+	})
+	if err != nil {
+		return usageError("sweep", err)
+	}
+	if list == "" {
+		return usageError("sweep", errors.New("-values is needed"))
+	}
+	if cfg.Output.Steps <= 0 {
+		return usageError("sweep", errors.New("-steps is needed"))
+	}
+	var values []float64
+	for _, item := range strings.Split(list, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(item), 64)
+		if err != nil {
+			return usageError("sweep", fmt.Errorf("invalid value %q", item))
+		}
+		values = append(values, v)
+	}
+	m, err := model.New(cfg.Model)
+	if err != nil {
+		return usageError("sweep", err)
+	}
+	if tunable, ok := m.(model.Tunable); !ok {
+		return usageError("sweep", fmt.Errorf("the %s model has no parameters", cfg.Model))
+	} else if _, ok := tunable.Params()[param]; !ok {
+		return usageError("sweep", fmt.Errorf("the %s model has no parameter %q", cfg.Model, param))
+	}
+	if dump {
+		return runConfig(cfg, true)
+	}
+
+	if err := sweep(cfg, param, values, out); err != nil {
+		log.Printf("Error: %v", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// sweep runs cfg for each value of param and writes the results in out.
+func sweep(cfg config.Config, param string, values []float64, out string) error {
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return err
+	}
+	csv, err := os.Create(filepath.Join(out, "sweep.csv"))
+	if err != nil {
+		return err
+	}
+	defer csv.Close()
+	fmt.Fprintf(csv, "%s,steps,seconds,mean1,mean2,mean3\n", param)
+
+	log.Printf("Seed: %d", *cfg.Seed)
+	for _, value := range values {
+		run := cfg
+		if param == "ra" {
+			run.Kernel.Radius = value
+		} else {
+			run.Rules = maps.Clone(cfg.Rules)
+			if run.Rules == nil {
+				run.Rules = make(map[string]float64)
+			}
+			run.Rules[param] = value
+		}
+
+		m, opts, err := setup(run)
+		if err != nil {
+			return err
+		}
+		if err := m.Init(opts); err != nil {
+			return err
+		}
+		start := time.Now()
+		for step := 0; step < run.Output.Steps; step++ {
+			m.Step()
+		}
+		elapsed := time.Since(start)
+
+		name := fmt.Sprintf("%s_%s.png", param, strconv.FormatFloat(value, 'g', -1, 64))
+		if err := saveImage(m, filepath.Join(out, name), 8); err != nil {
+			return err
+		}
+		averages := means(m)
+		fmt.Fprintf(csv, "%g,%d,%.3f,%.6f,%.6f,%.6f\n", value, run.Output.Steps, elapsed.Seconds(), averages[0], averages[1], averages[2])
+		log.Printf("%s = %g: %d steps in %v, means %.4f %.4f %.4f", param, value, run.Output.Steps, elapsed.Round(time.Millisecond), averages[0], averages[1], averages[2])
+	}
+	return csv.Close()
+}
@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Commands sent to a running simulation by the user (keyboard, mouse or HTTP API).
+// The input side only pushes commands in a Queue, the simulation loop drains it
+// and gives each command to a Dispatcher, so no input code touches the simulation.
+// A command with a Reply channel gets the result of its handler back.
+
+type Kind int
+
+const (
+	Pause        Kind = iota // pause or resume
+	Step                     // compute a single step, used when paused
+	Reset                    // restart from the initial condition with the same seed
+	Reseed                   // restart from the initial condition with a new seed
+	Snapshot                 // save the current state
+	DtUp                     // increase the time step
+	DtDown                   // decrease the time step
+	RadiusUp                 // increase the kernel radius
+	RadiusDown               // decrease the kernel radius
+	Quit                     // stop the simulation
+	StrokeBegin              // start a brush stroke, Erase tells if it paints or erases
+	Paint                    // paint the stroke up to X, Y (grid coordinates)
+	StrokeEnd                // finish the brush stroke
+	Undo                     // undo the last brush stroke
+	BrushBigger              // increase the brush radius
+	BrushSmaller             // decrease the brush radius
+	BrushChannel             // paint only the channel Value (0, 1 or 2), or all of them if Value is -1
+	SetPaused                // pause if Value is not 0, resume otherwise
+	SetParam                 // set the parameter Name (a rule or the kernel radius) to Value
+	Load                     // load the snapshot Name
+	SetParams                // set the parameters of Params at once, none of them if one fails
+)
+
+var names = map[Kind]string{
+	Pause:        "pause",
+	Step:         "step",
+	Reset:        "reset",
+	Reseed:       "reseed",
+	Snapshot:     "snapshot",
+	DtUp:         "dt+",
+	DtDown:       "dt-",
+	RadiusUp:     "radius+",
+	RadiusDown:   "radius-",
+	Quit:         "quit",
+	StrokeBegin:  "stroke-begin",
+	Paint:        "paint",
+	StrokeEnd:    "stroke-end",
+	Undo:         "undo",
+	BrushBigger:  "brush+",
+	BrushSmaller: "brush-",
+	BrushChannel: "brush-channel",
+	SetPaused:    "set-paused",
+	SetParam:     "set-param",
+	Load:         "load",
+	SetParams:    "set-params",
+}
+
+func (k Kind) String() string {
+	if name, ok := names[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Parse returns the kind of command called name.
+func Parse(name string) (Kind, error) {
+	for kind, n := range names {
+		if n == name {
+			return kind, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown command %q", name)
+}
+
+// Command is a kind of command and its arguments, only some kinds use them.
+type Command struct {
+	Kind   Kind
+	X, Y   float64
+	Value  float64
+	Erase  bool
+	Name   string             // parameter or file name
+	Params map[string]float64 // parameters of SetParams by name
+	Reply  chan error         // if not nil, receives the result of the handler, it needs room for one value
+}
+
+// Queue is a list of commands safe to use from several goroutines.
+type Queue struct {
+	mutex    sync.Mutex
+	commands []Command
+}
+
+func (q *Queue) Push(c Command) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.commands = append(q.commands, c)
+}
+
+// Drain returns the pending commands in the order they were pushed and empties the queue.
+func (q *Queue) Drain() []Command {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	pending := q.commands
+	q.commands = nil
+	return pending
+}
+
+type Handler func(c Command) error
+
+// Dispatcher calls the handler registered for the kind of each command.
+type Dispatcher struct {
+	handlers map[Kind]Handler
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{handlers: make(map[Kind]Handler)}
+}
+
+func (d *Dispatcher) Handle(kind Kind, handler Handler) {
+	d.handlers[kind] = handler
+}
+
+// Dispatch runs the handler of c and returns its error, it fails if no handler is
+// registered for its kind. The result is also sent to c.Reply.
+func (d *Dispatcher) Dispatch(c Command) error {
+	var err error
+	if handler, ok := d.handlers[c.Kind]; ok {
+		err = handler(c)
+	} else {
+		err = fmt.Errorf("no handler for command %v", c.Kind)
+	}
+	if c.Reply != nil {
+		c.Reply <- err
+	}
+	return err
+}
+
+// DispatchAll drains q and dispatches every command. It returns the first error of
+// a command without Reply, the commands after it are still dispatched.
+func (d *Dispatcher) DispatchAll(q *Queue) error {
+	var first error
+	for _, c := range q.Drain() {
+		if err := d.Dispatch(c); err != nil && c.Reply == nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
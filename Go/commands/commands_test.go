@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestKeyCommand(t *testing.T) {
+	tests := []struct {
+		key    Key
+		mods   Mods
+		repeat bool
+		want   Command
+		ok     bool
+	}{
+		{KeySpace, 0, false, Command{Kind: Pause}, true},
+		{KeySpace, 0, true, Command{}, false}, // held down, pause is not repeated
+		{KeyN, 0, true, Command{Kind: Step}, true},
+		{KeyEqual, ModShift, false, Command{Kind: DtUp}, true},
+		{KeyEqual, ModControl, false, Command{Kind: RadiusUp}, true},
+		{KeyMinus, ModControl | 0x4, false, Command{Kind: RadiusDown}, true}, // alt is ignored
+		{KeyZ, 0, false, Command{}, false},
+		{KeyZ, ModControl, true, Command{Kind: Undo}, true},
+		{Key0, 0, false, Command{Kind: BrushChannel, Value: -1}, true},
+		{Key2, 0, false, Command{Kind: BrushChannel, Value: 1}, true},
+		{'Q', 0, false, Command{}, false},
+	}
+	for _, test := range tests {
+		got, ok := KeyCommand(test.key, test.mods, test.repeat)
+		if ok != test.ok || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("KeyCommand(%d, %d, %v) = %+v, %v, want %+v, %v", test.key, test.mods, test.repeat, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	var got []Command
+	d := NewDispatcher()
+	for _, kind := range []Kind{Pause, DtUp, BrushChannel} {
+		d.Handle(kind, func(c Command) error {
+			got = append(got, c)
+			return nil
+		})
+	}
+	failed := errors.New("failed")
+	d.Handle(Snapshot, func(c Command) error { return failed })
+
+	queue := &Queue{}
+	for _, key := range []Key{KeySpace, KeyEqual, Key3} {
+		c, _ := KeyCommand(key, 0, false)
+		queue.Push(c)
+	}
+	reply := make(chan error, 1)
+	queue.Push(Command{Kind: Snapshot, Reply: reply})
+	queue.Push(Command{Kind: Quit})
+	err := d.DispatchAll(queue)
+
+	want := []Command{{Kind: Pause}, {Kind: DtUp}, {Kind: BrushChannel, Value: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dispatched %+v, want %+v", got, want)
+	}
+	if r := <-reply; r != failed {
+		t.Errorf("reply %v, want %v", r, failed)
+	}
+	// the error of the snapshot went to its Reply, the quit one has no handler
+	if err == nil || err == failed {
+		t.Errorf("DispatchAll returned %v, want the error of quit", err)
+	}
+	if pending := queue.Drain(); len(pending) != 0 {
+		t.Errorf("%d commands left in the queue", len(pending))
+	}
+}
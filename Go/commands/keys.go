@@ -0,0 +1,88 @@
+package commands
+
+// Keyboard bindings of the window. The keys are plain ints with the codes of GLFW
+// (the letters and digits are their upper case ASCII code), so the renderer only
+// converts its key events and the table can be used and tested without a window.
+
+type Key int
+
+const (
+	KeySpace        Key = 32
+	KeyMinus        Key = 45
+	Key0            Key = 48
+	Key1            Key = 49
+	Key2            Key = 50
+	Key3            Key = 51
+	KeyEqual        Key = 61
+	KeyN            Key = 78
+	KeyR            Key = 82
+	KeyS            Key = 83
+	KeyZ            Key = 90
+	KeyLeftBracket  Key = 91
+	KeyRightBracket Key = 93
+	KeyEscape       Key = 256
+	KeyBackspace    Key = 259
+	KeyKPSubtract   Key = 333
+	KeyKPAdd        Key = 334
+)
+
+// Mods are the modifier keys held with a key, only shift and control are part of the bindings.
+type Mods int
+
+const (
+	ModShift   Mods = 0x1
+	ModControl Mods = 0x2
+)
+
+type KeyBinding struct {
+	Key  Key
+	Mods Mods
+}
+
+var KeyBindings = map[KeyBinding]Command{
+	{KeySpace, 0}:                     {Kind: Pause},
+	{KeyN, 0}:                         {Kind: Step},
+	{KeyBackspace, 0}:                 {Kind: Reset},
+	{KeyR, 0}:                         {Kind: Reseed},
+	{KeyS, 0}:                         {Kind: Snapshot},
+	{KeyEqual, 0}:                     {Kind: DtUp}, // + is shift and = on a qwerty keyboard
+	{KeyEqual, ModShift}:              {Kind: DtUp},
+	{KeyKPAdd, 0}:                     {Kind: DtUp},
+	{KeyMinus, 0}:                     {Kind: DtDown},
+	{KeyKPSubtract, 0}:                {Kind: DtDown},
+	{KeyEqual, ModControl}:            {Kind: RadiusUp},
+	{KeyEqual, ModControl | ModShift}: {Kind: RadiusUp},
+	{KeyKPAdd, ModControl}:            {Kind: RadiusUp},
+	{KeyMinus, ModControl}:            {Kind: RadiusDown},
+	{KeyKPSubtract, ModControl}:       {Kind: RadiusDown},
+	{KeyEscape, 0}:                    {Kind: Quit},
+	{KeyZ, ModControl}:                {Kind: Undo},
+	{KeyRightBracket, 0}:              {Kind: BrushBigger},
+	{KeyLeftBracket, 0}:               {Kind: BrushSmaller},
+	{Key1, 0}:                         {Kind: BrushChannel, Value: 0},
+	{Key2, 0}:                         {Kind: BrushChannel, Value: 1},
+	{Key3, 0}:                         {Kind: BrushChannel, Value: 2},
+	{Key0, 0}:                         {Kind: BrushChannel, Value: -1},
+}
+
+// repeatable commands are sent again while their key is held down
+var repeatable = map[Kind]bool{
+	Step:         true,
+	DtUp:         true,
+	DtDown:       true,
+	RadiusUp:     true,
+	RadiusDown:   true,
+	Undo:         true,
+	BrushBigger:  true,
+	BrushSmaller: true,
+}
+
+// KeyCommand returns the command bound to a key pressed with mods, repeat tells
+// that the key is held down. The modifiers other than shift and control are ignored.
+func KeyCommand(key Key, mods Mods, repeat bool) (Command, bool) {
+	c, ok := KeyBindings[KeyBinding{key, mods & (ModShift | ModControl)}]
+	if !ok || (repeat && !repeatable[c.Kind]) {
+		return Command{}, false
+	}
+	return c, true
+}
@@ -0,0 +1,478 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"main/boundary"
+	"main/game_of_life"
+)
+
+// Complete description of a run, stored as JSON. A run starts from Default, then
+// the file given with -config, then the flags given on the command line, so any
+// field of the file can be overridden. -dump-config prints the result, which
+// replays the same run when given back to -config.
+
+type Config struct {
+	Model      string             `json:"model"`
+	Grid       Grid               `json:"grid"`
+	Boundary   Boundary           `json:"boundary"`
+	Rules      map[string]float64 `json:"rules,omitempty"` // rules of smoothlife and smoothlife3d given, the others keep the defaults of the model
+	Rule       string             `json:"rule,omitempty"`  // rulestring of game_of_life, e.g. B36/S23, "" for the rule of the pattern or B3/S23
+	Engine     string             `json:"engine"`          // implementation of game_of_life, see model.LifeEngines
+	LifeStep   int                `json:"life_step"`       // game_of_life advances 2^life_step generations per step
+	LifeZoom   int                `json:"life_zoom"`       // a pixel shows 2^life_zoom x 2^life_zoom cells, unbounded engines only
+	LifeFollow bool               `json:"life_follow"`     // the view follows the cells, unbounded engines only
+	Kernel     Kernel             `json:"kernel"`
+	Init       Init               `json:"init"`
+	Seed       *int64             `json:"seed,omitempty"` // nil picks one from the clock
+	Brush      Brush              `json:"brush"`
+	Output     Output             `json:"output"`
+	Renderer   Renderer           `json:"renderer"`
+}
+
+// Grid is the size of the grid, 0 uses the size of the image or DefaultSize.
+type Grid struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+const DefaultSize = 1024
+
+// Boundary is what the cells next to the edges of the grid see beyond them, see boundary.Names.
+type Boundary struct {
+	Mode  string  `json:"mode"`
+	Value float64 `json:"value"` // value of the cells beyond the edges of the fixed mode
+}
+
+// Parse returns the boundary of b.
+func (b Boundary) Parse() (boundary.Boundary, error) {
+	return boundary.Parse(b.Mode, b.Value)
+}
+
+type Kernel struct {
+	Radius     float64 `json:"radius,omitempty"` // radius of the outer kernel, 0 keeps the one of the model (11)
+	InnerRatio float64 `json:"inner_ratio"`      // radius of the inner kernel relative to the outer one
+}
+
+// Init is the initial condition: an image, a pattern, a generator or random cells, in this order.
+type Init struct {
+	Image     string             `json:"image,omitempty"`
+	Pattern   string             `json:"pattern,omitempty"`    // .rle, .cells or .mc file of game_of_life
+	PatternAt string             `json:"pattern_at,omitempty"` // "x,y" of its top left cell, "" centres it
+	Transform string             `json:"transform,omitempty"`  // transformations of the pattern, e.g. rot90,flipx
+	Generator string             `json:"generator,omitempty"`
+	Params    map[string]float64 `json:"params,omitempty"`
+	Random    bool               `json:"random,omitempty"`
+	Threshold *float64           `json:"threshold,omitempty"` // of the random cells, nil uses model.DefaultThreshold
+
+	// loading of the image, see image_utils.LoadOptions
+	Resample       string  `json:"resample"`
+	Fit            string  `json:"fit"`
+	Gray           bool    `json:"gray"`
+	Invert         bool    `json:"invert"`
+	Contrast       float64 `json:"contrast"`
+	Gamma          float64 `json:"gamma"`
+	ImageThreshold float64 `json:"image_threshold"` // negative disables it
+	AlphaMask      bool    `json:"alpha_mask"`
+	Frame          int     `json:"frame"`
+}
+
+type Brush struct {
+	Radius   float64 `json:"radius"` // 0 uses the kernel radius
+	Hardness float64 `json:"hardness"`
+	Value    float64 `json:"value"`
+	Flow     float64 `json:"flow"`
+	Channels string  `json:"channels"` // any of r, g and b
+}
+
+type Output struct {
+	NpyDir      string   `json:"npy_dir,omitempty"`
+	NpyEvery    int      `json:"npy_every"`
+	NpyFields   []string `json:"npy_fields"`
+	Npz         bool     `json:"npz"`
+	Save        string   `json:"save,omitempty"`
+	SaveBits    int      `json:"save_bits"`
+	SnapshotDir string   `json:"snapshot_dir"`
+	Timings     string   `json:"timings,omitempty"`
+	Serve       string   `json:"serve,omitempty"`
+	API         bool     `json:"api"`
+	Steps       int      `json:"steps"` // 0 runs until the renderer is closed
+}
+
+type Renderer struct {
+	Name            string  `json:"name"`
+	Dir             string  `json:"dir"`
+	Format          string  `json:"format"`
+	Every           int     `json:"every"`
+	StepsPerSecond  float64 `json:"steps_per_second"` // 0 runs as fast as possible
+	FloatTexture    bool    `json:"float_texture"`
+	Colormap        string  `json:"colormap"`
+	ColormapChannel int     `json:"colormap_channel"`
+	Gamma           float64 `json:"gamma"`
+	Contrast        float64 `json:"contrast"`
+	HUD             bool    `json:"hud"`
+}
+
+// Default returns the configuration used when nothing is given.
+func Default() Config {
+	return Config{
+		Model:    "smoothlife3d",
+		Engine:   "bits", // model.DefaultLifeEngine
+		Boundary: Boundary{Mode: boundary.Torus.String()},
+		Kernel:   Kernel{InnerRatio: 1.0 / 3}, // smoothlife3d.InnerRatio
+		Init: Init{
+			ImageThreshold: -1,
+			Resample:       "bilinear",
+			Fit:            "stretch",
+			Contrast:       1,
+			Gamma:          1,
+		},
+		Brush: Brush{
+			// smoothlife3d.DefaultBrush
+			Hardness: 0.5,
+			Value:    1,
+			Flow:     1,
+			Channels: "rgb",
+		},
+		Output: Output{
+			NpyFields:   []string{"world1", "world2", "world3"},
+			SaveBits:    16,
+			SnapshotDir: ".",
+		},
+		Renderer: Renderer{
+			Name:            "opengl",
+			Dir:             "frames",
+			Format:          "png",
+			Every:           1,
+			Colormap:        "rgb",
+			ColormapChannel: 3,
+			Gamma:           1,
+			Contrast:        1,
+			HUD:             true,
+		},
+	}
+}
+
+// Load reads the JSON file at path into c. The fields missing from the file keep
+// their value, unknown fields are an error so typos are not silently ignored.
+func Load(path string, c *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// Write writes c as indented JSON.
+func (c Config) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Size returns the size of the grid, DefaultSize for the sizes that are not given.
+func (c Config) Size() (int, int) {
+	width, height := c.Grid.Width, c.Grid.Height
+	if width == 0 {
+		width = DefaultSize
+	}
+	if height == 0 {
+		height = DefaultSize
+	}
+	return width, height
+}
+
+// Validate checks the values that can only be wrong in a file.
+func (c Config) Validate() error {
+	if err := checkName("model", c.Model, registry.Models); err != nil {
+		return err
+	}
+	if c.Grid.Width < 0 || c.Grid.Height < 0 {
+		return fmt.Errorf("negative grid size %d x %d", c.Grid.Width, c.Grid.Height)
+	}
+	if b, err := c.Boundary.Parse(); err != nil {
+		return err
+	} else if b.Mode != boundary.Torus && c.Model == "game_of_life" && registry.UnboundedLifeEngine(c.Engine) {
+		return fmt.Errorf("the unbounded engines (hashlife, sparse) have no boundary")
+	}
+	if c.Rule != "" {
+		if _, err := game_of_life.ParseRule(c.Rule); err != nil {
+			return err
+		}
+	}
+	if err := checkName("game of life engine", c.Engine, registry.LifeEngines); err != nil {
+		return err
+	}
+	if limit := registry.LifeStepLimit(c.Engine); c.LifeStep < 0 || c.LifeStep > limit {
+		return fmt.Errorf("life_step must be in [0,%d] for the %s engine (up to %d with hashlife)", limit, c.Engine, game_of_life.MaxStep)
+	}
+	if c.LifeZoom < 0 || c.LifeZoom > registry.MaxLifeZoom || (c.LifeZoom != 0 && !registry.UnboundedLifeEngine(c.Engine)) {
+		return fmt.Errorf("life_zoom must be in [0,%d] and needs an unbounded engine (hashlife, sparse)", registry.MaxLifeZoom)
+	}
+	if c.LifeFollow && !registry.UnboundedLifeEngine(c.Engine) {
+		return fmt.Errorf("life_follow needs an unbounded engine (hashlife, sparse)")
+	}
+	if c.Init.PatternAt != "" {
+		if _, _, err := ParsePoint(c.Init.PatternAt); err != nil {
+			return err
+		}
+	}
+	if _, err := (&game_of_life.Pattern{}).Transform(c.Init.Transform); err != nil {
+		return err
+	}
+	for _, field := range c.Output.NpyFields {
+		if err := checkName("field of npy_fields", field, registry.Fields); err != nil {
+			return err
+		}
+	}
+	if c.Kernel.Radius < 0 || c.Kernel.InnerRatio <= 0 || c.Kernel.InnerRatio >= 1 {
+		return fmt.Errorf("the kernel radius must be positive and its inner ratio in ]0,1[")
+	}
+	for name := range c.Rules {
+		if err := checkName("rule", name, registry.Rules); err != nil {
+			return err
+		}
+	}
+	if err := checkName("renderer", c.Renderer.Name, registry.Renderers); err != nil {
+		return err
+	}
+	if err := checkName("colormap", c.Renderer.Colormap, append([]string{"rgb"}, registry.Colormaps...)); err != nil {
+		return err
+	}
+	if t := c.Init.Threshold; t != nil && (*t < 0 || *t > 1) {
+		return fmt.Errorf("the threshold %g is not in [0,1]", *t)
+	}
+	return nil
+}
+
+// Flags defines on fs the command line flags of the fields of c, whose current
+// values are the defaults. Parsing fs changes c.
+func (c *Config) Flags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Model, "model", c.Model, "simulated model: "+strings.Join(registry.Models, ", "))
+	fs.StringVar(&c.Rule, "rule", c.Rule, "rule of game_of_life: B3/S23, 23/3 or a name ("+strings.Join(names(game_of_life.NamedRules), ", ")+"), the rule of -pattern or B3/S23 by default")
+	fs.StringVar(&c.Engine, "engine", c.Engine, "implementation of game_of_life: "+strings.Join(registry.LifeEngines, ", "))
+	fs.IntVar(&c.LifeStep, "life-step", c.LifeStep, "game_of_life advances 2^k generations per step (at once with -engine hashlife)")
+	fs.IntVar(&c.LifeZoom, "life-zoom", c.LifeZoom, "with -engine hashlife or sparse, a pixel shows 2^k x 2^k cells")
+	fs.BoolVar(&c.LifeFollow, "follow", c.LifeFollow, "with -engine hashlife or sparse, the view follows the cells")
+	fs.StringVar(&c.Boundary.Mode, "boundary", c.Boundary.Mode, "what the cells see beyond the edges of the grid: "+strings.Join(boundary.Names, ", "))
+	fs.Float64Var(&c.Boundary.Value, "boundary-value", c.Boundary.Value, "value in [0,1] of the cells beyond the edges with -boundary fixed")
+	fs.StringVar(&c.Init.Image, "i", c.Init.Image, "path to image file to use as start grid")
+	fs.StringVar(&c.Init.Pattern, "pattern", c.Init.Pattern, "pattern file (.rle, .cells or .mc) to use as start grid, centred on the grid of -w x -h (implies -model game_of_life)")
+	fs.StringVar(&c.Init.PatternAt, "pattern-at", c.Init.PatternAt, "x,y of the top left cell of the pattern (the pattern is centred by default)")
+	fs.StringVar(&c.Init.Transform, "pattern-transform", c.Init.Transform, "comma separated transformations of the pattern: "+strings.Join(game_of_life.TransformNames(), ", "))
+	fs.BoolVar(&c.Init.Random, "r", c.Init.Random, "use random grid (requires -w and -h)")
+	fs.IntVar(&c.Grid.Width, "w", c.Grid.Width, "grid width, a power of two for smoothlife3d (default 1024, or the width of the image)")
+	fs.IntVar(&c.Grid.Height, "h", c.Grid.Height, "grid height, a power of two for smoothlife3d (default 1024, or the height of the image)")
+	fs.Float64Var(&c.Kernel.Radius, "ra", c.Kernel.Radius, "radius to use for the outer kernel (11 by default)")
+	fs.Float64Var(&c.Kernel.InnerRatio, "inner-ratio", c.Kernel.InnerRatio, "radius of the inner kernel relative to the outer one")
+	fs.Var(optionalFloat{&c.Init.Threshold}, "t", "threshold for random grid generation (share of the alive cells for game_of_life, 0.33 by default, 1 for the other models)")
+	fs.Var(rulesValue{&c.Rules}, "rules", "rules of smoothlife and smoothlife3d, e.g. dt=0.1,b1=0.27 (names: "+strings.Join(registry.Rules, ", ")+")")
+	fs.StringVar(&c.Output.NpyDir, "npy", c.Output.NpyDir, "directory where float fields are exported as .npy files")
+	fs.IntVar(&c.Output.NpyEvery, "npy-every", c.Output.NpyEvery, "export the fields every K steps (0 only exports the last step)")
+	fs.Var((*listValue)(&c.Output.NpyFields), "npy-fields", "comma separated fields to export: "+strings.Join(registry.Fields, ","))
+	fs.BoolVar(&c.Output.Npz, "npz", c.Output.Npz, "bundle the exported fields of a step in a single .npz file")
+	fs.StringVar(&c.Init.Generator, "init", c.Init.Generator, "initial condition generator: "+strings.Join(registry.Generators, ", ")+" (uses -w and -h)")
+	fs.Var((*paramsValue)(&c.Init.Params), "init-params", "parameters of the generator, e.g. count=20,radius=11")
+	fs.StringVar(&c.Init.Resample, "resample", c.Init.Resample, "resampling of the image to the grid size: nearest, bilinear or area")
+	fs.StringVar(&c.Init.Fit, "fit", c.Init.Fit, "how the image fits the grid: stretch, crop or pad")
+	fs.BoolVar(&c.Init.Gray, "gray", c.Init.Gray, "use the luminance of the image for the 3 channels")
+	fs.BoolVar(&c.Init.Invert, "invert", c.Init.Invert, "invert the image")
+	fs.Float64Var(&c.Init.Contrast, "contrast", c.Init.Contrast, "contrast of the image (S curve steepness, 1 keeps it)")
+	fs.Float64Var(&c.Init.Gamma, "gamma", c.Init.Gamma, "gamma applied to the image")
+	fs.Float64Var(&c.Init.ImageThreshold, "image-threshold", c.Init.ImageThreshold, "values of the image at or above it become 1, the others 0 (negative disables it)")
+	fs.BoolVar(&c.Init.AlphaMask, "alpha-mask", c.Init.AlphaMask, "use the alpha channel of the image as a mask")
+	fs.IntVar(&c.Init.Frame, "frame", c.Init.Frame, "frame of an animated gif used as start grid")
+	fs.StringVar(&c.Output.Save, "save", c.Output.Save, "save the last state as an image (.png, .pgm, .ppm, .jpg or .gif), or a pattern of game_of_life (.rle, .cells or .mc)")
+	fs.IntVar(&c.Output.SaveBits, "save-bits", c.Output.SaveBits, "bits per channel of the saved image (8 or 16, png and netpbm only)")
+	fs.StringVar(&c.Output.SnapshotDir, "snapshot-dir", c.Output.SnapshotDir, "directory of the snapshots taken with the S key")
+	fs.Float64Var(&c.Brush.Radius, "brush-radius", c.Brush.Radius, "radius of the mouse brush (0 uses the kernel radius)")
+	fs.Float64Var(&c.Brush.Hardness, "brush-hardness", c.Brush.Hardness, "part of the brush radius painted at full strength")
+	fs.Float64Var(&c.Brush.Value, "brush-value", c.Brush.Value, "value painted by the brush")
+	fs.Float64Var(&c.Brush.Flow, "brush-flow", c.Brush.Flow, "strength of each brush dab, in [0,1]")
+	fs.StringVar(&c.Brush.Channels, "brush-channels", c.Brush.Channels, "channels painted by the brush (any of r, g and b)")
+	fs.BoolVar(&c.Renderer.FloatTexture, "float-texture", c.Renderer.FloatTexture, "upload the float fields to the GPU instead of 8 bits pixels")
+	fs.StringVar(&c.Renderer.Colormap, "colormap", c.Renderer.Colormap, "colormap of the display: rgb, "+strings.Join(registry.Colormaps, ", "))
+	fs.IntVar(&c.Renderer.ColormapChannel, "colormap-channel", c.Renderer.ColormapChannel, "channel shown with the colormap: 0, 1, 2 or 3 for the luminance")
+	fs.Float64Var(&c.Renderer.Gamma, "display-gamma", c.Renderer.Gamma, "gamma of the display")
+	fs.Float64Var(&c.Renderer.Contrast, "display-contrast", c.Renderer.Contrast, "contrast of the display")
+	fs.BoolVar(&c.Renderer.HUD, "hud", c.Renderer.HUD, "show the overlay with the step, fps and parameters (toggled with H)")
+	fs.StringVar(&c.Output.Timings, "timings", c.Output.Timings, "log the timings of each frame to this file (- for stderr)")
+	fs.Var(seedValue{&c.Seed}, "seed", "seed of the random generators (picked from the clock when it is not given)")
+	fs.StringVar(&c.Renderer.Name, "renderer", c.Renderer.Name, "how the frames are shown: "+strings.Join(registry.Renderers, ", "))
+	fs.StringVar(&c.Renderer.Dir, "render-dir", c.Renderer.Dir, "directory of the images written by the image renderer")
+	fs.StringVar(&c.Renderer.Format, "render-format", c.Renderer.Format, "format of the images written by the image renderer: png, ppm, pgm, jpg or gif")
+	fs.IntVar(&c.Renderer.Every, "render-every", c.Renderer.Every, "the image renderer writes a frame every K steps")
+	fs.StringVar(&c.Output.Serve, "serve", c.Output.Serve, "serve the frames over HTTP on this address, e.g. :8080")
+	fs.BoolVar(&c.Output.API, "api", c.Output.API, "with -serve, also serve the JSON API controlling the simulation under /api/")
+	fs.IntVar(&c.Output.Steps, "steps", c.Output.Steps, "stop after this many steps (0 runs until the renderer is closed)")
+	fs.Float64Var(&c.Renderer.StepsPerSecond, "sps", c.Renderer.StepsPerSecond, "target steps per second of the simulation (0 runs as fast as possible)")
+}
+
+// ParseParams parses a "name=value,name=value" list of numeric parameters.
+func ParseParams(list string) (map[string]float64, error) {
+	params := make(map[string]float64)
+	if list == "" {
+		return params, nil
+	}
+	for _, item := range strings.Split(list, ",") {
+		name, value, found := strings.Cut(item, "=")
+		if !found {
+			return nil, fmt.Errorf("parameter %q is not of the form name=value", item)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %q: %v", name, err)
+		}
+		params[strings.TrimSpace(name)] = v
+	}
+	return params, nil
+}
+
+// ParsePoint parses the "x,y" coordinates of a cell.
+func ParsePoint(s string) (int, int, error) {
+	xs, ys, found := strings.Cut(s, ",")
+	x, errX := strconv.Atoi(strings.TrimSpace(xs))
+	y, errY := strconv.Atoi(strings.TrimSpace(ys))
+	if !found || errX != nil || errY != nil {
+		return 0, 0, fmt.Errorf("invalid cell %q, expected x,y", s)
+	}
+	return x, y, nil
+}
+
+func formatParams(params map[string]float64) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	items := make([]string, len(names))
+	for i, name := range names {
+		items[i] = name + "=" + strconv.FormatFloat(params[name], 'g', -1, 64)
+	}
+	return strings.Join(items, ",")
+}
+
+// names returns the keys of m, sorted.
+func names(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// paramsValue is a flag of name=value parameters.
+type paramsValue map[string]float64
+
+func (p *paramsValue) String() string {
+	if p == nil {
+		return ""
+	}
+	return formatParams(*p)
+}
+
+func (p *paramsValue) Set(list string) error {
+	params, err := ParseParams(list)
+	if err != nil {
+		return err
+	}
+	*p = params
+	return nil
+}
+
+// rulesValue is a flag changing some of the rules with name=value parameters.
+type rulesValue struct{ rules *map[string]float64 }
+
+func (r rulesValue) String() string {
+	if r.rules == nil {
+		return ""
+	}
+	return formatParams(*r.rules)
+}
+
+func (r rulesValue) Set(list string) error {
+	params, err := ParseParams(list)
+	if err != nil {
+		return err
+	}
+	for name, value := range params {
+		if err := checkName("rule", name, registry.Rules); err != nil {
+			return err
+		}
+		if *r.rules == nil {
+			*r.rules = make(map[string]float64)
+		}
+		(*r.rules)[name] = value
+	}
+	return nil
+}
+
+// seedValue is the flag of the seed, nil until it is given so every int64 is a seed.
+type seedValue struct{ seed **int64 }
+
+func (s seedValue) String() string {
+	if s.seed == nil || *s.seed == nil {
+		return ""
+	}
+	return strconv.FormatInt(**s.seed, 10)
+}
+
+func (s seedValue) Set(value string) error {
+	seed, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid seed %q", value)
+	}
+	*s.seed = &seed
+	return nil
+}
+
+// optionalFloat is a flag left nil until it is given, so that 0 can be given.
+type optionalFloat struct{ value **float64 }
+
+func (f optionalFloat) String() string {
+	if f.value == nil || *f.value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(**f.value, 'g', -1, 64)
+}
+
+func (f optionalFloat) Set(s string) error {
+	value, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", s)
+	}
+	*f.value = &value
+	return nil
+}
+
+// listValue is a flag of comma separated names.
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(list string) error {
+	*l = strings.Split(list, ",")
+	return nil
+}
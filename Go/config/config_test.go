@@ -0,0 +1,68 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// withRegistry registers r for the duration of the test.
+func withRegistry(t *testing.T, r Registry) {
+	before := registry
+	Register(r)
+	t.Cleanup(func() { Register(before) })
+}
+
+var testRegistry = Registry{
+	Models:              []string{"game_of_life", "smoothlife", "smoothlife3d"},
+	LifeEngines:         []string{"bits", "hashlife"},
+	UnboundedLifeEngine: func(engine string) bool { return engine == "hashlife" },
+	LifeStepLimit: func(engine string) int {
+		if engine == "hashlife" {
+			return 48
+		}
+		return 10
+	},
+	MaxLifeZoom: 40,
+	Rules:       []string{"dt", "b1"},
+	Fields:      []string{"world1", "world2", "world3", "outer1"},
+	Renderers:   []string{"image", "opengl"},
+	Colormaps:   []string{"gray"},
+}
+
+func TestValidate(t *testing.T) {
+	withRegistry(t, testRegistry)
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("default configuration: %v", err)
+	}
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		err    string // part of the error, "" for none
+	}{
+		{"model", func(c *Config) { c.Model = "life" }, `unknown model "life"`},
+		{"engine", func(c *Config) { c.Engine = "sparse" }, `unknown game of life engine "sparse"`},
+		{"rule", func(c *Config) { c.Rule = "B9" }, "B9"},
+		{"rules", func(c *Config) { c.Rules = map[string]float64{"dx": 1} }, `unknown rule "dx"`},
+		{"npy field", func(c *Config) { c.Output.NpyFields = []string{"world1", "world4"} }, `unknown field of npy_fields "world4"`},
+		{"renderer", func(c *Config) { c.Renderer.Name = "svg" }, `unknown renderer "svg"`},
+		{"colormap", func(c *Config) { c.Renderer.Colormap = "jet" }, `unknown colormap "jet"`},
+		{"rgb colormap", func(c *Config) { c.Renderer.Colormap = "rgb" }, ""},
+		{"life step", func(c *Config) { c.LifeStep = 11 }, "life_step must be in [0,10]"},
+		{"hashlife step", func(c *Config) { c.Engine, c.LifeStep = "hashlife", 40 }, ""},
+		{"life zoom", func(c *Config) { c.LifeZoom = 2 }, "life_zoom"},
+		{"boundary", func(c *Config) { c.Model, c.Engine, c.Boundary.Mode = "game_of_life", "hashlife", "dead" }, "no boundary"},
+		{"threshold", func(c *Config) { t := 1.5; c.Init.Threshold = &t }, "threshold"},
+		{"threshold 0", func(c *Config) { t := 0.0; c.Init.Threshold = &t }, ""},
+	}
+	for _, test := range tests {
+		c := Default()
+		test.change(&c)
+		err := c.Validate()
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%s: %v", test.name, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%s: error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Presets are named configurations, applied over Default before the file and the flags.
+
+type Preset struct {
+	Description string
+	Apply       func(c *Config)
+}
+
+var Presets = map[string]Preset{
+	"random": {
+		Description: "random 1024 x 1024 grid with the default rules, in a window",
+		Apply: func(c *Config) {
+			c.Init.Random = true
+		},
+	},
+	"discs": {
+		Description: "discs of the kernel radius as in the SmoothLife paper",
+		Apply: func(c *Config) {
+			c.Init.Generator = "discs"
+			c.Init.Params = map[string]float64{"count": 40}
+		},
+	},
+	"terminal": {
+		Description: "small random grid with a smaller kernel, drawn in the terminal",
+		Apply: func(c *Config) {
+			c.Grid = Grid{Width: 128, Height: 128}
+			c.Kernel.Radius = 7
+			c.Init.Random = true
+			c.Renderer.Name = "terminal"
+		},
+	},
+	"smooth": {
+		Description: "smaller time step, slower and smoother evolution",
+		Apply: func(c *Config) {
+			c.Init.Random = true
+			c.Rules = map[string]float64{"dt": 0.05}
+		},
+	},
+	"big": {
+		Description: "2048 x 2048 grid with a bigger kernel, for large structures",
+		Apply: func(c *Config) {
+			c.Grid = Grid{Width: 2048, Height: 2048}
+			c.Kernel.Radius = 21
+			c.Init.Generator = "discs"
+			c.Init.Params = map[string]float64{"count": 60}
+		},
+	},
+	"life": {
+		Description: "game of life on a random 512 x 512 grid, a third of the cells alive",
+		Apply: func(c *Config) {
+			c.Model = "game_of_life"
+			c.Grid = Grid{Width: 512, Height: 512}
+			c.Init.Random = true
+		},
+	},
+	"batch": {
+		Description: "headless 512 x 512 run of 500 steps, frames every 10 steps and fields every 100",
+		Apply: func(c *Config) {
+			c.Grid = Grid{Width: 512, Height: 512}
+			c.Init.Random = true
+			c.Renderer.Name = "image"
+			c.Renderer.Every = 10
+			c.Output.Steps = 500
+			c.Output.NpyDir = "out"
+			c.Output.NpyEvery = 100
+			c.Output.Npz = true
+		},
+	},
+}
+
+// PresetNames returns the names of the presets, sorted.
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyPreset applies the preset called name to c.
+func ApplyPreset(name string, c *Config) error {
+	preset, ok := Presets[name]
+	if !ok {
+		return fmt.Errorf("unknown preset %q (available: %v)", name, PresetNames())
+	}
+	preset.Apply(c)
+	return nil
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// The models, the renderers and the colormaps are defined by packages using cgo
+// (fftw, GLFW). Their names are registered here by main, so config only depends
+// on pure Go packages and can be built and tested without them.
+
+// Registry is what the flags and Validate know of the other packages.
+type Registry struct {
+	Models      []string // see model.Names
+	LifeEngines []string // see model.LifeEngineNames
+	// UnboundedLifeEngine tells whether an engine of game_of_life has no boundary,
+	// only those can zoom and follow the cells.
+	UnboundedLifeEngine func(engine string) bool
+	// LifeStepLimit returns the biggest life_step of an engine of game_of_life.
+	LifeStepLimit func(engine string) int
+	MaxLifeZoom   int
+	Rules         []string // rules of smoothlife and smoothlife3d, see smoothlife3d.RuleNames
+	Fields        []string // float fields of smoothlife3d, see smoothlife3d.FieldNames
+	Generators    []string // initial conditions of -init, see smoothlife3d.InitNames
+	Renderers     []string // see renderer.Names
+	Colormaps     []string // colormaps other than rgb, see opengl_utils.ColormapNames
+}
+
+var registry = Registry{
+	UnboundedLifeEngine: func(string) bool { return false },
+	LifeStepLimit:       func(string) int { return 0 },
+}
+
+// Register sets the registry used by the flags and Validate, before they are used.
+func Register(r Registry) {
+	registry = r
+}
+
+// checkName returns an error when name is not one of names.
+func checkName(kind, name string, names []string) error {
+	if !slices.Contains(names, name) {
+		return fmt.Errorf("unknown %s %q (available: %s)", kind, name, strings.Join(names, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package game_of_life
+
+import (
+	"math/bits"
+	"runtime"
+	"sync"
+
+	"main/boundary"
+)
+
+// Board is a grid packed in bits, 64 cells per uint64: cell x of a line is
+// bit x%64 of word x/64, the bits of the last word past the width stay 0. A step
+// counts the neighbours of 64 cells at once with bitwise adders (SWAR) and the
+// lines are split in bands updated in parallel. It follows the same rule and
+// boundary as UpdateGrid, only much faster.
+type Board struct {
+	width, height int
+	words         int // words per line
+	cells, next   []uint64
+	outside       []uint64 // a line beyond a dead or fixed edge, set by Step
+}
+
+func NewBoard(width, height int) *Board {
+	words := (width + 63) / 64
+	return &Board{
+		width: width, height: height, words: words,
+		cells: make([]uint64, words*height),
+		next:  make([]uint64, words*height),
+	}
+}
+
+// RandomBoard returns a board where each cell is alive with the probability
+// threshold, with the same cells as GenerateRandomPixels for the same seed.
+func RandomBoard(width, height int, threshold float32) *Board {
+	b := NewBoard(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			b.Set(x, y, rng.Float32() < threshold)
+		}
+	}
+	return b
+}
+
+// LoadBoard returns a board from values in [0,1], the cells at or above threshold are alive.
+func LoadBoard(values []float64, width, height int, threshold float64) *Board {
+	b := NewBoard(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			b.Set(x, y, values[y*width+x] >= threshold)
+		}
+	}
+	return b
+}
+
+func (b *Board) Size() (int, int) {
+	return b.width, b.height
+}
+
+func (b *Board) Get(x, y int) bool {
+	return b.cells[y*b.words+x/64]&(1<<(x%64)) != 0
+}
+
+func (b *Board) Set(x, y int, alive bool) {
+	if alive {
+		b.cells[y*b.words+x/64] |= 1 << (x % 64)
+	} else {
+		b.cells[y*b.words+x/64] &^= 1 << (x % 64)
+	}
+}
+
+// Population returns the number of alive cells.
+func (b *Board) Population() int {
+	count := 0
+	for _, word := range b.cells {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+// Cells returns the alive cells.
+func (b *Board) Cells() []Cell {
+	var cells []Cell
+	for y := 0; y < b.height; y++ {
+		for i, word := range b.line(y) {
+			for ; word != 0; word &= word - 1 {
+				cells = append(cells, Cell{i*64 + bits.TrailingZeros64(word), y})
+			}
+		}
+	}
+	return cells
+}
+
+// Step advances the board by one generation with the current rule (see SetRule).
+func (b *Board) Step() {
+	born, survive := ruleCounts()
+	b.outside = make([]uint64, b.words)
+	if edgesAlive() {
+		for i := range b.outside {
+			b.outside[i] = ^uint64(0)
+		}
+		b.outside[b.words-1] &= b.mask()
+	}
+	bands := min(runtime.NumCPU(), b.height)
+	var wg sync.WaitGroup
+	for i := 0; i < bands; i++ {
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			// west and east neighbours of the 3 lines around y, rotated from line to line
+			west := make([][]uint64, 3)
+			east := make([][]uint64, 3)
+			for l := range west {
+				west[l] = make([]uint64, b.words)
+				east[l] = make([]uint64, b.words)
+			}
+			b.shift(b.around(start-1), west[0], east[0])
+			b.shift(b.line(start), west[1], east[1])
+			for y := start; y < end; y++ {
+				b.shift(b.around(y+1), west[2], east[2])
+				b.stepLine(y, west, east, born, survive)
+				west[0], west[1], west[2] = west[1], west[2], west[0]
+				east[0], east[1], east[2] = east[1], east[2], east[0]
+			}
+		}(i*b.height/bands, (i+1)*b.height/bands)
+	}
+	wg.Wait()
+	b.cells, b.next = b.next, b.cells
+}
+
+func (b *Board) line(y int) []uint64 {
+	return b.cells[y*b.words : (y+1)*b.words]
+}
+
+// around returns the line y seen by the lines next to it, y can be -1 or height:
+// the lines beyond the edges follow the boundary (see SetBoundary).
+func (b *Board) around(y int) []uint64 {
+	if y, inside := edges.Index(y, b.height); inside {
+		return b.line(y)
+	}
+	return b.outside
+}
+
+// edgesAlive returns true if the cells beyond the edges are alive, for a fixed boundary.
+func edgesAlive() bool {
+	return edges.Mode == boundary.Fixed && edges.Value >= 0.5
+}
+
+// shift writes in west (east) the line where each cell holds its west (east)
+// neighbour, the cells beyond the edges follow the boundary.
+func (b *Board) shift(line, west, east []uint64) {
+	last := b.words - 1
+	top := uint((b.width - 1) % 64) // bit of the last cell in the last word
+	first := line[0] & 1
+	lastCell := (line[last] >> top) & 1
+	switch {
+	case edges.Mode == boundary.Reflect:
+		first, lastCell = lastCell, first // each edge cell is its own neighbour
+	case edges.Mode != boundary.Torus:
+		first, lastCell = 0, 0
+		if edgesAlive() {
+			first, lastCell = 1, 1
+		}
+	}
+	for i := range line {
+		var before, after uint64
+		if i > 0 {
+			before = line[i-1] >> 63
+		} else {
+			before = lastCell
+		}
+		if i < last {
+			after = line[i+1] << 63
+		}
+		west[i] = line[i]<<1 | before
+		east[i] = line[i]>>1 | after
+	}
+	west[last] &= b.mask()
+	east[last] |= first << top
+}
+
+// mask returns the bits of the last word of a line that are cells.
+func (b *Board) mask() uint64 {
+	if b.width%64 == 0 {
+		return ^uint64(0)
+	}
+	return 1<<(b.width%64) - 1
+}
+
+// stepLine computes line y of the next generation.
+func (b *Board) stepLine(y int, west, east [][]uint64, born, survive []int) {
+	above := b.around(y - 1)
+	center := b.line(y)
+	below := b.around(y + 1)
+	next := b.next[y*b.words : (y+1)*b.words]
+	for i := range center {
+		next[i] = nextWord(center[i], [8]uint64{
+			west[0][i], above[i], east[0][i],
+			west[1][i], east[1][i],
+			west[2][i], below[i], east[2][i],
+		}, born, survive)
+	}
+	next[b.words-1] &= b.mask()
+}
+
+// ruleCounts returns the birth and survival masks of the rule, as lists of neighbour counts.
+func ruleCounts() (born, survive []int) {
+	for n := 0; n <= 8; n++ {
+		if rule.Birth[n] {
+			born = append(born, n)
+		}
+		if rule.Survival[n] {
+			survive = append(survive, n)
+		}
+	}
+	return born, survive
+}
+
+// nextWord returns the next generation of 64 cells from the words of their 8
+// neighbours. The neighbours of each cell are summed in a 4 bits counter stored as
+// 4 words, one bit of the count per word.
+func nextWord(center uint64, neighbours [8]uint64, born, survive []int) uint64 {
+	var c0, c1, c2, c3 uint64
+	for _, neighbour := range neighbours {
+		carry := c0 & neighbour
+		c0 ^= neighbour
+		carry, c1 = c1&carry, c1^carry
+		carry, c2 = c2&carry, c2^carry
+		c3 |= carry
+	}
+	count := func(n int) uint64 {
+		// cells with exactly n neighbours
+		m := ^uint64(0)
+		for bit, c := range [4]uint64{c0, c1, c2, c3} {
+			if n&(1<<bit) != 0 {
+				m &= c
+			} else {
+				m &^= c
+			}
+		}
+		return m
+	}
+	var birth, survival uint64
+	for _, n := range born {
+		birth |= count(n)
+	}
+	for _, n := range survive {
+		survival |= count(n)
+	}
+	return (^center & birth) | (center & survival)
+}
+
+// Render writes the board as R,G,B pixels, white for the alive cells.
+func (b *Board) Render(pixels []uint8) []uint8 {
+	if len(pixels) != b.width*b.height*3 {
+		pixels = make([]uint8, b.width*b.height*3)
+	}
+	for y := 0; y < b.height; y++ {
+		line := b.line(y)
+		for x := 0; x < b.width; x++ {
+			v := uint8(0)
+			if line[x/64]&(1<<(x%64)) != 0 {
+				v = 255
+			}
+			index := (y*b.width + x) * 3
+			pixels[index], pixels[index+1], pixels[index+2] = v, v, v
+		}
+	}
+	return pixels
+}
@@ -0,0 +1,131 @@
+package game_of_life
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"main/boundary"
+)
+
+// withBoundary sets the boundary b for the duration of the test.
+func withBoundary(t testing.TB, b boundary.Boundary) {
+	before := GetBoundary()
+	SetBoundary(b)
+	t.Cleanup(func() { SetBoundary(before) })
+}
+
+func randomValues(width, height int, seed int64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	values := make([]float64, width*height)
+	for i := range values {
+		values[i] = rng.Float64()
+	}
+	return values
+}
+
+// TestBoardMatchesUpdateGrid checks that Board and UpdateGrid compute the same
+// cells, on widths around the 64 bits of a word.
+func TestBoardMatchesUpdateGrid(t *testing.T) {
+	boundaries := []boundary.Boundary{
+		{Mode: boundary.Torus},
+		{Mode: boundary.Dead},
+		{Mode: boundary.Reflect},
+		{Mode: boundary.Fixed, Value: 1},
+	}
+	for _, rule := range []string{"B3/S23", "B36/S23", "B2/S", "B3678/S34678", "B1357/S1357"} {
+		for _, b := range boundaries {
+			for _, width := range []int{1, 2, 63, 64, 65, 130} {
+				t.Run(fmt.Sprintf("%s/%s/%d", rule, b, width), func(t *testing.T) {
+					withRule(t, rule)
+					withBoundary(t, b)
+					height := 37
+					values := randomValues(width, height, int64(width))
+					pixels := LoadCells(values, width, height, 0.6)
+					board := LoadBoard(values, width, height, 0.6)
+					for generation := 1; generation <= 30; generation++ {
+						pixels = UpdateGrid(pixels)
+						board.Step()
+						for y := 0; y < height; y++ {
+							for x := 0; x < width; x++ {
+								if alive := pixels[y][x][0] == 255; alive != board.Get(x, y) {
+									t.Fatalf("generation %d, cell (%d,%d): UpdateGrid %v, Board %v", generation, x, y, alive, board.Get(x, y))
+								}
+							}
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// TestGliderAbsorbedByDeadBoundary checks that a glider stops at a dead edge
+// instead of coming back from the other side, it ends as a block in the corner.
+func TestGliderAbsorbedByDeadBoundary(t *testing.T) {
+	glider := []Cell{{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2}}
+	block := []Cell{{14, 14}, {15, 14}, {14, 15}, {15, 15}}
+	tests := []struct {
+		boundary boundary.Boundary
+		want     []Cell
+	}{
+		{boundary.Boundary{Mode: boundary.Dead}, block},
+		{boundary.Boundary{Mode: boundary.Torus}, glider}, // 64 generations move it by 16 cells, back to its start
+	}
+	for _, test := range tests {
+		t.Run(test.boundary.String(), func(t *testing.T) {
+			withBoundary(t, test.boundary)
+			board := NewBoard(16, 16)
+			values := make([]float64, 16*16)
+			for _, c := range glider {
+				board.Set(c.X, c.Y, true)
+				values[c.Y*16+c.X] = 1
+			}
+			pixels := LoadCells(values, 16, 16, 0.5)
+			for i := 0; i < 64; i++ {
+				board.Step()
+				pixels = UpdateGrid(pixels)
+			}
+			want := sortedCells(test.want)
+			for name, got := range map[string][]Cell{"Board": sortedCells(board.Cells()), "UpdateGrid": aliveCells(pixels)} {
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s after 64 generations: %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBoardCells(t *testing.T) {
+	board := NewBoard(130, 3)
+	cells := []Cell{{0, 0}, {63, 1}, {64, 1}, {129, 2}}
+	for _, c := range cells {
+		board.Set(c.X, c.Y, true)
+	}
+	if board.Population() != len(cells) {
+		t.Errorf("population %d, want %d", board.Population(), len(cells))
+	}
+	got := sortedCells(board.Cells())
+	for i, c := range sortedCells(cells) {
+		if i >= len(got) || got[i] != c {
+			t.Fatalf("cells %v, want %v", got, cells)
+		}
+	}
+}
+
+func BenchmarkBoardStep4096(b *testing.B) {
+	board := LoadBoard(randomValues(4096, 4096, 1), 4096, 4096, 0.67)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		board.Step()
+	}
+}
+
+func BenchmarkUpdateGrid4096(b *testing.B) {
+	pixels := LoadCells(randomValues(4096, 4096, 1), 4096, 4096, 0.67)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pixels = UpdateGrid(pixels)
+	}
+}
@@ -2,13 +2,24 @@ package game_of_life
 
 import (
   "math/rand"
+
+  "main/boundary"
 )
 
 var (
   width = 1000
   height = 1000
+
+  rng = rand.New(rand.NewSource(1)) // random source of this engine, see SetSeed
+
+  edges boundary.Boundary // what the cells next to the edges see beyond them, see SetBoundary
 )
 
+func SetSeed(seed int64) {
+  // Reseeds the random source used by GenerateRandomPixels so a run can be reproduced
+  rng = rand.New(rand.NewSource(seed))
+}
+
 
 // genere des pixels avec une couleur random
 func GenerateRandomPixels(grid_width int, grid_height int, threshold float32) [][][]uint8 {
@@ -18,7 +29,7 @@ func GenerateRandomPixels(grid_width int, grid_height int, threshold float32) []
 	for y := range nestedPixels {
 		nestedPixels[y] = make([][]uint8, width)
 		for x := range nestedPixels[y] {
-			if rand.Float32() < threshold { 
+			if rng.Float32() < threshold { 
 				nestedPixels[y][x] = []uint8{
 					uint8(255), // R
 					uint8(255), // G
@@ -32,16 +43,48 @@ func GenerateRandomPixels(grid_width int, grid_height int, threshold float32) []
 	return nestedPixels
 }
 
-func checkNeighbors(pixels [][][]uint8, x int, y int) int { 
-    if x + 1 > width {
-      x = 0
-    } else if x-1 < 0 {
-      x = width - 1
+// Loads a grid from values in [0,1], the cells at or above threshold are alive
+func LoadCells(values []float64, grid_width int, grid_height int, threshold float64) [][][]uint8 {
+  width = grid_width
+  height = grid_height
+  nestedPixels := make([][][]uint8, height)
+  for y := range nestedPixels {
+    nestedPixels[y] = make([][]uint8, width)
+    for x := range nestedPixels[y] {
+      if values[y*width+x] >= threshold {
+        nestedPixels[y][x] = []uint8{255, 255, 255}
+      } else {
+        nestedPixels[y][x] = []uint8{0, 0, 0}
+      }
     }
-    if y + 1 > height {
-      y = 0
-    } else if y-1 < 0 {
-      y = height - 1
+  }
+  return nestedPixels
+}
+
+func Size() (int, int) {
+  // Returns the width and height of the current grid
+  return width, height
+}
+
+func GetBoundary() boundary.Boundary {
+  return edges
+}
+
+func SetBoundary(b boundary.Boundary) {
+  // Changes the boundary of UpdateGrid and Board, used from the next update. A fixed
+  // boundary is made of alive cells when its value is at least 0.5
+  edges = b
+}
+
+func checkNeighbors(pixels [][][]uint8, x int, y int) int { 
+    // The coordinates out of the grid follow the boundary (see SetBoundary)
+    x, insideX := edges.Index(x, width)
+    y, insideY := edges.Index(y, height)
+    if !insideX || !insideY {
+      if edges.Mode == boundary.Fixed && edges.Value >= 0.5 {
+        return 1
+      }
+      return 0
     }
     if pixels[y][x][0] == 255 {
       return 1 
@@ -70,12 +113,10 @@ func updateLine(pixels [][][]uint8, y int) [][]uint8 {
   
 		for x := range newPixels {
       neigh := countNeighbors(pixels, x, y)
-				if neigh < 2 || neigh > 3 {
-          newPixels[x] = []uint8{0, 0, 0}
-        } else if neigh == 2 {
-          newPixels[x] = pixels[y][x]
-        } else {
+				if rule.Next(pixels[y][x][0] == 255, neigh) {
           newPixels[x] = []uint8{255, 255, 255}
+        } else {
+          newPixels[x] = []uint8{0, 0, 0}
         }
   }
   return newPixels
@@ -0,0 +1,33 @@
+package game_of_life
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestSeedReplays checks that two runs with the same seed are identical, for both
+// bounded engines, and that another seed gives another grid.
+func TestSeedReplays(t *testing.T) {
+	run := func(seed int64) ([]Cell, []Cell) {
+		SetSeed(seed)
+		pixels := GenerateRandomPixels(64, 48, 0.33)
+		SetSeed(seed)
+		board := RandomBoard(64, 48, 0.33)
+		for i := 0; i < 20; i++ {
+			pixels = UpdateGrid(pixels)
+			board.Step()
+		}
+		return aliveCells(pixels), sortedCells(board.Cells())
+	}
+	cells, board := run(0)
+	againCells, againBoard := run(0)
+	if !reflect.DeepEqual(cells, againCells) || !reflect.DeepEqual(board, againBoard) {
+		t.Errorf("two runs with the seed 0 differ")
+	}
+	if !reflect.DeepEqual(cells, board) {
+		t.Errorf("UpdateGrid and Board differ for the same seed")
+	}
+	if other, _ := run(1); reflect.DeepEqual(cells, other) {
+		t.Errorf("the seeds 0 and 1 give the same grid")
+	}
+}
@@ -0,0 +1,501 @@
+package game_of_life
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HashLife (Gosper's algorithm). The plane is a quadtree whose nodes are
+// hash-consed: two nodes with the same cells are the same pointer, so the result
+// of a node (its center 2^k generations later) is computed once and reused
+// everywhere the node appears. Regular patterns advance billions of generations in
+// a few milliseconds. The universe is unbounded, nothing wraps around.
+
+// node is a square of 2^level x 2^level cells, made of 4 nodes of the level below.
+type node struct {
+	nw, ne, sw, se *node // nil for the leaves (level 0, a single cell)
+	level          int
+	population     int
+	next           *node // memoised result, see Universe.result
+	nextStep       int   // log2 of the generations of next, -1 when there is none
+	marked         bool  // reachable, used by GC
+}
+
+type quad struct{ nw, ne, sw, se *node }
+
+var (
+	deadLeaf  = &node{nextStep: -1}
+	aliveLeaf = &node{population: 1, nextStep: -1}
+)
+
+// DefaultMaxNodes is the size of the node cache of a new universe, about 100 bytes per node.
+const DefaultMaxNodes = 1 << 21
+
+// MaxStep is the biggest log2 of the generations of a step, so the coordinates fit in an int64.
+const MaxStep = 48
+
+type Universe struct {
+	root       *node
+	x, y       int64 // coordinates of the top left cell of root
+	nodes      map[quad]*node
+	empty      []*node // empty node of each level
+	rule       Rule    // rule of the memoised results
+	generation uint64
+
+	MaxNodes int // the cache is collected by Step when it has more nodes, see GC
+}
+
+func NewUniverse() *Universe {
+	u := &Universe{nodes: make(map[quad]*node), empty: []*node{deadLeaf}, rule: rule, MaxNodes: DefaultMaxNodes}
+	u.root = u.emptyNode(3)
+	return u
+}
+
+// LoadUniverse returns a universe with the cells of a width x height grid, the
+// cell (0,0) of the grid is the cell (0,0) of the universe.
+func LoadUniverse(width, height int, alive func(x, y int) bool) *Universe {
+	u := NewUniverse()
+	level := 3
+	for 1<<level < max(width, height) {
+		level++
+	}
+	u.root = u.build(level, 0, 0, width, height, alive)
+	return u
+}
+
+// build returns the node of level whose top left cell is (x,y) of a width x height grid.
+func (u *Universe) build(level, x, y, width, height int, alive func(x, y int) bool) *node {
+	if x >= width || y >= height {
+		return u.emptyNode(level)
+	}
+	if level == 0 {
+		if alive(x, y) {
+			return aliveLeaf
+		}
+		return deadLeaf
+	}
+	half := 1 << (level - 1)
+	return u.join(
+		u.build(level-1, x, y, width, height, alive), u.build(level-1, x+half, y, width, height, alive),
+		u.build(level-1, x, y+half, width, height, alive), u.build(level-1, x+half, y+half, width, height, alive))
+}
+
+// Clone returns a copy of u with its own node cache, stepping one does not change the other.
+func (u *Universe) Clone() *Universe {
+	c := NewUniverse()
+	copies := make(map[*node]*node)
+	var clone func(n *node) *node
+	clone = func(n *node) *node {
+		if n.level == 0 {
+			return n
+		}
+		if copied, ok := copies[n]; ok {
+			return copied
+		}
+		copied := c.join(clone(n.nw), clone(n.ne), clone(n.sw), clone(n.se))
+		copies[n] = copied
+		return copied
+	}
+	c.root = clone(u.root)
+	c.x, c.y, c.generation, c.MaxNodes = u.x, u.y, u.generation, u.MaxNodes
+	return c
+}
+
+func (u *Universe) join(nw, ne, sw, se *node) *node {
+	key := quad{nw, ne, sw, se}
+	if n, ok := u.nodes[key]; ok {
+		return n
+	}
+	n := &node{
+		nw: nw, ne: ne, sw: sw, se: se,
+		level:      nw.level + 1,
+		population: nw.population + ne.population + sw.population + se.population,
+		nextStep:   -1,
+	}
+	u.nodes[key] = n
+	return n
+}
+
+func (u *Universe) emptyNode(level int) *node {
+	for len(u.empty) <= level {
+		e := u.empty[len(u.empty)-1]
+		u.empty = append(u.empty, u.join(e, e, e, e))
+	}
+	return u.empty[level]
+}
+
+// centre returns the node of the level below at the center of n.
+func (u *Universe) centre(n *node) *node {
+	return u.join(n.nw.se, n.ne.sw, n.sw.ne, n.se.nw)
+}
+
+// expand doubles the size of the root, it stays at the center.
+func (u *Universe) expand() {
+	r := u.root
+	e := u.emptyNode(r.level - 1)
+	half := int64(1) << (r.level - 1)
+	u.root = u.join(u.join(e, e, e, r.nw), u.join(e, e, r.ne, e), u.join(e, r.sw, e, e), u.join(r.se, e, e, e))
+	u.x -= half
+	u.y -= half
+}
+
+// centred returns whether all the cells are in the middle quarter of the root,
+// far enough from its sides to compute its result.
+func (u *Universe) centred() bool {
+	r := u.root
+	inner := u.join(r.nw.se.se, r.ne.sw.sw, r.sw.ne.ne, r.se.nw.nw)
+	return inner.population == r.population
+}
+
+// result returns the center of n (a node of the level below) 2^k generations
+// later, with 0 <= k <= n.level-2. The cells outside n can not reach it in time.
+func (u *Universe) result(n *node, k int) *node {
+	if n.population == 0 {
+		return u.emptyNode(n.level - 1)
+	}
+	if n.nextStep == k {
+		return n.next
+	}
+	var r *node
+	if n.level == 2 {
+		r = u.base(n)
+	} else {
+		// the 9 overlapping nodes of the level below, from the grand children of n
+		nw, ne, sw, se := n.nw, n.ne, n.sw, n.se
+		sub := [9]*node{
+			nw, u.join(nw.ne, ne.nw, nw.se, ne.sw), ne,
+			u.join(nw.sw, nw.se, sw.nw, sw.ne), u.join(nw.se, ne.sw, sw.ne, se.nw), u.join(ne.sw, ne.se, se.nw, se.ne),
+			sw, u.join(sw.ne, se.nw, sw.se, se.sw), se,
+		}
+		// At full speed each stage advances half of the generations, otherwise
+		// the first stage only takes the centers and the second one advances them all.
+		fullSpeed := k == n.level-2
+		var t [9]*node
+		for i, s := range sub {
+			if fullSpeed {
+				t[i] = u.result(s, k-1)
+			} else {
+				t[i] = u.centre(s)
+			}
+		}
+		second := k
+		if fullSpeed {
+			second = k - 1
+		}
+		r = u.join(
+			u.result(u.join(t[0], t[1], t[3], t[4]), second),
+			u.result(u.join(t[1], t[2], t[4], t[5]), second),
+			u.result(u.join(t[3], t[4], t[6], t[7]), second),
+			u.result(u.join(t[4], t[5], t[7], t[8]), second),
+		)
+	}
+	n.next, n.nextStep = r, k
+	return r
+}
+
+// base returns the 2 x 2 center of a 4 x 4 node after one generation.
+func (u *Universe) base(n *node) *node {
+	var cells [4][4]bool
+	for qy, row := range [2][2]*node{{n.nw, n.ne}, {n.sw, n.se}} {
+		for qx, q := range row {
+			for cy, crow := range [2][2]*node{{q.nw, q.ne}, {q.sw, q.se}} {
+				for cx, c := range crow {
+					cells[qy*2+cy][qx*2+cx] = c.population == 1
+				}
+			}
+		}
+	}
+	var next [4]*node
+	for i, c := range [4][2]int{{1, 1}, {2, 1}, {1, 2}, {2, 2}} {
+		count := 0
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if (dx != 0 || dy != 0) && cells[c[1]+dy][c[0]+dx] {
+					count++
+				}
+			}
+		}
+		next[i] = deadLeaf
+		if u.rule.Next(cells[c[1]][c[0]], count) {
+			next[i] = aliveLeaf
+		}
+	}
+	return u.join(next[0], next[1], next[2], next[3])
+}
+
+// Step advances the universe by 2^k generations with the current rule (see SetRule),
+// which must not have B0. The node cache is collected when it is bigger than MaxNodes.
+func (u *Universe) Step(k int) {
+	if u.rule != rule {
+		// The memoised results were computed with another rule
+		for _, n := range u.nodes {
+			n.next, n.nextStep = nil, -1
+		}
+		u.rule = rule
+	}
+	for u.root.level < k+3 || !u.centred() {
+		u.expand()
+	}
+	u.root = u.result(u.root, k)
+	quarter := int64(1) << (u.root.level - 1)
+	u.x += quarter
+	u.y += quarter
+	u.generation += 1 << k
+	if len(u.nodes) > u.MaxNodes {
+		u.GC()
+	}
+}
+
+// GC removes from the cache the nodes that are not part of the universe, with the
+// memoised results pointing to them.
+func (u *Universe) GC() {
+	var mark func(n *node)
+	mark = func(n *node) {
+		if n.level == 0 || n.marked {
+			return
+		}
+		n.marked = true
+		mark(n.nw)
+		mark(n.ne)
+		mark(n.sw)
+		mark(n.se)
+	}
+	mark(u.root)
+	for _, e := range u.empty {
+		mark(e)
+	}
+	for key, n := range u.nodes {
+		if !n.marked {
+			delete(u.nodes, key)
+		}
+	}
+	for _, n := range u.nodes {
+		if n.next != nil && n.next.level > 0 && !n.next.marked {
+			n.next, n.nextStep = nil, -1
+		}
+	}
+	for _, n := range u.nodes {
+		n.marked = false
+	}
+}
+
+// Move moves all the cells by (dx,dy).
+func (u *Universe) Move(dx, dy int64) {
+	u.x += dx
+	u.y += dy
+}
+
+// Box returns the top left cell and the size of the smallest box around the alive
+// cells, 0 x 0 when there is none.
+func (u *Universe) Box() (x, y, width, height int64) {
+	if u.root.population == 0 {
+		return u.x, u.y, 0, 0
+	}
+	// The distance of the alive cells to a side of the root: in the children on this
+	// side (near) when they have some, otherwise in the others (far), half a node away.
+	distance := func(near, far func(n *node) [2]*node) int64 {
+		memo := make(map[*node]int64)
+		var walk func(n *node) int64
+		walk = func(n *node) int64 {
+			if n.level == 0 {
+				return 0
+			}
+			if d, ok := memo[n]; ok {
+				return d
+			}
+			d := int64(-1)
+			for _, c := range near(n) {
+				if c.population > 0 && (d < 0 || walk(c) < d) {
+					d = walk(c)
+				}
+			}
+			if d < 0 {
+				half := int64(1) << (n.level - 1)
+				for _, c := range far(n) {
+					if c.population > 0 && (d < 0 || half+walk(c) < d) {
+						d = half + walk(c)
+					}
+				}
+			}
+			memo[n] = d
+			return d
+		}
+		return walk(u.root)
+	}
+	west := func(n *node) [2]*node { return [2]*node{n.nw, n.sw} }
+	east := func(n *node) [2]*node { return [2]*node{n.ne, n.se} }
+	north := func(n *node) [2]*node { return [2]*node{n.nw, n.ne} }
+	south := func(n *node) [2]*node { return [2]*node{n.sw, n.se} }
+	size := int64(1) << u.root.level
+	left, right := distance(west, east), distance(east, west)
+	top, bottom := distance(north, south), distance(south, north)
+	return u.x + left, u.y + top, size - left - right, size - top - bottom
+}
+
+// Transform applies the comma separated transformations of list to the root, in
+// order. They are the ones of Pattern.Transform.
+func (u *Universe) Transform(list string) error {
+	if list == "" {
+		return nil
+	}
+	steps := map[string][]string{
+		"rot90": {"transpose", "flipx"}, "rot180": {"flipx", "flipy"}, "rot270": {"transpose", "flipy"},
+		"flipx": {"flipx"}, "flipy": {"flipy"}, "transpose": {"transpose"},
+	}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := steps[name]; !ok {
+			return fmt.Errorf("unknown transformation %q (available: %v)", name, TransformNames())
+		}
+		for _, step := range steps[name] {
+			memo := make(map[*node]*node)
+			var transform func(n *node) *node
+			transform = func(n *node) *node {
+				if n.level == 0 || n.population == 0 {
+					return n
+				}
+				if t, ok := memo[n]; ok {
+					return t
+				}
+				var t *node
+				switch step {
+				case "flipx":
+					t = u.join(transform(n.ne), transform(n.nw), transform(n.se), transform(n.sw))
+				case "flipy":
+					t = u.join(transform(n.sw), transform(n.se), transform(n.nw), transform(n.ne))
+				default:
+					t = u.join(transform(n.nw), transform(n.sw), transform(n.ne), transform(n.se))
+				}
+				memo[n] = t
+				return t
+			}
+			u.root = transform(u.root)
+		}
+	}
+	return nil
+}
+
+// NodeCount returns the number of nodes in the cache.
+func (u *Universe) NodeCount() int {
+	return len(u.nodes)
+}
+
+func (u *Universe) Population() int {
+	return u.root.population
+}
+
+func (u *Universe) Generation() uint64 {
+	return u.generation
+}
+
+// Bounds returns the top left cell and the size of the root, the cells are inside.
+func (u *Universe) Bounds() (x, y, size int64) {
+	return u.x, u.y, int64(1) << u.root.level
+}
+
+func (u *Universe) Get(x, y int64) bool {
+	x, y = x-u.x, y-u.y
+	n := u.root
+	size := int64(1) << n.level
+	if x < 0 || y < 0 || x >= size || y >= size {
+		return false
+	}
+	for n.level > 0 {
+		half := int64(1) << (n.level - 1)
+		switch {
+		case x < half && y < half:
+			n = n.nw
+		case y < half:
+			n, x = n.ne, x-half
+		case x < half:
+			n, y = n.sw, y-half
+		default:
+			n, x, y = n.se, x-half, y-half
+		}
+	}
+	return n.population == 1
+}
+
+func (u *Universe) Set(x, y int64, alive bool) {
+	for x < u.x || y < u.y || x >= u.x+int64(1)<<u.root.level || y >= u.y+int64(1)<<u.root.level {
+		u.expand()
+	}
+	var set func(n *node, x, y int64) *node
+	set = func(n *node, x, y int64) *node {
+		if n.level == 0 {
+			if alive {
+				return aliveLeaf
+			}
+			return deadLeaf
+		}
+		half := int64(1) << (n.level - 1)
+		nw, ne, sw, se := n.nw, n.ne, n.sw, n.se
+		switch {
+		case x < half && y < half:
+			nw = set(nw, x, y)
+		case y < half:
+			ne = set(ne, x-half, y)
+		case x < half:
+			sw = set(sw, x, y-half)
+		default:
+			se = set(se, x-half, y-half)
+		}
+		return u.join(nw, ne, sw, se)
+	}
+	u.root = set(u.root, x-u.x, y-u.y)
+}
+
+// Cells returns the alive cells.
+func (u *Universe) Cells() []Cell {
+	cells := make([]Cell, 0, u.root.population)
+	var walk func(n *node, x, y int64)
+	walk = func(n *node, x, y int64) {
+		switch {
+		case n.population == 0:
+		case n.level == 0:
+			cells = append(cells, Cell{int(x), int(y)})
+		default:
+			half := int64(1) << (n.level - 1)
+			walk(n.nw, x, y)
+			walk(n.ne, x+half, y)
+			walk(n.sw, x, y+half)
+			walk(n.se, x+half, y+half)
+		}
+	}
+	walk(u.root, u.x, u.y)
+	return cells
+}
+
+// Render draws the cells of the viewport of width x height pixels whose top left
+// cell is (x0,y0) as R,G,B pixels. A pixel shows 2^zoom x 2^zoom cells, it is
+// white when one of them is alive.
+func (u *Universe) Render(pixels []uint8, x0, y0 int64, width, height, zoom int) []uint8 {
+	if len(pixels) != width*height*3 {
+		pixels = make([]uint8, width*height*3)
+	}
+	clear(pixels)
+	x1, y1 := x0+int64(width)<<zoom, y0+int64(height)<<zoom
+	var draw func(n *node, nx, ny int64)
+	draw = func(n *node, nx, ny int64) {
+		size := int64(1) << n.level
+		if n.population == 0 || nx+size <= x0 || ny+size <= y0 || nx >= x1 || ny >= y1 {
+			return
+		}
+		// A node is drawn when it is inside a single pixel, the view is not aligned
+		// on the nodes: a small node can straddle two pixels and a big one the sides
+		px, py := (nx-x0)>>zoom, (ny-y0)>>zoom
+		if n.level == 0 || (nx >= x0 && ny >= y0 && nx+size <= x1 && ny+size <= y1 &&
+			(nx+size-1-x0)>>zoom == px && (ny+size-1-y0)>>zoom == py) {
+			index := (int(py)*width + int(px)) * 3
+			pixels[index], pixels[index+1], pixels[index+2] = 255, 255, 255
+			return
+		}
+		half := size / 2
+		draw(n.nw, nx, ny)
+		draw(n.ne, nx+half, ny)
+		draw(n.sw, nx, ny+half)
+		draw(n.se, nx+half, ny+half)
+	}
+	draw(u.root, u.x, u.y)
+	return pixels
+}
@@ -0,0 +1,240 @@
+package game_of_life
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"main/boundary"
+)
+
+// soupBoard returns a board of size x size cells with a random size/8 x size/8
+// soup in its middle, far enough from the edges for the generations of the tests.
+func soupBoard(size int, seed int64) *Board {
+	board := NewBoard(size, size)
+	soup := size / 8
+	for i, v := range randomValues(soup, soup, seed) {
+		if v < 0.4 {
+			board.Set(size/2-soup/2+i%soup, size/2-soup/2+i/soup, true)
+		}
+	}
+	return board
+}
+
+// gliderBoard returns a board of size x size cells with a glider in its middle, going to the south east.
+func gliderBoard(size int) *Board {
+	board := NewBoard(size, size)
+	for _, c := range []Cell{{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2}} {
+		board.Set(size/2+c.X, size/2+c.Y, true)
+	}
+	return board
+}
+
+// boardUniverse returns the universe with the cells of board.
+func boardUniverse(board *Board) *Universe {
+	width, height := board.Size()
+	return LoadUniverse(width, height, board.Get)
+}
+
+// TestStepMatchesBoard checks that Step(k) gives the cells of 2^k steps of Board,
+// with steps of various sizes in a row, so the results memoised for a k are not
+// used for another one.
+func TestStepMatchesBoard(t *testing.T) {
+	withBoundary(t, boundary.Boundary{Mode: boundary.Dead})
+	steps := []int{0, 3, 1, 5, 3, 0, 2}
+	boards := map[string]func() *Board{
+		"soup":   func() *Board { return soupBoard(512, 1) },
+		"glider": func() *Board { return gliderBoard(512) },
+	}
+	for _, rule := range []string{"B3/S23", "B36/S23", "B3678/S34678", "B2/S", "B1357/S1357"} {
+		for name, newBoard := range boards {
+			t.Run(fmt.Sprintf("%s/%s", rule, name), func(t *testing.T) {
+				withRule(t, rule)
+				board := newBoard()
+				u := boardUniverse(board)
+				generation := 0
+				for _, k := range steps {
+					u.Step(k)
+					for i := 0; i < 1<<k; i++ {
+						board.Step()
+					}
+					generation += 1 << k
+					if u.Generation() != uint64(generation) {
+						t.Fatalf("generation %d, want %d", u.Generation(), generation)
+					}
+					if got, want := sortedCells(u.Cells()), sortedCells(board.Cells()); !reflect.DeepEqual(got, want) {
+						t.Fatalf("generation %d after Step(%d): %d cells differ from the %d cells of Board", generation, k, len(got), len(want))
+					}
+					if u.Population() != board.Population() {
+						t.Fatalf("generation %d: population %d, want %d", generation, u.Population(), board.Population())
+					}
+				}
+			})
+		}
+	}
+}
+
+// TestRuleChangeForgetsResults checks that the results memoised with a rule are not
+// used after SetRule: the still lifes of Conway die without survival.
+func TestRuleChangeForgetsResults(t *testing.T) {
+	withBoundary(t, boundary.Boundary{Mode: boundary.Dead})
+	withRule(t, "B3/S23")
+	board := soupBoard(256, 2)
+	u := boardUniverse(board)
+	steps := func(n int) {
+		for i := 0; i < n; i++ {
+			board.Step()
+		}
+	}
+	u.Step(3)
+	u.Step(3) // the nodes of the still lifes come back, with their results
+	steps(16)
+	withRule(t, "B3/S")
+	u.Step(3)
+	steps(8)
+	if got, want := sortedCells(u.Cells()), sortedCells(board.Cells()); !reflect.DeepEqual(got, want) {
+		t.Errorf("after a change of rule: %d cells, want the %d cells of Board", len(got), len(want))
+	}
+}
+
+// TestGC checks that collecting the node cache, at every step with a tiny MaxNodes,
+// changes neither the cells nor the following steps.
+func TestGC(t *testing.T) {
+	withBoundary(t, boundary.Boundary{Mode: boundary.Dead})
+	withRule(t, "B3/S23")
+	u := boardUniverse(soupBoard(512, 3))
+	u.Step(4)
+	reference := u.Clone()
+	u.MaxNodes = 1
+	for _, k := range []int{4, 2, 4} {
+		u.Step(k)
+		reference.Step(k)
+		if u.NodeCount() >= reference.NodeCount() {
+			t.Errorf("Step(%d): %d nodes in the cache, %d without GC", k, u.NodeCount(), reference.NodeCount())
+		}
+		if got, want := sortedCells(u.Cells()), sortedCells(reference.Cells()); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Step(%d) after a GC: the cells differ from a universe without GC", k)
+		}
+	}
+
+	// the nodes of the universe and their memoised results stay usable
+	u.GC()
+	u.MaxNodes = DefaultMaxNodes
+	u.Step(5)
+	reference.Step(5)
+	if !reflect.DeepEqual(sortedCells(u.Cells()), sortedCells(reference.Cells())) {
+		t.Errorf("Step(5) after an explicit GC: the cells differ from a universe without GC")
+	}
+}
+
+func TestCloneAndBox(t *testing.T) {
+	withRule(t, "B3/S23")
+	u := NewUniverse()
+	glider := []Cell{{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2}}
+	for _, c := range glider {
+		u.Set(int64(c.X)-20, int64(c.Y)+7, true)
+	}
+	if x, y, w, h := u.Box(); x != -20 || y != 7 || w != 3 || h != 3 {
+		t.Errorf("box (%d,%d) %d x %d, want (-20,7) 3 x 3", x, y, w, h)
+	}
+
+	c := u.Clone()
+	c.Step(6) // 64 generations move the glider by (16,16)
+	if got, want := sortedCells(u.Cells()), moved(glider, -20, 7); !reflect.DeepEqual(got, want) {
+		t.Errorf("the original changed with its clone: %v, want %v", got, want)
+	}
+	if got, want := sortedCells(c.Cells()), moved(glider, -4, 23); !reflect.DeepEqual(got, want) {
+		t.Errorf("clone after 64 generations: %v, want %v", got, want)
+	}
+	if x, y, w, h := c.Box(); x != -4 || y != 23 || w != 3 || h != 3 {
+		t.Errorf("box of the clone (%d,%d) %d x %d, want (-4,23) 3 x 3", x, y, w, h)
+	}
+	if u.Generation() != 0 || c.Generation() != 64 {
+		t.Errorf("generations %d and %d, want 0 and 64", u.Generation(), c.Generation())
+	}
+
+	u.Move(5, -5)
+	if x, y, _, _ := u.Box(); x != -15 || y != 2 {
+		t.Errorf("box at (%d,%d) after Move(5,-5), want (-15,2)", x, y)
+	}
+	if empty := NewUniverse(); empty.Population() != 0 {
+		t.Errorf("population %d of an empty universe", empty.Population())
+	} else if _, _, w, h := empty.Box(); w != 0 || h != 0 {
+		t.Errorf("box %d x %d of an empty universe", w, h)
+	}
+}
+
+// TestUniverseTransform checks the transformations of a universe against the ones
+// of a pattern, on an asymmetric pattern.
+func TestUniverseTransform(t *testing.T) {
+	// the R-pentomino and a lone cell, no transformation keeps it
+	cells := []Cell{{1, 0}, {2, 0}, {0, 1}, {1, 1}, {1, 2}, {4, 3}}
+	pattern := NewPattern(cells)
+	lists := append(TransformNames(), "rot90,flipx", "transpose,rot180,flipy")
+	for _, list := range lists {
+		u := NewUniverse()
+		for _, c := range cells {
+			u.Set(int64(c.X)+3, int64(c.Y)-9, true)
+		}
+		if err := u.Transform(list); err != nil {
+			t.Fatalf("%s: %v", list, err)
+		}
+		want, err := pattern.Transform(list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := NewPattern(u.Cells()); !reflect.DeepEqual(sortedCells(got.Cells), sortedCells(want.Cells)) {
+			t.Errorf("%s: cells %v, want %v", list, sortedCells(got.Cells), sortedCells(want.Cells))
+		}
+	}
+	if err := NewUniverse().Transform("rot45"); err == nil {
+		t.Errorf("rot45: no error")
+	}
+}
+
+// TestRender checks viewports away from the origin of the universe, at the zoom 0
+// and at zooms where a pixel shows several cells.
+func TestRender(t *testing.T) {
+	withBoundary(t, boundary.Boundary{Mode: boundary.Dead})
+	withRule(t, "B3/S23")
+	u := boardUniverse(soupBoard(256, 4))
+	u.Step(3)
+	u.Move(-300, 50)
+	tests := []struct {
+		x0, y0        int64
+		width, height int
+		zoom          int
+	}{
+		{-190, 155, 40, 30, 0},
+		{-170, 183, 17, 9, 0},
+		{-200, 140, 20, 16, 2},
+		{-197, 151, 21, 15, 2}, // the nodes of 4 x 4 cells straddle the pixels
+		{-231, 117, 11, 9, 4},
+	}
+	for _, test := range tests {
+		pixels := u.Render(nil, test.x0, test.y0, test.width, test.height, test.zoom)
+		if len(pixels) != test.width*test.height*3 {
+			t.Fatalf("%d bytes, want %d", len(pixels), test.width*test.height*3)
+		}
+		lit := 0
+		for py := 0; py < test.height; py++ {
+			for px := 0; px < test.width; px++ {
+				alive := false
+				for dy := int64(0); dy < 1<<test.zoom; dy++ {
+					for dx := int64(0); dx < 1<<test.zoom; dx++ {
+						alive = alive || u.Get(test.x0+int64(px)<<test.zoom+dx, test.y0+int64(py)<<test.zoom+dy)
+					}
+				}
+				if pixel := pixels[(py*test.width+px)*3]; (pixel == 255) != alive {
+					t.Fatalf("viewport (%d,%d) zoom %d, pixel (%d,%d): %d, alive %v", test.x0, test.y0, test.zoom, px, py, pixel, alive)
+				}
+				if alive {
+					lit++
+				}
+			}
+		}
+		if lit == 0 {
+			t.Errorf("viewport (%d,%d) zoom %d: no alive cell, the test checks nothing", test.x0, test.y0, test.zoom)
+		}
+	}
+}
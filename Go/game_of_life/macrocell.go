@@ -0,0 +1,285 @@
+package game_of_life
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Golly's macrocell format (.mc): the quadtree of a universe, each distinct node
+// written once. After the "[M2]" line and the "#" lines (#R for the rule, #G for
+// the generation, #N and #C), each line is a node, numbered from 1:
+//   - a leaf of 8 x 8 cells, its rows of "." (dead) and "*" (alive) ended by "$",
+//     without the dead cells and rows at the end: "$.*$**$"
+//   - "level nw ne sw se", a node of 2^level x 2^level cells and the numbers of
+//     its children, 0 for an empty child. The children of level 1 nodes are states.
+// The last node is the root. Patterns of billions of cells with repeated parts
+// have small files, they are loaded in a Universe without listing the cells.
+
+// Macrocell is a pattern as a quadtree.
+type Macrocell struct {
+	Name     string
+	Comments []string
+	Rule     string // rulestring in the B/S notation, "" when the file has none
+	Universe *Universe
+}
+
+// Pattern returns the cells of m as a pattern, in the box around them.
+func (m *Macrocell) Pattern() *Pattern {
+	p := NewPattern(m.Universe.Cells())
+	p.Name, p.Comments, p.Rule = m.Name, m.Comments, m.Rule
+	return p
+}
+
+// NewMacrocell returns the pattern p as a quadtree.
+func NewMacrocell(p *Pattern) *Macrocell {
+	u := NewUniverse()
+	for _, c := range p.Cells {
+		u.Set(int64(c.X), int64(c.Y), true)
+	}
+	return &Macrocell{Name: p.Name, Comments: p.Comments, Rule: p.Rule, Universe: u}
+}
+
+// ReadMacrocellFile reads a .mc file.
+func ReadMacrocellFile(path string) (*Macrocell, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	m, err := ReadMacrocell(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return m, nil
+}
+
+// WriteMacrocellFile writes m as a .mc file.
+func WriteMacrocellFile(path string, m *Macrocell) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteMacrocell(file, m); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ReadMacrocell reads a pattern in the macrocell format. Like in Golly, the
+// center of the root is the cell (0,0).
+func ReadMacrocell(r io.Reader) (*Macrocell, error) {
+	m := &Macrocell{}
+	u := NewUniverse()
+	nodes := []*node{nil} // by number, 0 is an empty child
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<24)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == 1:
+			if !strings.HasPrefix(text, "[M2]") {
+				return nil, fmt.Errorf("not a macrocell file, the first line is not [M2]")
+			}
+		case text == "":
+		case strings.HasPrefix(text, "#"):
+			if err := m.comment(text[1:], u); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+		case text[0] == '.' || text[0] == '*' || text[0] == '$':
+			n, err := u.leaf(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			nodes = append(nodes, n)
+		default:
+			n, err := u.parseNode(text, nodes)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			nodes = append(nodes, n)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(nodes) == 1 {
+		return nil, fmt.Errorf("no node")
+	}
+	u.root = nodes[len(nodes)-1]
+	half := int64(1) << (u.root.level - 1)
+	u.x, u.y = -half, -half
+	for u.root.level < 3 {
+		u.expand()
+	}
+	m.Universe = u
+	return m, nil
+}
+
+// comment reads a "#" line of a macrocell file, without its "#".
+func (m *Macrocell) comment(text string, u *Universe) error {
+	if text == "" {
+		return nil
+	}
+	value := strings.TrimSpace(text[1:])
+	switch text[0] {
+	case 'R':
+		rule, err := ParseRule(value)
+		if err != nil {
+			return fmt.Errorf("unsupported rule: %v", err)
+		}
+		m.Rule = rule.String()
+	case 'G':
+		generation, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid generation %q", value)
+		}
+		u.generation = generation
+	case 'N':
+		m.Name = value
+	case 'C':
+		m.Comments = append(m.Comments, value)
+	}
+	return nil
+}
+
+// leaf returns the node of an 8 x 8 leaf line.
+func (u *Universe) leaf(text string) (*node, error) {
+	var cells [8][8]bool
+	x, y := 0, 0
+	for _, c := range text {
+		switch c {
+		case '.':
+			x++
+		case '*':
+			if x >= 8 || y >= 8 {
+				return nil, fmt.Errorf("cell (%d,%d) out of the 8 x 8 leaf", x, y)
+			}
+			cells[y][x] = true
+			x++
+		case '$':
+			x, y = 0, y+1
+		default:
+			return nil, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+	return u.build(3, 0, 0, 8, 8, func(x, y int) bool { return cells[y][x] }), nil
+}
+
+// parseNode returns the node of a "level nw ne sw se" line, the children are numbers of nodes.
+func (u *Universe) parseNode(text string, nodes []*node) (*node, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid node %q, expected level nw ne sw se", text)
+	}
+	var numbers [5]int
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid node %q, expected level nw ne sw se", text)
+		}
+		numbers[i] = n
+	}
+	level := numbers[0]
+	if level < 1 || level > 62 {
+		return nil, fmt.Errorf("invalid level %d", level)
+	}
+	var children [4]*node
+	for i, number := range numbers[1:] {
+		switch {
+		case level == 1 && number == 0:
+			children[i] = deadLeaf
+		case level == 1:
+			children[i] = aliveLeaf // the states of multi-state files are alive
+		case number == 0:
+			children[i] = u.emptyNode(level - 1)
+		case number >= len(nodes):
+			return nil, fmt.Errorf("node %d is not defined yet", number)
+		case nodes[number].level != level-1:
+			return nil, fmt.Errorf("node %d of level %d is not a child of level %d", number, nodes[number].level, level)
+		default:
+			children[i] = nodes[number]
+		}
+	}
+	return u.join(children[0], children[1], children[2], children[3]), nil
+}
+
+// WriteMacrocell writes m in the macrocell format, with leaves of 8 x 8 cells.
+func WriteMacrocell(w io.Writer, m *Macrocell) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("[M2] (smoothlife)\n")
+	if m.Rule != "" {
+		fmt.Fprintf(bw, "#R %s\n", m.Rule)
+	}
+	u := m.Universe
+	if u.generation > 0 {
+		fmt.Fprintf(bw, "#G %d\n", u.generation)
+	}
+	if m.Name != "" {
+		fmt.Fprintf(bw, "#N %s\n", m.Name)
+	}
+	for _, comment := range m.Comments {
+		fmt.Fprintf(bw, "#C %s\n", comment)
+	}
+
+	for u.root.level < 3 {
+		u.expand()
+	}
+	numbers := make(map[*node]int)
+	var write func(n *node) int
+	write = func(n *node) int {
+		if n.population == 0 {
+			return 0
+		}
+		if number, ok := numbers[n]; ok {
+			return number
+		}
+		if n.level == 3 {
+			writeLeaf(bw, n)
+		} else {
+			nw, ne, sw, se := write(n.nw), write(n.ne), write(n.sw), write(n.se)
+			fmt.Fprintf(bw, "%d %d %d %d %d\n", n.level, nw, ne, sw, se)
+		}
+		numbers[n] = len(numbers) + 1
+		return numbers[n]
+	}
+	if write(u.root) == 0 {
+		bw.WriteString("$\n") // an empty leaf as the root
+	}
+	return bw.Flush()
+}
+
+// writeLeaf writes the 8 x 8 cells of a node of level 3 as a leaf line.
+func writeLeaf(w *bufio.Writer, n *node) {
+	var rows [8][]byte
+	last := 0 // rows after the last alive cell are not written
+	var walk func(n *node, x, y int)
+	walk = func(n *node, x, y int) {
+		if n.population == 0 {
+			return
+		}
+		if n.level == 0 {
+			for len(rows[y]) < x {
+				rows[y] = append(rows[y], '.')
+			}
+			rows[y] = append(rows[y], '*')
+			last = max(last, y)
+			return
+		}
+		half := 1 << (n.level - 1)
+		walk(n.nw, x, y)
+		walk(n.ne, x+half, y)
+		walk(n.sw, x, y+half)
+		walk(n.se, x+half, y+half)
+	}
+	walk(n, 0, 0)
+	for y := 0; y <= last; y++ {
+		w.Write(rows[y])
+		w.WriteByte('$')
+	}
+	w.WriteByte('\n')
+}
@@ -0,0 +1,145 @@
+package game_of_life
+
+import (
+	"bufio"
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// gliders is a macrocell file as written by Golly: a root of 16 x 16 cells with the
+// leaf of a glider in its north west and south east quarters, and a leaf with two
+// cells in its south west quarter.
+const gliders = `[M2] (golly 4.2)
+#R B36/S23
+#G 42
+#N two gliders
+#C written by hand
+.*$..*$***$
+$$$$$$$*......*$
+4 1 0 2 1
+`
+
+func TestReadMacrocell(t *testing.T) {
+	m, err := ReadMacrocell(strings.NewReader(gliders))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the center of the root is the cell (0,0)
+	want := sortedCells([]Cell{
+		{-7, -8}, {-6, -7}, {-8, -6}, {-7, -6}, {-6, -6},
+		{-8, 7}, {-1, 7},
+		{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2},
+	})
+	if got := sortedCells(m.Universe.Cells()); !reflect.DeepEqual(got, want) {
+		t.Errorf("cells %v, want %v", got, want)
+	}
+	if m.Rule != "B36/S23" || m.Universe.Generation() != 42 {
+		t.Errorf("rule %q and generation %d, want B36/S23 and 42", m.Rule, m.Universe.Generation())
+	}
+	if m.Name != "two gliders" || !reflect.DeepEqual(m.Comments, []string{"written by hand"}) {
+		t.Errorf("name %q and comments %q", m.Name, m.Comments)
+	}
+	if p := m.Pattern(); p.Width != 11 || p.Height != 16 || p.Rule != m.Rule {
+		t.Errorf("pattern of %d x %d with the rule %q, want 11 x 16 and %q", p.Width, p.Height, p.Rule, m.Rule)
+	}
+
+	// the leaf of the glider is written once, then the nodes come in the same order
+	var out bytes.Buffer
+	if err := WriteMacrocell(&out, m); err != nil {
+		t.Fatal(err)
+	}
+	_, body, _ := strings.Cut(gliders, "\n")
+	if _, got, _ := strings.Cut(out.String(), "\n"); got != body {
+		t.Errorf("written\n%s\nwant\n%s", got, body)
+	}
+}
+
+func TestReadMacrocellErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"x = 3, y = 3\nbo$2bo$3o!\n",   // an RLE file
+		"[M2]\n#R B9/S23\n$\n",         // unknown rule
+		"[M2]\n#G -1\n$\n",             // negative generation
+		"[M2]\n",                       // no node
+		"[M2]\n.*$x\n",                 // unknown cell
+		"[M2]\n$$$$$$$$*\n",            // row out of the leaf
+		"[M2]\n.........*\n",           // column out of the leaf
+		"[M2]\n.*$\n5 1 0 0 0\n",       // child of level 3 in a node of level 5
+		"[M2]\n.*$\n4 2 0 0 0\n",       // child not defined yet
+		"[M2]\n.*$\n4 1 0 0\n",         // 3 children
+		"[M2]\n.*$\n63 1 0 0 0\n",      // level too big
+		"[M2]\n.*$\n4 1 0 -1 0\n",      // negative number
+		"[M2]\n.*$\n4 one two 0 0 0\n", // not a number
+	} {
+		if _, err := ReadMacrocell(strings.NewReader(text)); err == nil {
+			t.Errorf("%q: no error", text)
+		}
+	}
+}
+
+func TestWriteLeaf(t *testing.T) {
+	tests := []struct {
+		cells []Cell
+		want  string
+	}{
+		{[]Cell{{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2}}, ".*$..*$***$"},
+		{[]Cell{{0, 7}, {7, 7}}, "$$$$$$$*......*$"},
+		{[]Cell{{7, 0}}, ".......*$"},
+		{nil, "$"}, // only written for an empty root, the empty children are 0
+	}
+	for _, test := range tests {
+		u := NewUniverse()
+		for _, c := range test.cells {
+			u.Set(int64(c.X), int64(c.Y), true)
+		}
+		var out bytes.Buffer
+		w := bufio.NewWriter(&out)
+		writeLeaf(w, u.root) // the root of a new universe is the leaf of the cells from (0,0)
+		w.Flush()
+		if got := out.String(); got != test.want+"\n" {
+			t.Errorf("leaf of %v: %q, want %q", test.cells, got, test.want)
+		}
+	}
+}
+
+// TestMacrocellRoundTrip writes 500 random cells spread over many leaves and reads
+// them back, at the same place relatively to each other.
+func TestMacrocellRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	u := NewUniverse()
+	for i := 0; i < 500; i++ {
+		u.Set(rng.Int63n(1000)-300, rng.Int63n(200)-100, true)
+	}
+	u.generation = 1 << 40
+	m := &Macrocell{Name: "random", Comments: []string{"500 cells"}, Rule: "B3/S23", Universe: u}
+
+	var out bytes.Buffer
+	if err := WriteMacrocell(&out, m); err != nil {
+		t.Fatal(err)
+	}
+	again, err := ReadMacrocell(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sortedCells(again.Pattern().Cells), sortedCells(m.Pattern().Cells); !reflect.DeepEqual(got, want) {
+		t.Errorf("%d cells read back, want %d at the same places", len(got), len(want))
+	}
+	if again.Universe.Population() != u.Population() {
+		t.Errorf("population %d, want %d", again.Universe.Population(), u.Population())
+	}
+	if again.Name != m.Name || again.Rule != m.Rule || !reflect.DeepEqual(again.Comments, m.Comments) || again.Universe.Generation() != 1<<40 {
+		t.Errorf("read back %q %q %q generation %d", again.Name, again.Rule, again.Comments, again.Universe.Generation())
+	}
+
+	// an empty universe is a single empty leaf
+	out.Reset()
+	if err := WriteMacrocell(&out, &Macrocell{Universe: NewUniverse()}); err != nil {
+		t.Fatal(err)
+	}
+	if empty, err := ReadMacrocell(&out); err != nil || empty.Universe.Population() != 0 {
+		t.Errorf("empty universe read back: %v", err)
+	}
+}
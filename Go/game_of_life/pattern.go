@@ -0,0 +1,450 @@
+package game_of_life
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Patterns as distributed on the LifeWiki: the run length encoded format (.rle)
+// and the plaintext format (.cells). The macrocell files (.mc) are read and written
+// as patterns too, see macrocell.go.
+
+// Cell is the position of an alive cell.
+type Cell struct{ X, Y int }
+
+// Pattern is a set of alive cells in a box of Width x Height cells, whose top
+// left cell is (0,0).
+type Pattern struct {
+	Name     string
+	Author   string
+	Comments []string
+	Rule     string // rulestring in the B/S notation, "" when the file has none
+	Width    int
+	Height   int
+	Cells    []Cell
+}
+
+// NewPattern returns the pattern of the smallest box around cells, moved so its
+// top left cell is (0,0).
+func NewPattern(cells []Cell) *Pattern {
+	p := &Pattern{}
+	if len(cells) == 0 {
+		return p
+	}
+	minX, minY, maxX, maxY := cells[0].X, cells[0].Y, cells[0].X, cells[0].Y
+	for _, c := range cells {
+		minX, minY = min(minX, c.X), min(minY, c.Y)
+		maxX, maxY = max(maxX, c.X), max(maxY, c.Y)
+	}
+	p.Width, p.Height = maxX-minX+1, maxY-minY+1
+	p.Cells = make([]Cell, len(cells))
+	for i, c := range cells {
+		p.Cells[i] = Cell{c.X - minX, c.Y - minY}
+	}
+	return p
+}
+
+// LoadPattern returns the pattern of a grid of values in [0,1], the cells at or
+// above threshold are alive.
+func LoadPattern(values []float64, width, height int, threshold float64) *Pattern {
+	p := &Pattern{Width: width, Height: height}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if values[y*width+x] >= threshold {
+				p.Cells = append(p.Cells, Cell{x, y})
+			}
+		}
+	}
+	return p
+}
+
+// CellValues returns a width x height grid with 1 for the alive cells, the cells
+// outside of the grid wrap around it.
+func CellValues(cells []Cell, width, height int) []float64 {
+	values := make([]float64, width*height)
+	for _, c := range cells {
+		x, y := (c.X%width+width)%width, (c.Y%height+height)%height
+		values[y*width+x] = 1
+	}
+	return values
+}
+
+// Place returns the cells of p with its top left cell at (x,y).
+func (p *Pattern) Place(x, y int) []Cell {
+	cells := make([]Cell, len(p.Cells))
+	for i, c := range p.Cells {
+		cells[i] = Cell{c.X + x, c.Y + y}
+	}
+	return cells
+}
+
+// Transforms maps the transformations of Transform to the new position of a cell
+// of a width x height box. The rotations are clockwise.
+var Transforms = map[string]func(x, y, width, height int) (int, int){
+	"rot90":     func(x, y, width, height int) (int, int) { return height - 1 - y, x },
+	"rot180":    func(x, y, width, height int) (int, int) { return width - 1 - x, height - 1 - y },
+	"rot270":    func(x, y, width, height int) (int, int) { return y, width - 1 - x },
+	"flipx":     func(x, y, width, height int) (int, int) { return width - 1 - x, y },  // left to right
+	"flipy":     func(x, y, width, height int) (int, int) { return x, height - 1 - y }, // top to bottom
+	"transpose": func(x, y, width, height int) (int, int) { return y, x },
+}
+
+// TransformNames returns the names of Transforms, sorted.
+func TransformNames() []string {
+	names := make([]string, 0, len(Transforms))
+	for name := range Transforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Transform returns p after the comma separated transformations of list, applied
+// in order, e.g. "rot90,flipx".
+func (p *Pattern) Transform(list string) (*Pattern, error) {
+	result := *p
+	result.Cells = append([]Cell(nil), p.Cells...)
+	if list == "" {
+		return &result, nil
+	}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		transform, ok := Transforms[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown transformation %q (available: %v)", name, TransformNames())
+		}
+		for i, c := range result.Cells {
+			x, y := transform(c.X, c.Y, result.Width, result.Height)
+			result.Cells[i] = Cell{x, y}
+		}
+		if name != "rot180" && name != "flipx" && name != "flipy" {
+			result.Width, result.Height = result.Height, result.Width
+		}
+	}
+	return &result, nil
+}
+
+// ReadPattern reads a .rle, .cells or .mc file.
+func ReadPattern(path string) (*Pattern, error) {
+	if strings.ToLower(filepath.Ext(path)) == ".mc" {
+		m, err := ReadMacrocellFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return m.Pattern(), nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var p *Pattern
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".rle":
+		p, err = ReadRLE(file)
+	case ".cells":
+		p, err = ReadCells(file)
+	default:
+		return nil, fmt.Errorf("%s: unknown pattern format, .rle, .cells or .mc is expected", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return p, nil
+}
+
+// WritePattern writes p as a .rle, .cells or .mc file.
+func WritePattern(path string, p *Pattern) error {
+	write := WriteRLE
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".rle":
+	case ".cells":
+		write = WriteCells
+	case ".mc":
+		return WriteMacrocellFile(path, NewMacrocell(p))
+	default:
+		return fmt.Errorf("%s: unknown pattern format, .rle, .cells or .mc is expected", path)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file, p); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// IsPatternFile returns whether path has the extension of a pattern format.
+func IsPatternFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".rle" || ext == ".cells" || ext == ".mc"
+}
+
+// ReadRLE reads a pattern in the RLE format: "#" lines for the name (#N), the
+// author (#O) and the comments (#C), the header "x = 3, y = 3, rule = B3/S23" and
+// runs of dead (b) and alive (o) cells, "$" ending a line and "!" the pattern.
+func ReadRLE(r io.Reader) (*Pattern, error) {
+	p := &Pattern{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<24)
+	header, done := false, false
+	x, y, count := 0, 0, 0
+	for line := 1; !done && scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "":
+			continue
+		case strings.HasPrefix(text, "#"):
+			p.comment(text[1:])
+			continue
+		case !header:
+			if err := p.header(text); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			header = true
+			continue
+		}
+		for _, c := range text {
+			n := max(count, 1)
+			switch {
+			case c >= '0' && c <= '9':
+				count = count*10 + int(c-'0')
+				continue
+			case c == ' ' || c == '\t':
+				continue
+			case c == 'b' || c == '.':
+				x += n
+			case c == 'o' || (c >= 'A' && c <= 'X'): // the alive states of the multi-state files
+				for i := 0; i < n; i++ {
+					p.Cells = append(p.Cells, Cell{x + i, y})
+				}
+				x += n
+			case c == '$':
+				x, y = 0, y+n
+			case c == '!':
+				done = true
+			default:
+				return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+			}
+			count = 0
+			if done {
+				break
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !header {
+		return nil, fmt.Errorf("no header line x = width, y = height")
+	}
+	for _, c := range p.Cells {
+		// Some files have cells out of the size of their header
+		p.Width, p.Height = max(p.Width, c.X+1), max(p.Height, c.Y+1)
+	}
+	return p, nil
+}
+
+// comment reads a "#" line of an RLE file, without its "#".
+func (p *Pattern) comment(text string) {
+	if text == "" {
+		return
+	}
+	value := strings.TrimSpace(text[1:])
+	switch text[0] {
+	case 'N':
+		p.Name = value
+	case 'O':
+		p.Author = value
+	case 'C', 'c':
+		p.Comments = append(p.Comments, value)
+	}
+}
+
+// header reads the "x = 3, y = 3, rule = B3/S23" line of an RLE file.
+func (p *Pattern) header(text string) error {
+	// The rule is the last item, its value can have commas
+	items := strings.Split(text, ",")
+	for i, item := range items {
+		if strings.HasPrefix(strings.TrimSpace(item), "rule") {
+			items = append(items[:i], strings.Join(items[i:], ","))
+			break
+		}
+	}
+	for _, item := range items {
+		key, value, found := strings.Cut(item, "=")
+		if !found {
+			return fmt.Errorf("invalid header %q, expected x = width, y = height", text)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch key {
+		case "x", "y":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid size %s = %q", key, value)
+			}
+			if key == "x" {
+				p.Width = n
+			} else {
+				p.Height = n
+			}
+		case "rule":
+			// The bounded grids of Golly (B3/S23:T100,100) run on the grid of the simulation
+			rulestring, _, _ := strings.Cut(value, ":")
+			rule, err := ParseRule(rulestring)
+			if err != nil {
+				return fmt.Errorf("unsupported rule: %v", err)
+			}
+			p.Rule = rule.String()
+		}
+	}
+	return nil
+}
+
+// WriteRLE writes p in the RLE format, with lines of at most 70 characters.
+func WriteRLE(w io.Writer, p *Pattern) error {
+	bw := bufio.NewWriter(w)
+	if p.Name != "" {
+		fmt.Fprintf(bw, "#N %s\n", p.Name)
+	}
+	if p.Author != "" {
+		fmt.Fprintf(bw, "#O %s\n", p.Author)
+	}
+	for _, comment := range p.Comments {
+		fmt.Fprintf(bw, "#C %s\n", comment)
+	}
+	fmt.Fprintf(bw, "x = %d, y = %d", p.Width, p.Height)
+	if p.Rule != "" {
+		fmt.Fprintf(bw, ", rule = %s", p.Rule)
+	}
+	bw.WriteString("\n")
+
+	var line []byte
+	run := func(n int, tag byte) {
+		token := string(tag)
+		if n > 1 {
+			token = strconv.Itoa(n) + token
+		}
+		if len(line)+len(token) > 70 {
+			bw.Write(append(line, '\n'))
+			line = line[:0]
+		}
+		line = append(line, token...)
+	}
+	cells := sortedCells(p.Cells)
+	x, y := 0, 0 // next cell to write
+	for i := 0; i < len(cells); {
+		c := cells[i]
+		if c.Y > y {
+			run(c.Y-y, '$')
+			x, y = 0, c.Y
+		}
+		if c.X > x {
+			run(c.X-x, 'b')
+		}
+		n := 1
+		for i+n < len(cells) && cells[i+n] == (Cell{c.X + n, c.Y}) {
+			n++
+		}
+		run(n, 'o')
+		x, i = c.X+n, i+n
+	}
+	run(1, '!')
+	bw.Write(append(line, '\n'))
+	return bw.Flush()
+}
+
+// ReadCells reads a pattern in the plaintext format: "!" lines for the name
+// (!Name:) and the comments, then a line of "." (dead) and "O" (alive) per row.
+func ReadCells(r io.Reader) (*Pattern, error) {
+	p := &Pattern{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<24)
+	y := 0
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.HasPrefix(text, "!") {
+			if name, found := strings.CutPrefix(text, "!Name:"); found {
+				p.Name = strings.TrimSpace(name)
+			} else if author, found := strings.CutPrefix(text, "!Author:"); found {
+				p.Author = strings.TrimSpace(author)
+			} else {
+				p.Comments = append(p.Comments, strings.TrimSpace(text[1:]))
+			}
+			continue
+		}
+		for x, c := range text {
+			switch c {
+			case '.':
+			case 'O', '*':
+				p.Cells = append(p.Cells, Cell{x, y})
+			default:
+				return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+			}
+		}
+		p.Width = max(p.Width, len(text))
+		y++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	p.Height = y
+	return p, nil
+}
+
+// WriteCells writes p in the plaintext format. The format has no rule, it is
+// written as a comment.
+func WriteCells(w io.Writer, p *Pattern) error {
+	bw := bufio.NewWriter(w)
+	if p.Name != "" {
+		fmt.Fprintf(bw, "!Name: %s\n", p.Name)
+	}
+	if p.Author != "" {
+		fmt.Fprintf(bw, "!Author: %s\n", p.Author)
+	}
+	for _, comment := range p.Comments {
+		fmt.Fprintf(bw, "! %s\n", comment)
+	}
+	if p.Rule != "" && p.Rule != Conway.String() {
+		fmt.Fprintf(bw, "! rule %s\n", p.Rule)
+	}
+	cells := sortedCells(p.Cells)
+	row := make([]byte, p.Width)
+	for y, i := 0, 0; y < p.Height; y++ {
+		for x := range row {
+			row[x] = '.'
+		}
+		for ; i < len(cells) && cells[i].Y == y; i++ {
+			row[cells[i].X] = 'O'
+		}
+		bw.Write(row)
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
+
+// sortedCells returns the cells line by line, from left to right, without duplicates.
+func sortedCells(cells []Cell) []Cell {
+	sorted := append([]Cell(nil), cells...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Y != sorted[j].Y {
+			return sorted[i].Y < sorted[j].Y
+		}
+		return sorted[i].X < sorted[j].X
+	})
+	unique := sorted[:0]
+	for i, c := range sorted {
+		if i == 0 || c != sorted[i-1] {
+			unique = append(unique, c)
+		}
+	}
+	return unique
+}
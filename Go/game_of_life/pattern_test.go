@@ -0,0 +1,209 @@
+package game_of_life
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// gosperGun is the Gosper glider gun as distributed on the LifeWiki, its sixth row
+// is split across two lines, and the count of the last run too.
+const gosperGun = `#N Gosper glider gun
+#O Bill Gosper
+#C A true period 30 glider gun.
+#C The first known gun and the first known finite pattern with unbounded growth.
+x = 36, y = 9, rule = B3/S23
+24bo11b$22bobo11b$12b2o6b2o12b2o$11bo3bo4b2o12b2o$2o8bo5bo3b2o14b$2o8b
+o3bob2o4bobo11b$10bo5bo7bo11b$11bo3bo20b$1
+2b2o!
+`
+
+var gosperGunPicture = []string{
+	"........................O...........",
+	"......................O.O...........",
+	"............OO......OO............OO",
+	"...........O...O....OO............OO",
+	"OO........O.....O...OO..............",
+	"OO........O...O.OO....O.O...........",
+	"..........O.....O.......O...........",
+	"...........O...O....................",
+	"............OO......................",
+}
+
+// pictureCells returns the cells of picture ("O" for alive), sorted.
+func pictureCells(picture ...string) []Cell {
+	var cells []Cell
+	for y, line := range picture {
+		for x, c := range line {
+			if c == 'O' {
+				cells = append(cells, Cell{x, y})
+			}
+		}
+	}
+	return sortedCells(cells)
+}
+
+func TestReadRLE(t *testing.T) {
+	p, err := ReadRLE(strings.NewReader(gosperGun))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sortedCells(p.Cells), pictureCells(gosperGunPicture...); !reflect.DeepEqual(got, want) {
+		t.Errorf("cells %v, want %v", got, want)
+	}
+	if p.Width != 36 || p.Height != 9 || len(p.Cells) != 36 {
+		t.Errorf("%d cells in %d x %d, want 36 in 36 x 9", len(p.Cells), p.Width, p.Height)
+	}
+	if p.Name != "Gosper glider gun" || p.Author != "Bill Gosper" || len(p.Comments) != 2 || p.Comments[0] != "A true period 30 glider gun." {
+		t.Errorf("name %q, author %q, comments %q", p.Name, p.Author, p.Comments)
+	}
+	if p.Rule != "B3/S23" {
+		t.Errorf("rule %q, want B3/S23", p.Rule)
+	}
+}
+
+func TestReadRLEHeader(t *testing.T) {
+	tests := []struct {
+		header string
+		rule   string // "" when the header has none
+	}{
+		{"x = 3, y = 3", ""},
+		{"x = 3, y = 3, rule = B3/S23", "B3/S23"},
+		{"x=3,y=3,rule=b36/s23", "B36/S23"},
+		{"x = 3, y = 3, rule = 23/36", "B36/S23"},
+		{"x = 3, y = 3, rule = B3/S23:T100,100", "B3/S23"}, // a bounded grid of Golly, the size is dropped
+		{"x = 3, y = 3, rule = HighLife", "B36/S23"},
+	}
+	for _, test := range tests {
+		p, err := ReadRLE(strings.NewReader(test.header + "\nbo$2bo$3o!\n"))
+		if err != nil {
+			t.Errorf("%q: %v", test.header, err)
+			continue
+		}
+		if p.Rule != test.rule {
+			t.Errorf("%q: rule %q, want %q", test.header, p.Rule, test.rule)
+		}
+	}
+	for _, text := range []string{
+		"bo$2bo$3o!",                     // no header
+		"x = 3, y\nbo$2bo$3o!",           // no size
+		"x = -3, y = 3\nbo$2bo$3o!",      // negative size
+		"x = 3, y = 3, rule = B9/S23\n!", // unknown rule
+		"x = 3, y = 3\nbo$2bz$3o!",       // unknown cell
+	} {
+		if _, err := ReadRLE(strings.NewReader(text)); err == nil {
+			t.Errorf("%q: no error", text)
+		}
+	}
+}
+
+// TestPatternRoundTrip writes the Gosper gun in each format and reads it back.
+func TestPatternRoundTrip(t *testing.T) {
+	gun, err := ReadRLE(strings.NewReader(gosperGun))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gun.Rule = "B36/S23" // not the default rule, .cells writes it as a comment
+
+	var rle bytes.Buffer
+	if err := WriteRLE(&rle, gun); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(rle.String(), "\n") {
+		if len(line) > 70 && !strings.HasPrefix(line, "#") {
+			t.Errorf("line of %d characters %q", len(line), line)
+		}
+	}
+	again, err := ReadRLE(&rle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gun.Cells = sortedCells(gun.Cells)
+	again.Cells = sortedCells(again.Cells)
+	if !reflect.DeepEqual(again, gun) {
+		t.Errorf("RLE round trip: %+v, want %+v", again, gun)
+	}
+
+	var cells bytes.Buffer
+	if err := WriteCells(&cells, gun); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(cells.String(), "\n"+gosperGunPicture[5]+"\n") {
+		t.Errorf("no row %q in\n%s", gosperGunPicture[5], cells.String())
+	}
+	again, err = ReadCells(&cells)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again.Cells, gun.Cells) || again.Width != gun.Width || again.Height != gun.Height {
+		t.Errorf(".cells round trip: %d cells in %d x %d, want %d in %d x %d", len(again.Cells), again.Width, again.Height, len(gun.Cells), gun.Width, gun.Height)
+	}
+	if again.Name != gun.Name || again.Author != gun.Author {
+		t.Errorf(".cells round trip: name %q and author %q", again.Name, again.Author)
+	}
+	if want := "rule B36/S23"; again.Comments[len(again.Comments)-1] != want {
+		t.Errorf(".cells comments %q, want the last one %q", again.Comments, want)
+	}
+
+	// the same through the files, chosen by their extension
+	dir := t.TempDir()
+	for _, name := range []string{"gun.rle", "gun.cells", "gun.mc"} {
+		path := filepath.Join(dir, name)
+		if err := WritePattern(path, gun); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		p, err := ReadPattern(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(sortedCells(p.Cells), gun.Cells) {
+			t.Errorf("%s: the cells differ", name)
+		}
+	}
+	if err := WritePattern(filepath.Join(dir, "gun.txt"), gun); err == nil {
+		t.Errorf("gun.txt: no error")
+	}
+}
+
+// TestTransform checks the 8 symmetries of the square on an asymmetric pattern.
+func TestTransform(t *testing.T) {
+	p := NewPattern(pictureCells(
+		"OOO",
+		"O..",
+	))
+	tests := []struct {
+		list string
+		want []string
+	}{
+		{"", []string{"OOO", "O.."}},
+		{"rot90", []string{"OO", ".O", ".O"}},
+		{"rot180", []string{"..O", "OOO"}},
+		{"rot270", []string{"O.", "O.", "OO"}},
+		{"flipx", []string{"OOO", "..O"}},
+		{"flipy", []string{"O..", "OOO"}},
+		{"transpose", []string{"OO", "O.", "O."}},
+		{"rot90,flipy", []string{".O", ".O", "OO"}}, // the other diagonal
+		{"rot90, rot90", []string{"..O", "OOO"}},
+		{"flipx,flipx", []string{"OOO", "O.."}},
+	}
+	for _, test := range tests {
+		got, err := p.Transform(test.list)
+		if err != nil {
+			t.Fatalf("%q: %v", test.list, err)
+		}
+		if cells := sortedCells(got.Cells); !reflect.DeepEqual(cells, pictureCells(test.want...)) {
+			t.Errorf("%q: cells %v, want %v", test.list, cells, pictureCells(test.want...))
+		}
+		if got.Width != len(test.want[0]) || got.Height != len(test.want) {
+			t.Errorf("%q: %d x %d, want %d x %d", test.list, got.Width, got.Height, len(test.want[0]), len(test.want))
+		}
+	}
+	if _, err := p.Transform("rot90,mirror"); err == nil {
+		t.Errorf("mirror: no error")
+	}
+	if cells := sortedCells(p.Cells); !reflect.DeepEqual(cells, pictureCells("OOO", "O..")) {
+		t.Errorf("Transform changed the pattern: %v", cells)
+	}
+}
@@ -0,0 +1,116 @@
+package game_of_life
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Life-like rules: a dead cell is born when its number of alive neighbours is in
+// Birth, an alive cell survives when it is in Survival. They are written as
+// rulestrings, "B3/S23" for Conway's game of life.
+
+type Rule struct {
+	Birth, Survival [9]bool
+}
+
+// Conway is the rule of Conway's game of life, B3/S23.
+var Conway = Rule{Birth: [9]bool{3: true}, Survival: [9]bool{2: true, 3: true}}
+
+// NamedRules are rules known by their name, accepted by ParseRule.
+var NamedRules = map[string]string{
+	"life":       "B3/S23",
+	"highlife":   "B36/S23",
+	"seeds":      "B2/S",
+	"daynight":   "B3678/S34678",
+	"replicator": "B1357/S1357",
+	"diamoeba":   "B35678/S5678",
+	"2x2":        "B36/S125",
+	"maze":       "B3/S12345",
+	"lwod":       "B3/S012345678", // life without death
+}
+
+var rule = Conway // rule used by UpdateGrid
+
+// ParseRule parses a rulestring: "B36/S23" (the letters can be lowercase and in
+// any order), "23/36" (survival/birth, the older notation) or a name of NamedRules.
+func ParseRule(s string) (Rule, error) {
+	if named, ok := NamedRules[strings.ToLower(s)]; ok {
+		s = named
+	}
+	var r Rule
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(s)), "/")
+	if len(parts) != 2 {
+		return r, fmt.Errorf("invalid rule %q, expected B3/S23, 23/3 or one of %v", s, ruleNames())
+	}
+	digits := func(part string, counts *[9]bool) error {
+		for _, c := range part {
+			if c < '0' || c > '8' {
+				return fmt.Errorf("invalid rule %q: %q is not a number of neighbours", s, c)
+			}
+			counts[c-'0'] = true
+		}
+		return nil
+	}
+
+	first, second := parts[0], parts[1]
+	switch {
+	case strings.HasPrefix(first, "B") && strings.HasPrefix(second, "S"):
+		first, second = first[1:], second[1:]
+	case strings.HasPrefix(first, "S") && strings.HasPrefix(second, "B"):
+		first, second = second[1:], first[1:]
+	default:
+		first, second = second, first // S/B without letters
+	}
+	if err := digits(first, &r.Birth); err != nil {
+		return r, err
+	}
+	if err := digits(second, &r.Survival); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// String returns the rulestring of r in the B/S notation.
+func (r Rule) String() string {
+	var b strings.Builder
+	b.WriteString("B")
+	for n, born := range r.Birth {
+		if born {
+			b.WriteByte(byte('0' + n))
+		}
+	}
+	b.WriteString("/S")
+	for n, survives := range r.Survival {
+		if survives {
+			b.WriteByte(byte('0' + n))
+		}
+	}
+	return b.String()
+}
+
+// Next returns whether a cell is alive at the next generation.
+func (r Rule) Next(alive bool, neighbours int) bool {
+	if alive {
+		return r.Survival[neighbours]
+	}
+	return r.Birth[neighbours]
+}
+
+func ruleNames() []string {
+	names := make([]string, 0, len(NamedRules))
+	for name := range NamedRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func GetRule() Rule {
+	return rule
+}
+
+func SetRule(r Rule) {
+	// Changes the rule, used from the next update
+	rule = r
+}
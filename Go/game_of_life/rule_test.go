@@ -0,0 +1,186 @@
+package game_of_life
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"B3/S23", "B3/S23"},
+		{"b3/s23", "B3/S23"},
+		{"23/3", "B3/S23"},
+		{"S23/B36", "B36/S23"},
+		{"B2/S", "B2/S"},
+		{"/3", "B3/S"},
+		{"life", "B3/S23"},
+		{"HighLife", "B36/S23"},
+		{"seeds", "B2/S"},
+		{"lwod", "B3/S012345678"},
+	}
+	for _, test := range tests {
+		r, err := ParseRule(test.s)
+		if err != nil {
+			t.Errorf("ParseRule(%q): %v", test.s, err)
+			continue
+		}
+		if got := r.String(); got != test.want {
+			t.Errorf("ParseRule(%q) = %s, want %s", test.s, got, test.want)
+		}
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	for _, s := range []string{"", "B9", "B3S23", "B9/S23", "B3/S2x", "B3/S23/C4", "notarule"} {
+		if r, err := ParseRule(s); err == nil {
+			t.Errorf("ParseRule(%q) = %s, want an error", s, r)
+		}
+	}
+}
+
+// withRule sets the rule named s for the duration of the test.
+func withRule(t *testing.T, s string) {
+	t.Helper()
+	r, err := ParseRule(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := GetRule()
+	SetRule(r)
+	t.Cleanup(func() { SetRule(before) })
+}
+
+// lifeGrid returns a width x height grid of UpdateGrid with the cells of picture
+// ("o" for alive) from (x,y).
+func lifeGrid(width, height, x, y int, picture ...string) [][][]uint8 {
+	values := make([]float64, width*height)
+	for dy, line := range picture {
+		for dx, c := range line {
+			if c == 'o' {
+				values[(y+dy)*width+x+dx] = 1
+			}
+		}
+	}
+	return LoadCells(values, width, height, 0.5)
+}
+
+// aliveCells returns the alive cells of a grid of UpdateGrid, sorted.
+func aliveCells(pixels [][][]uint8) []Cell {
+	var cells []Cell
+	for y, line := range pixels {
+		for x, pixel := range line {
+			if pixel[0] == 255 {
+				cells = append(cells, Cell{x, y})
+			}
+		}
+	}
+	return sortedCells(cells)
+}
+
+// moved returns the cells moved by (dx,dy), sorted.
+func moved(cells []Cell, dx, dy int) []Cell {
+	out := make([]Cell, len(cells))
+	for i, c := range cells {
+		out[i] = Cell{c.X + dx, c.Y + dy}
+	}
+	return sortedCells(out)
+}
+
+func TestOscillatorsAndSpaceships(t *testing.T) {
+	glider := []string{".o.", "..o", "ooo"}
+	tests := []struct {
+		name, rule string
+		picture    []string
+		period     int
+		dx, dy     int // displacement after a period
+	}{
+		{"blinker", "life", []string{"ooo"}, 2, 0, 0},
+		{"blinker", "highlife", []string{"ooo"}, 2, 0, 0},
+		{"toad", "life", []string{".ooo", "ooo."}, 2, 0, 0},
+		{"block", "life", []string{"oo", "oo"}, 1, 0, 0},
+		{"block", "highlife", []string{"oo", "oo"}, 1, 0, 0},
+		{"glider", "life", glider, 4, 1, 1},
+		{"glider", "highlife", glider, 4, 1, 1},
+		{"lwss", "life", []string{".o..o", "o....", "o...o", "oooo."}, 4, -2, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name+"/"+test.rule, func(t *testing.T) {
+			withRule(t, test.rule)
+			pixels := lifeGrid(32, 32, 12, 12, test.picture...)
+			start := aliveCells(pixels)
+			for period := 1; period <= 3; period++ {
+				for i := 0; i < test.period; i++ {
+					pixels = UpdateGrid(pixels)
+				}
+				got := aliveCells(pixels)
+				if want := moved(start, period*test.dx, period*test.dy); !reflect.DeepEqual(got, want) {
+					t.Fatalf("after %d generations: %v, want %v", period*test.period, got, want)
+				}
+			}
+			if test.period > 1 {
+				pixels = UpdateGrid(pixels)
+				if reflect.DeepEqual(aliveCells(pixels), moved(start, 3*test.dx, 3*test.dy)) {
+					t.Errorf("the period is shorter than %d", test.period)
+				}
+			}
+		})
+	}
+}
+
+func TestHighLifeReplicator(t *testing.T) {
+	replicator := []string{"..ooo", ".o..o", "o...o", "o..o.", "ooo.."}
+	withRule(t, "highlife")
+	pixels := lifeGrid(64, 64, 30, 30, replicator...)
+	start := aliveCells(pixels)
+	for i := 0; i < 12; i++ {
+		pixels = UpdateGrid(pixels)
+	}
+	// after 12 generations the replicator has two copies of itself, on its diagonal
+	got := aliveCells(pixels)
+	want := sortedCells(append(moved(start, -2, -2), moved(start, 2, 2)...))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("after 12 generations: %v, want two copies %v", got, want)
+	}
+
+	// under Conway's rule it is not a replicator
+	withRule(t, "life")
+	pixels = lifeGrid(64, 64, 30, 30, replicator...)
+	for i := 0; i < 12; i++ {
+		pixels = UpdateGrid(pixels)
+	}
+	if reflect.DeepEqual(aliveCells(pixels), want) {
+		t.Errorf("the replicator also replicates under B3/S23")
+	}
+}
+
+func TestSeeds(t *testing.T) {
+	withRule(t, "seeds")
+	// no cell survives, the cells with exactly 2 neighbours are born
+	pixels := UpdateGrid(lifeGrid(16, 16, 5, 5, "oo"))
+	want := []Cell{{5, 4}, {6, 4}, {5, 6}, {6, 6}}
+	if got := aliveCells(pixels); !reflect.DeepEqual(got, sortedCells(want)) {
+		t.Errorf("domino: %v, want %v", got, want)
+	}
+	// a lone cell dies and nothing is born
+	if got := aliveCells(UpdateGrid(lifeGrid(16, 16, 5, 5, "o"))); len(got) != 0 {
+		t.Errorf("lone cell: %v, want no cell", got)
+	}
+}
+
+func TestRuleString(t *testing.T) {
+	for name, s := range NamedRules {
+		r, err := ParseRule(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.String() != s {
+			t.Errorf("%s = %s, want %s", name, r, s)
+		}
+		if again, _ := ParseRule(r.String()); again != r {
+			t.Errorf("%s does not parse back", r)
+		}
+	}
+}
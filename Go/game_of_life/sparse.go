@@ -0,0 +1,270 @@
+package game_of_life
+
+import (
+	"math/bits"
+	"runtime"
+	"sync"
+)
+
+// Sparse is an unbounded universe made of tiles of 64 x 64 cells, allocated when
+// cells are alive in them and dropped when they die out: the spaceships fly away
+// instead of wrapping around the grid into their own wake. A tile is 64 words like
+// the lines of Board, a step only computes the tiles with cells and the ones
+// next to their sides, with the same bitwise adders.
+type Sparse struct {
+	tiles      map[tileKey]*tile
+	population int
+	generation uint64
+	box        [4]int64 // smallest box around the cells: min x, min y, max x, max y
+}
+
+// tileKey is the position of a tile, the cell (x,y) is in the tile (x>>6, y>>6).
+type tileKey struct{ x, y int64 }
+
+// tile holds cell x of line y in bit x of word y.
+type tile [64]uint64
+
+func NewSparse() *Sparse {
+	return &Sparse{tiles: make(map[tileKey]*tile)}
+}
+
+func (s *Sparse) Get(x, y int64) bool {
+	t := s.tiles[tileKey{x >> 6, y >> 6}]
+	return t != nil && t[y&63]&(1<<(x&63)) != 0
+}
+
+func (s *Sparse) Set(x, y int64, alive bool) {
+	key := tileKey{x >> 6, y >> 6}
+	t := s.tiles[key]
+	if t == nil {
+		if !alive {
+			return
+		}
+		t = &tile{}
+		s.tiles[key] = t
+	}
+	before := t[y&63]
+	if alive {
+		t[y&63] |= 1 << (x & 63)
+	} else {
+		t[y&63] &^= 1 << (x & 63)
+	}
+	s.population += bits.OnesCount64(t[y&63]) - bits.OnesCount64(before)
+	switch {
+	case alive && s.population == 1:
+		s.box = [4]int64{x, y, x, y}
+	case alive:
+		s.box = [4]int64{min(s.box[0], x), min(s.box[1], y), max(s.box[2], x), max(s.box[3], y)}
+	default:
+		if *t == (tile{}) {
+			delete(s.tiles, key)
+		}
+		s.updateBox() // the box can only shrink by looking at all the cells
+	}
+}
+
+func (s *Sparse) Population() int {
+	return s.population
+}
+
+func (s *Sparse) Generation() uint64 {
+	return s.generation
+}
+
+// TileCount returns the number of tiles allocated.
+func (s *Sparse) TileCount() int {
+	return len(s.tiles)
+}
+
+// Box returns the top left cell and the size of the smallest box around the alive
+// cells, 0 x 0 when there is none. It is kept up to date by Set and Step.
+func (s *Sparse) Box() (x, y, width, height int64) {
+	if s.population == 0 {
+		return 0, 0, 0, 0
+	}
+	return s.box[0], s.box[1], s.box[2] - s.box[0] + 1, s.box[3] - s.box[1] + 1
+}
+
+// updateBox computes the box around the cells from the box of each tile.
+func (s *Sparse) updateBox() {
+	first := true
+	for key, t := range s.tiles {
+		box := t.box(key)
+		if first {
+			s.box, first = box, false
+			continue
+		}
+		s.box = [4]int64{min(s.box[0], box[0]), min(s.box[1], box[1]), max(s.box[2], box[2]), max(s.box[3], box[3])}
+	}
+}
+
+// box returns the box around the cells of a tile that is not empty, as Sparse.box.
+func (t *tile) box(key tileKey) [4]int64 {
+	var columns uint64
+	top, bottom := -1, 0
+	for y, line := range t {
+		if line != 0 {
+			columns |= line
+			if top < 0 {
+				top = y
+			}
+			bottom = y
+		}
+	}
+	x, y := key.x<<6, key.y<<6
+	return [4]int64{
+		x + int64(bits.TrailingZeros64(columns)), y + int64(top),
+		x + int64(63-bits.LeadingZeros64(columns)), y + int64(bottom),
+	}
+}
+
+// Cells returns the alive cells.
+func (s *Sparse) Cells() []Cell {
+	cells := make([]Cell, 0, s.population)
+	for key, t := range s.tiles {
+		for y, line := range t {
+			for ; line != 0; line &= line - 1 {
+				cells = append(cells, Cell{int(key.x<<6) + bits.TrailingZeros64(line), int(key.y<<6) + y})
+			}
+		}
+	}
+	return cells
+}
+
+// Step advances the universe by one generation with the current rule (see SetRule),
+// which must not have B0. The tiles are computed in parallel.
+func (s *Sparse) Step() {
+	born, survive := ruleCounts()
+
+	// The tiles with cells, and their neighbours on the sides where cells are alive
+	candidates := make(map[tileKey]bool, 2*len(s.tiles))
+	for key, t := range s.tiles {
+		var columns uint64
+		for _, line := range t {
+			columns |= line
+		}
+		for _, side := range [...]struct {
+			dx, dy int64
+			alive  bool
+		}{
+			{0, 0, true},
+			{-1, 0, columns&1 != 0}, {1, 0, columns>>63 != 0},
+			{0, -1, t[0] != 0}, {0, 1, t[63] != 0},
+			{-1, -1, t[0]&1 != 0}, {1, -1, t[0]>>63 != 0},
+			{-1, 1, t[63]&1 != 0}, {1, 1, t[63]>>63 != 0},
+		} {
+			if side.alive {
+				candidates[tileKey{key.x + side.dx, key.y + side.dy}] = true
+			}
+		}
+	}
+	keys := make([]tileKey, 0, len(candidates))
+	for key := range candidates {
+		keys = append(keys, key)
+	}
+
+	next := make([]*tile, len(keys))
+	workers := min(runtime.NumCPU(), (len(keys)+15)/16) // at least 16 tiles per goroutine
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for j := start; j < end; j++ {
+				next[j] = s.stepTile(keys[j], born, survive)
+			}
+		}(i*len(keys)/workers, (i+1)*len(keys)/workers)
+	}
+	wg.Wait()
+
+	s.tiles = make(map[tileKey]*tile, len(keys))
+	s.population = 0
+	for i, t := range next {
+		if t == nil {
+			continue
+		}
+		s.tiles[keys[i]] = t
+		for _, line := range t {
+			s.population += bits.OnesCount64(line)
+		}
+	}
+	s.updateBox()
+	s.generation++
+}
+
+// stepTile returns the next generation of the tile at key, nil when it is empty.
+func (s *Sparse) stepTile(key tileKey, born, survive []int) *tile {
+	var around [3][3]*tile // the tile and its neighbours, by dy+1 and dx+1
+	for dy := int64(-1); dy <= 1; dy++ {
+		for dx := int64(-1); dx <= 1; dx++ {
+			around[dy+1][dx+1] = s.tiles[tileKey{key.x + dx, key.y + dy}]
+		}
+	}
+	// line returns line y in [-1,64] of the column dx of around
+	line := func(dx, y int) uint64 {
+		row := 1
+		switch {
+		case y < 0:
+			row, y = 0, 63
+		case y > 63:
+			row, y = 2, 0
+		}
+		if t := around[row][dx+1]; t != nil {
+			return t[y]
+		}
+		return 0
+	}
+
+	var next tile
+	empty := true
+	for y := 0; y < 64; y++ {
+		// the lines above, at and below y, with the west and east neighbours of each cell
+		var center, west, east [3]uint64
+		for i := range center {
+			center[i] = line(0, y+i-1)
+			west[i] = center[i]<<1 | line(-1, y+i-1)>>63
+			east[i] = center[i]>>1 | line(1, y+i-1)<<63
+		}
+		next[y] = nextWord(center[1], [8]uint64{
+			west[0], center[0], east[0],
+			west[1], east[1],
+			west[2], center[2], east[2],
+		}, born, survive)
+		empty = empty && next[y] == 0
+	}
+	if empty {
+		return nil
+	}
+	return &next
+}
+
+// Render draws the cells of the viewport of width x height pixels whose top left
+// cell is (x0,y0) as R,G,B pixels, like Universe.Render.
+func (s *Sparse) Render(pixels []uint8, x0, y0 int64, width, height, zoom int) []uint8 {
+	if len(pixels) != width*height*3 {
+		pixels = make([]uint8, width*height*3)
+	}
+	clear(pixels)
+	x1, y1 := x0+int64(width)<<zoom, y0+int64(height)<<zoom
+	for key, t := range s.tiles {
+		tx, ty := key.x<<6, key.y<<6
+		if tx+64 <= x0 || ty+64 <= y0 || tx >= x1 || ty >= y1 {
+			continue
+		}
+		for y, line := range t {
+			cy := ty + int64(y)
+			if cy < y0 || cy >= y1 {
+				continue
+			}
+			for ; line != 0; line &= line - 1 {
+				cx := tx + int64(bits.TrailingZeros64(line))
+				if cx < x0 || cx >= x1 {
+					continue
+				}
+				index := (int((cy-y0)>>zoom)*width + int((cx-x0)>>zoom)) * 3
+				pixels[index], pixels[index+1], pixels[index+2] = 255, 255, 255
+			}
+		}
+	}
+	return pixels
+}
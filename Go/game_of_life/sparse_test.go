@@ -0,0 +1,137 @@
+package game_of_life
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// TestSparseGlider follows a glider across the sides and the corners of several
+// tiles, to negative coordinates, against HashLife. Its box moves with it and the
+// tiles it leaves are freed.
+func TestSparseGlider(t *testing.T) {
+	withRule(t, "B3/S23")
+	// a glider going to the north west, from the corner of 4 tiles
+	glider := []Cell{{0, 0}, {1, 0}, {2, 0}, {0, 1}, {1, 2}}
+	s, u := NewSparse(), NewUniverse()
+	for _, c := range moved(glider, 62, 62) {
+		s.Set(int64(c.X), int64(c.Y), true)
+		u.Set(int64(c.X), int64(c.Y), true)
+	}
+	for generation := 1; generation <= 4*200; generation++ {
+		s.Step()
+		u.Step(0)
+		if got, want := sortedCells(s.Cells()), sortedCells(u.Cells()); !reflect.DeepEqual(got, want) {
+			t.Fatalf("generation %d: cells %v, want %v", generation, got, want)
+		}
+		if s.Population() != 5 || s.Generation() != uint64(generation) {
+			t.Fatalf("generation %d: population %d at generation %d", generation, s.Population(), s.Generation())
+		}
+		x, y, w, h := s.Box()
+		if ux, uy, uw, uh := u.Box(); x != ux || y != uy || w != uw || h != uh {
+			t.Fatalf("generation %d: box (%d,%d) %d x %d, want (%d,%d) %d x %d", generation, x, y, w, h, ux, uy, uw, uh)
+		}
+		if s.TileCount() > 4 {
+			t.Fatalf("generation %d: %d tiles for a glider", generation, s.TileCount())
+		}
+	}
+	// 4 generations move the glider by (-1,-1)
+	if x, y, w, h := s.Box(); x != 62-200 || y != 62-200 || w != 3 || h != 3 {
+		t.Errorf("box (%d,%d) %d x %d after 800 generations, want (-138,-138) 3 x 3", x, y, w, h)
+	}
+	if s.TileCount() != 1 {
+		t.Errorf("%d tiles for a glider inside a tile, want 1", s.TileCount())
+	}
+}
+
+// TestSparseMatchesHashLife compares the two unbounded engines on a soup, for
+// several rules. The soup grows past the sides of its tiles.
+func TestSparseMatchesHashLife(t *testing.T) {
+	for _, rule := range []string{"B3/S23", "B36/S23", "B3678/S34678", "B2/S"} {
+		t.Run(rule, func(t *testing.T) {
+			withRule(t, rule)
+			s, u := NewSparse(), NewUniverse()
+			for i, v := range randomValues(40, 40, 5) {
+				if v < 0.4 {
+					x, y := int64(i%40-20), int64(i/40-20)
+					s.Set(x, y, true)
+					u.Set(x, y, true)
+				}
+			}
+			for generation := 1; generation <= 100; generation++ {
+				s.Step()
+				u.Step(0)
+				if got, want := sortedCells(s.Cells()), sortedCells(u.Cells()); !reflect.DeepEqual(got, want) {
+					t.Fatalf("generation %d: %d cells, want %d", generation, len(got), len(want))
+				}
+			}
+			if s.Population() != u.Population() {
+				t.Errorf("population %d, want %d", s.Population(), u.Population())
+			}
+		})
+	}
+}
+
+func TestSparseSet(t *testing.T) {
+	s := NewSparse()
+	cells := []Cell{{-1, -1}, {0, 0}, {63, 64}, {-65, 200}}
+	for _, c := range cells {
+		s.Set(int64(c.X), int64(c.Y), true)
+	}
+	s.Set(0, 0, true) // already alive
+	if s.Population() != 4 || s.TileCount() != 4 {
+		t.Errorf("population %d in %d tiles, want 4 in 4", s.Population(), s.TileCount())
+	}
+	if x, y, w, h := s.Box(); x != -65 || y != -1 || w != 129 || h != 202 {
+		t.Errorf("box (%d,%d) %d x %d, want (-65,-1) 129 x 202", x, y, w, h)
+	}
+	for _, c := range cells {
+		if !s.Get(int64(c.X), int64(c.Y)) {
+			t.Errorf("(%d,%d) is dead", c.X, c.Y)
+		}
+	}
+
+	tests := []struct {
+		cell       Cell
+		tiles      int
+		x, y, w, h int64
+	}{
+		{Cell{-65, 200}, 3, -1, -1, 65, 66},
+		{Cell{-1, -1}, 2, 0, 0, 64, 65},
+		{Cell{5, 5}, 2, 0, 0, 64, 65}, // already dead
+		{Cell{63, 64}, 1, 0, 0, 1, 1},
+		{Cell{0, 0}, 0, 0, 0, 0, 0},
+	}
+	for _, test := range tests {
+		s.Set(int64(test.cell.X), int64(test.cell.Y), false)
+		if s.TileCount() != test.tiles {
+			t.Errorf("after killing %v: %d tiles, want %d", test.cell, s.TileCount(), test.tiles)
+		}
+		if x, y, w, h := s.Box(); x != test.x || y != test.y || w != test.w || h != test.h {
+			t.Errorf("after killing %v: box (%d,%d) %d x %d, want (%d,%d) %d x %d", test.cell, x, y, w, h, test.x, test.y, test.w, test.h)
+		}
+	}
+}
+
+func TestSparseRender(t *testing.T) {
+	withRule(t, "B3/S23")
+	s, u := NewSparse(), NewUniverse()
+	for i, v := range randomValues(100, 100, 6) {
+		if v < 0.3 {
+			x, y := int64(i%100-50), int64(i/100-30)
+			s.Set(x, y, true)
+			u.Set(x, y, true)
+		}
+	}
+	for _, zoom := range []int{0, 1, 3} {
+		for _, origin := range [][2]int64{{-64, -64}, {-40, -8}, {0, 16}} {
+			t.Run(fmt.Sprintf("%d/%d,%d", zoom, origin[0], origin[1]), func(t *testing.T) {
+				got := s.Render(nil, origin[0], origin[1], 30, 20, zoom)
+				want := u.Render(nil, origin[0], origin[1], 30, 20, zoom)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("the pixels differ from HashLife")
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package image_utils
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+)
+
+// Loading of images used as a start grid. An image of any size is decoded to float
+// channels in [0,1], then resampled to the grid size and transformed with LoadOptions
+// so that any photo can make a useful seed.
+
+// Fields is an image as float channels in [0,1], stored line by line.
+// A is the alpha channel (1 when the image has no transparency).
+type Fields struct {
+	Width, Height int
+	R, G, B, A    []float64
+}
+
+// LoadOptions describes how an image is turned into a start grid.
+type LoadOptions struct {
+	Width, Height int     // size of the grid, 0 keeps the size of the image
+	Resample      string  // "nearest", "bilinear" or "area"
+	Fit           string  // "stretch", "crop" (centre-crop to the grid ratio) or "pad" (letterbox)
+	Grayscale     bool    // use the luminance for the 3 channels
+	Invert        bool    // 1-value
+	Contrast      float64 // steepness of an S curve around 0.5, 1 (or 0) keeps the values
+	Gamma         float64 // value^gamma, 1 (or 0) keeps the values
+	Binarize      bool    // the values >= Threshold become 1, the others 0
+	Threshold     float64 // threshold of Binarize, in [0,1]
+	AlphaMask     bool    // multiply the values by the alpha channel
+	Frame         int     // frame of an animated gif
+}
+
+// Decode reads an image file. The format is detected from the content (PNG, JPEG,
+// GIF, PGM or PPM). For an animated GIF, frame chooses the frame that is returned.
+func Decode(path string, frame int) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// the magic bytes of the GIF format, whatever the extension of the file
+	r := bufio.NewReader(file)
+	if magic, _ := r.Peek(4); string(magic) == "GIF8" {
+		animation, err := gif.DecodeAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return gifFrame(animation, frame)
+	}
+	if frame != 0 {
+		return nil, fmt.Errorf("%s is not an animated gif, it has no frame %d", path, frame)
+	}
+
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// gifFrame renders the frame of an animation as it is displayed, since a frame
+// only contains the part of the image that changed since the previous ones.
+func gifFrame(animation *gif.GIF, frame int) (image.Image, error) {
+	if frame < 0 || frame >= len(animation.Image) {
+		return nil, fmt.Errorf("gif frame %d out of range (the gif has %d frames)", frame, len(animation.Image))
+	}
+	bounds := image.Rect(0, 0, animation.Config.Width, animation.Config.Height)
+	canvas := image.NewRGBA(bounds)
+	for i := 0; i <= frame; i++ {
+		previous := image.NewRGBA(bounds)
+		copy(previous.Pix, canvas.Pix)
+
+		img := animation.Image[i]
+		draw.Draw(canvas, img.Bounds(), img, img.Bounds().Min, draw.Over)
+		if i == frame {
+			break
+		}
+		switch animation.Disposal[i] {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, img.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return canvas, nil
+}
+
+// ToFields converts an image to float channels, without the premultiplied alpha
+// of image.Image so transparent pixels keep their color.
+func ToFields(img image.Image) *Fields {
+	bounds := img.Bounds()
+	f := newFields(bounds.Dx(), bounds.Dy())
+
+	index := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			// The RGBA() method returns premultiplied values in the range [0, 65535].
+			r, g, b, a := img.At(x, y).RGBA()
+			f.A[index] = float64(a) / 65535
+			if a != 0 {
+				f.R[index] = float64(r) / float64(a)
+				f.G[index] = float64(g) / float64(a)
+				f.B[index] = float64(b) / float64(a)
+			}
+			index++
+		}
+	}
+	return f
+}
+
+// Load decodes the image at path and applies the options.
+func Load(path string, opts LoadOptions) (*Fields, error) {
+	img, err := Decode(path, opts.Frame)
+	if err != nil {
+		return nil, err
+	}
+	return Convert(ToFields(img), opts)
+}
+
+// Convert resamples f to the grid size and applies the tone options.
+func Convert(f *Fields, opts LoadOptions) (*Fields, error) {
+	if opts.Width != 0 && opts.Height != 0 && (opts.Width != f.Width || opts.Height != f.Height) {
+		var err error
+		f, err = resize(f, opts.Width, opts.Height, opts.Resample, opts.Fit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range f.R {
+		r, g, b := f.R[i], f.G[i], f.B[i]
+		if opts.Grayscale {
+			r = luminance(r, g, b)
+			g, b = r, r
+		}
+		r, g, b = tone(r, opts), tone(g, opts), tone(b, opts)
+		if opts.AlphaMask {
+			r, g, b = r*f.A[i], g*f.A[i], b*f.A[i]
+		}
+		f.R[i], f.G[i], f.B[i] = r, g, b
+	}
+	return f, nil
+}
+
+// Pixels returns the channels as R,G,B bytes, the format of the OpenGL texture.
+func (f *Fields) Pixels() []uint8 {
+	pixels := make([]uint8, f.Width*f.Height*3)
+	for i := range f.R {
+		pixels[i*3] = uint8(255*clamp(f.R[i]) + 0.5)
+		pixels[i*3+1] = uint8(255*clamp(f.G[i]) + 0.5)
+		pixels[i*3+2] = uint8(255*clamp(f.B[i]) + 0.5)
+	}
+	return pixels
+}
+
+// Luminance returns the luminance of each pixel, for the models with a single channel.
+func (f *Fields) Luminance() []float64 {
+	values := make([]float64, len(f.R))
+	for i := range f.R {
+		values[i] = luminance(f.R[i], f.G[i], f.B[i])
+	}
+	return values
+}
+
+func newFields(width, height int) *Fields {
+	return &Fields{
+		Width: width, Height: height,
+		R: make([]float64, width*height),
+		G: make([]float64, width*height),
+		B: make([]float64, width*height),
+		A: make([]float64, width*height),
+	}
+}
+
+func clamp(x float64) float64 {
+	return math.Max(0, math.Min(1, x))
+}
+
+func luminance(r, g, b float64) float64 {
+	// Rec. 709 luminance
+	return 0.2126*r + 0.7152*g + 0.0722*b
+}
+
+func tone(v float64, opts LoadOptions) float64 {
+	// Applies invert, contrast, gamma and threshold to a single value
+	if opts.Invert {
+		v = 1 - v
+	}
+	if opts.Contrast != 0 && opts.Contrast != 1 {
+		// logistic curve normalized so that 0 and 1 are kept
+		s := func(x float64) float64 { return 1 / (1 + math.Exp(-opts.Contrast*4*(x-0.5))) }
+		v = (s(v) - s(0)) / (s(1) - s(0))
+	}
+	if opts.Gamma != 0 && opts.Gamma != 1 {
+		v = math.Pow(clamp(v), opts.Gamma)
+	}
+	if opts.Binarize {
+		if v >= opts.Threshold {
+			v = 1
+		} else {
+			v = 0
+		}
+	}
+	return v
+}
+
+// resize maps f on a width x height grid. The part of the source that is used and the
+// part of the destination it covers depend on fit, the rest of the destination stays at 0.
+func resize(f *Fields, width, height int, resample, fit string) (*Fields, error) {
+	srcX, srcY, srcW, srcH := 0.0, 0.0, float64(f.Width), float64(f.Height)
+	dstX, dstY, dstW, dstH := 0.0, 0.0, float64(width), float64(height)
+
+	srcRatio := srcW / srcH
+	dstRatio := dstW / dstH
+	switch fit {
+	case "", "stretch":
+	case "crop":
+		if srcRatio > dstRatio {
+			srcW = srcH * dstRatio
+			srcX = (float64(f.Width) - srcW) / 2
+		} else {
+			srcH = srcW / dstRatio
+			srcY = (float64(f.Height) - srcH) / 2
+		}
+	case "pad":
+		if srcRatio > dstRatio {
+			dstH = dstW / srcRatio
+			dstY = (float64(height) - dstH) / 2
+		} else {
+			dstW = dstH * srcRatio
+			dstX = (float64(width) - dstW) / 2
+		}
+	default:
+		return nil, fmt.Errorf("unknown fit mode %q (stretch, crop or pad)", fit)
+	}
+
+	var sample func(c []float64, x0, y0, x1, y1 float64) float64
+	switch resample {
+	case "nearest":
+		sample = f.nearest
+	case "", "bilinear":
+		sample = f.bilinear
+	case "area":
+		sample = f.area
+	default:
+		return nil, fmt.Errorf("unknown resampling %q (nearest, bilinear or area)", resample)
+	}
+
+	out := newFields(width, height)
+	scaleX, scaleY := srcW/dstW, srcH/dstH
+	for y := 0; y < height; y++ {
+		if float64(y)+0.5 < dstY || float64(y)+0.5 > dstY+dstH {
+			continue
+		}
+		// source area covered by this destination line
+		y0 := srcY + (float64(y)-dstY)*scaleY
+		y1 := y0 + scaleY
+		for x := 0; x < width; x++ {
+			if float64(x)+0.5 < dstX || float64(x)+0.5 > dstX+dstW {
+				continue
+			}
+			x0 := srcX + (float64(x)-dstX)*scaleX
+			x1 := x0 + scaleX
+			index := y*width + x
+			out.R[index] = sample(f.R, x0, y0, x1, y1)
+			out.G[index] = sample(f.G, x0, y0, x1, y1)
+			out.B[index] = sample(f.B, x0, y0, x1, y1)
+			out.A[index] = sample(f.A, x0, y0, x1, y1)
+		}
+	}
+	return out, nil
+}
+
+func (f *Fields) at(c []float64, x, y int) float64 {
+	// Value of a channel with the coordinates clamped to the image
+	x = max(0, min(f.Width-1, x))
+	y = max(0, min(f.Height-1, y))
+	return c[y*f.Width+x]
+}
+
+func (f *Fields) nearest(c []float64, x0, y0, x1, y1 float64) float64 {
+	return f.at(c, int((x0+x1)/2), int((y0+y1)/2))
+}
+
+func (f *Fields) bilinear(c []float64, x0, y0, x1, y1 float64) float64 {
+	// pixel centers are at +0.5
+	x := (x0+x1)/2 - 0.5
+	y := (y0+y1)/2 - 0.5
+	ix, iy := int(math.Floor(x)), int(math.Floor(y))
+	fx, fy := x-float64(ix), y-float64(iy)
+
+	top := f.at(c, ix, iy)*(1-fx) + f.at(c, ix+1, iy)*fx
+	bottom := f.at(c, ix, iy+1)*(1-fx) + f.at(c, ix+1, iy+1)*fx
+	return top*(1-fy) + bottom*fy
+}
+
+func (f *Fields) area(c []float64, x0, y0, x1, y1 float64) float64 {
+	// Average of the source pixels weighted by how much of them is covered by [x0,x1[ x [y0,y1[
+	sum, total := 0.0, 0.0
+	for y := int(math.Floor(y0)); float64(y) < y1; y++ {
+		wy := math.Min(y1, float64(y+1)) - math.Max(y0, float64(y))
+		for x := int(math.Floor(x0)); float64(x) < x1; x++ {
+			wx := math.Min(x1, float64(x+1)) - math.Max(x0, float64(x))
+			sum += wx * wy * f.at(c, x, y)
+			total += wx * wy
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return sum / total
+}
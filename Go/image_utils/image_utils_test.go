@@ -0,0 +1,194 @@
+package image_utils
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// gradient returns a width x height image whose red channel goes from 0 on the
+// left column to 1 on the right one, and whose green channel is the same from
+// top to bottom.
+func gradient(width, height int) *Fields {
+	f := newFields(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			f.R[i] = float64(x) / float64(width-1)
+			f.G[i] = float64(y) / float64(height-1)
+			f.B[i] = 0.5
+			f.A[i] = 1
+		}
+	}
+	return f
+}
+
+// near returns whether a and b are equal but for the rounding errors.
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-12
+}
+
+// TestResample scales a 4 x 4 gradient to square grids: as R only depends on x
+// and G on y, the values of a line of R are the ones of a column of G.
+func TestResample(t *testing.T) {
+	tests := []struct {
+		resample string
+		size     int
+		want     []float64 // values of a line of R
+	}{
+		// downscaling, a pixel covers 2 x 2 pixels of the source
+		{"nearest", 2, []float64{1.0 / 3, 1}},
+		{"bilinear", 2, []float64{1.0 / 6, 5.0 / 6}},
+		{"area", 2, []float64{1.0 / 6, 5.0 / 6}},
+		// a pixel covers 4/3 pixels of the source
+		{"nearest", 3, []float64{0, 2.0 / 3, 1}},
+		{"area", 3, []float64{1.0 / 12, 1.0 / 2, 11.0 / 12}},
+		// upscaling, the pixels of the edges are clamped
+		{"nearest", 8, []float64{0, 0, 1.0 / 3, 1.0 / 3, 2.0 / 3, 2.0 / 3, 1, 1}},
+		{"bilinear", 8, []float64{0, 1.0 / 12, 3.0 / 12, 5.0 / 12, 7.0 / 12, 9.0 / 12, 11.0 / 12, 1}},
+		{"", 8, []float64{0, 1.0 / 12, 3.0 / 12, 5.0 / 12, 7.0 / 12, 9.0 / 12, 11.0 / 12, 1}}, // bilinear by default
+		{"area", 8, []float64{0, 0, 1.0 / 3, 1.0 / 3, 2.0 / 3, 2.0 / 3, 1, 1}},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s/%d", test.resample, test.size), func(t *testing.T) {
+			f, err := Convert(gradient(4, 4), LoadOptions{Width: test.size, Height: test.size, Resample: test.resample})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Width != test.size || f.Height != test.size || len(f.R) != test.size*test.size {
+				t.Fatalf("%d x %d with %d values, want %d x %d", f.Width, f.Height, len(f.R), test.size, test.size)
+			}
+			for y := 0; y < test.size; y++ {
+				for x := 0; x < test.size; x++ {
+					i := y*test.size + x
+					if !near(f.R[i], test.want[x]) || !near(f.G[i], test.want[y]) || !near(f.B[i], 0.5) || !near(f.A[i], 1) {
+						t.Fatalf("pixel (%d,%d): %g %g %g %g, want %g %g 0.5 1", x, y, f.R[i], f.G[i], f.B[i], f.A[i], test.want[x], test.want[y])
+					}
+				}
+			}
+		})
+	}
+	if _, err := Convert(gradient(4, 4), LoadOptions{Width: 2, Height: 2, Resample: "bicubic"}); err == nil {
+		t.Errorf("bicubic: no error")
+	}
+}
+
+// TestFit maps a 4 x 2 gradient on grids of another ratio, with the area resampling.
+func TestFit(t *testing.T) {
+	tests := []struct {
+		fit           string
+		width, height int
+		r, g          [][]float64 // lines of R and G, -1 for the padding (all the channels at 0)
+	}{
+		{"stretch", 2, 2,
+			[][]float64{{1.0 / 6, 5.0 / 6}, {1.0 / 6, 5.0 / 6}},
+			[][]float64{{0, 0}, {1, 1}}},
+		{"", 2, 4, // stretch by default
+			[][]float64{{1.0 / 6, 5.0 / 6}, {1.0 / 6, 5.0 / 6}, {1.0 / 6, 5.0 / 6}, {1.0 / 6, 5.0 / 6}},
+			[][]float64{{0, 0}, {0, 0}, {1, 1}, {1, 1}}},
+		{"crop", 2, 2, // the middle 2 x 2 pixels of the source
+			[][]float64{{1.0 / 3, 2.0 / 3}, {1.0 / 3, 2.0 / 3}},
+			[][]float64{{0, 0}, {1, 1}}},
+		{"crop", 8, 2, // the middle 4 x 1 pixels of the source
+			[][]float64{{0, 0, 1.0 / 3, 1.0 / 3, 2.0 / 3, 2.0 / 3, 1, 1}, {0, 0, 1.0 / 3, 1.0 / 3, 2.0 / 3, 2.0 / 3, 1, 1}},
+			[][]float64{{0, 0, 0, 0, 0, 0, 0, 0}, {1, 1, 1, 1, 1, 1, 1, 1}}},
+		{"pad", 4, 4, // a line of padding above and below
+			[][]float64{{-1, -1, -1, -1}, {0, 1.0 / 3, 2.0 / 3, 1}, {0, 1.0 / 3, 2.0 / 3, 1}, {-1, -1, -1, -1}},
+			[][]float64{{-1, -1, -1, -1}, {0, 0, 0, 0}, {1, 1, 1, 1}, {-1, -1, -1, -1}}},
+		{"pad", 8, 2, // two columns of padding on each side
+			[][]float64{{-1, -1, 0, 1.0 / 3, 2.0 / 3, 1, -1, -1}, {-1, -1, 0, 1.0 / 3, 2.0 / 3, 1, -1, -1}},
+			[][]float64{{-1, -1, 0, 0, 0, 0, -1, -1}, {-1, -1, 1, 1, 1, 1, -1, -1}}},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s/%dx%d", test.fit, test.width, test.height), func(t *testing.T) {
+			f, err := Convert(gradient(4, 2), LoadOptions{Width: test.width, Height: test.height, Resample: "area", Fit: test.fit})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Width != test.width || f.Height != test.height {
+				t.Fatalf("%d x %d, want %d x %d", f.Width, f.Height, test.width, test.height)
+			}
+			for y := 0; y < test.height; y++ {
+				for x := 0; x < test.width; x++ {
+					i := y*test.width + x
+					r, g, b, a := test.r[y][x], test.g[y][x], 0.5, 1.0
+					if r < 0 {
+						r, g, b, a = 0, 0, 0, 0
+					}
+					if !near(f.R[i], r) || !near(f.G[i], g) || !near(f.B[i], b) || !near(f.A[i], a) {
+						t.Fatalf("pixel (%d,%d): %g %g %g %g, want %g %g %g %g", x, y, f.R[i], f.G[i], f.B[i], f.A[i], r, g, b, a)
+					}
+				}
+			}
+		})
+	}
+	if _, err := Convert(gradient(4, 2), LoadOptions{Width: 2, Height: 2, Fit: "zoom"}); err == nil {
+		t.Errorf("zoom: no error")
+	}
+}
+
+func TestTone(t *testing.T) {
+	// the S curves keep 0, 0.5 and 1 and are increasing
+	for _, contrast := range []float64{0.25, 0.5, 2, 10} {
+		opts := LoadOptions{Contrast: contrast}
+		for _, v := range []float64{0, 0.5, 1} {
+			if got := tone(v, opts); !near(got, v) {
+				t.Errorf("contrast %g: tone(%g) = %g, want %g", contrast, v, got, v)
+			}
+		}
+		previous := 0.0
+		for v := 0.05; v <= 1; v += 0.05 {
+			got := tone(v, opts)
+			if got <= previous {
+				t.Fatalf("contrast %g: tone(%g) = %g, not above %g", contrast, v, got, previous)
+			}
+			previous = got
+		}
+	}
+
+	tests := []struct {
+		name  string
+		opts  LoadOptions
+		value float64
+		want  float64
+	}{
+		{"no option", LoadOptions{}, 0.3, 0.3},
+		{"contrast 1", LoadOptions{Contrast: 1}, 0.3, 0.3},
+		{"gamma 1", LoadOptions{Gamma: 1}, 0.3, 0.3},
+		{"gamma of 0", LoadOptions{Gamma: 2}, 0, 0},
+		{"gamma of 1", LoadOptions{Gamma: 0.5}, 1, 1},
+		{"gamma 2", LoadOptions{Gamma: 2}, 0.25, 0.0625},
+		{"gamma 0.5", LoadOptions{Gamma: 0.5}, 0.25, 0.5},
+		{"invert 0", LoadOptions{Invert: true}, 0, 1},
+		{"invert 1", LoadOptions{Invert: true}, 1, 0},
+		{"invert then gamma", LoadOptions{Invert: true, Gamma: 2}, 0.2, 0.64},
+		{"below the threshold", LoadOptions{Binarize: true, Threshold: 0.5}, 0.49, 0},
+		{"at the threshold", LoadOptions{Binarize: true, Threshold: 0.5}, 0.5, 1},
+		{"threshold 0", LoadOptions{Binarize: true, Threshold: 0}, 0, 1},
+		{"gamma then threshold", LoadOptions{Gamma: 2, Binarize: true, Threshold: 0.5}, 0.6, 0},
+	}
+	for _, test := range tests {
+		if got := tone(test.value, test.opts); !near(got, test.want) {
+			t.Errorf("%s: tone(%g) = %g, want %g", test.name, test.value, got, test.want)
+		}
+	}
+}
+
+func TestConvertChannels(t *testing.T) {
+	f := newFields(2, 1)
+	f.R[0], f.G[0], f.B[0], f.A[0] = 1, 0, 0, 0.5
+	f.R[1], f.G[1], f.B[1], f.A[1] = 0, 1, 1, 1
+	f, err := Convert(f, LoadOptions{Grayscale: true, AlphaMask: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{0.2126 * 0.5, 0.7152 + 0.0722}
+	for i, v := range want {
+		if !near(f.R[i], v) || !near(f.G[i], v) || !near(f.B[i], v) {
+			t.Errorf("pixel %d: %g %g %g, want %g", i, f.R[i], f.G[i], f.B[i], v)
+		}
+	}
+	if pixels := f.Pixels(); pixels[0] != 27 || pixels[3] != 201 {
+		t.Errorf("pixels %v", pixels)
+	}
+}
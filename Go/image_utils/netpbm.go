@@ -0,0 +1,218 @@
+package image_utils
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Netpbm grayscale (PGM, P2/P5) and color (PPM, P3/P6) images, 8 or 16 bits.
+// Format reference : https://netpbm.sourceforge.net/doc/pgm.html and ppm.html
+// The formats are registered in the image package so Decode handles them like PNG.
+
+func init() {
+	for _, magic := range []string{"P2", "P3", "P5", "P6"} {
+		image.RegisterFormat("netpbm", magic, DecodeNetpbm, decodeNetpbmConfig)
+	}
+}
+
+type netpbmHeader struct {
+	magic         string
+	width, height int
+	maxval        int
+}
+
+func readToken(r *bufio.Reader) (string, error) {
+	// Reads the next whitespace separated token of the header, skipping the # comments
+	token := []byte{}
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(token) > 0 {
+				return string(token), nil
+			}
+			return "", err
+		}
+		switch {
+		case c == '#' && len(token) == 0:
+			if _, err := r.ReadString('\n'); err != nil {
+				return "", err
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if len(token) > 0 {
+				return string(token), nil
+			}
+		default:
+			token = append(token, c)
+		}
+	}
+}
+
+func readHeader(r *bufio.Reader) (netpbmHeader, error) {
+	var h netpbmHeader
+	var err error
+	if h.magic, err = readToken(r); err != nil {
+		return h, err
+	}
+	if h.magic != "P2" && h.magic != "P3" && h.magic != "P5" && h.magic != "P6" {
+		return h, fmt.Errorf("netpbm: unsupported magic number %q", h.magic)
+	}
+
+	values := make([]int, 3)
+	for i := range values {
+		token, err := readToken(r)
+		if err != nil {
+			return h, err
+		}
+		if values[i], err = strconv.Atoi(token); err != nil {
+			return h, fmt.Errorf("netpbm: invalid header value %q", token)
+		}
+	}
+	h.width, h.height, h.maxval = values[0], values[1], values[2]
+	if h.width <= 0 || h.height <= 0 || h.maxval <= 0 || h.maxval > 65535 {
+		return h, fmt.Errorf("netpbm: invalid header %dx%d maxval %d", h.width, h.height, h.maxval)
+	}
+	if h.height > math.MaxInt/6/h.width {
+		return h, fmt.Errorf("netpbm: image of %dx%d pixels too big", h.width, h.height)
+	}
+	return h, nil
+}
+
+// samples reads the width x height x channels samples of the raster, in [0, maxval].
+// They are read before the image is allocated, so a header announcing more pixels
+// than the data holds is rejected without allocating them.
+func (h netpbmHeader) samples(r *bufio.Reader, channels int) ([]uint16, error) {
+	count := h.width * h.height * channels
+	truncated := func(found int) error {
+		return fmt.Errorf("netpbm: truncated raster, %dx%d maxval %d needs %d samples, %d found", h.width, h.height, h.maxval, count, found)
+	}
+
+	if h.magic == "P2" || h.magic == "P3" {
+		var samples []uint16
+		for len(samples) < count {
+			token, err := readToken(r)
+			if err == io.EOF {
+				return nil, truncated(len(samples))
+			} else if err != nil {
+				return nil, err
+			}
+			v, err := strconv.Atoi(token)
+			if err != nil || v < 0 {
+				return nil, fmt.Errorf("netpbm: invalid sample %q", token)
+			}
+			samples = append(samples, uint16(min(v, h.maxval)))
+		}
+		return samples, nil
+	}
+
+	size := 1 // bytes per sample, 16 bits samples are big endian
+	if h.maxval >= 256 {
+		size = 2
+	}
+	data, err := io.ReadAll(io.LimitReader(r, int64(count*size)))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < count*size {
+		return nil, truncated(len(data) / size)
+	}
+	samples := make([]uint16, count)
+	for i := range samples {
+		v := int(data[i*size])
+		if size == 2 {
+			v = v<<8 | int(data[i*size+1])
+		}
+		samples[i] = uint16(min(v, h.maxval))
+	}
+	return samples, nil
+}
+
+func decodeNetpbmConfig(r io.Reader) (image.Config, error) {
+	h, err := readHeader(bufio.NewReader(r))
+	if err != nil {
+		return image.Config{}, err
+	}
+	model := color.RGBA64Model
+	if h.magic == "P2" || h.magic == "P5" {
+		model = color.Gray16Model
+	}
+	return image.Config{ColorModel: model, Width: h.width, Height: h.height}, nil
+}
+
+// DecodeNetpbm reads a PGM or PPM image. Samples are scaled to 16 bits so
+// images with a maxval up to 65535 keep all their precision.
+func DecodeNetpbm(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+	h, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := 3
+	if h.magic == "P2" || h.magic == "P5" {
+		channels = 1
+	}
+	samples, err := h.samples(br, channels)
+	if err != nil {
+		return nil, err
+	}
+	scale := func(v uint16) uint16 {
+		return uint16(math.Round(float64(v) * 65535 / float64(h.maxval)))
+	}
+
+	rect := image.Rect(0, 0, h.width, h.height)
+	if channels == 1 {
+		img := image.NewGray16(rect)
+		for i, v := range samples {
+			img.SetGray16(i%h.width, i/h.width, color.Gray16{Y: scale(v)})
+		}
+		return img, nil
+	}
+
+	img := image.NewRGBA64(rect)
+	for i := 0; i < len(samples); i += 3 {
+		pixel := i / 3
+		img.SetRGBA64(pixel%h.width, pixel/h.width, color.RGBA64{R: scale(samples[i]), G: scale(samples[i+1]), B: scale(samples[i+2]), A: 0xffff})
+	}
+	return img, nil
+}
+
+// EncodeNetpbm writes f as a binary PGM (gray, the luminance is used) or PPM with 8 or 16 bits samples.
+func EncodeNetpbm(w io.Writer, f *Fields, gray bool, bits int) error {
+	if bits != 8 && bits != 16 {
+		return fmt.Errorf("netpbm: %d bits samples are not supported", bits)
+	}
+	maxval := 255
+	if bits == 16 {
+		maxval = 65535
+	}
+	magic := "P6"
+	if gray {
+		magic = "P5"
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s\n%d %d\n%d\n", magic, f.Width, f.Height, maxval)
+
+	write := func(v float64) {
+		sample := int(math.Round(clamp(v) * float64(maxval)))
+		if bits == 16 {
+			bw.WriteByte(byte(sample >> 8))
+		}
+		bw.WriteByte(byte(sample))
+	}
+	for i := range f.R {
+		if gray {
+			write(luminance(f.R[i], f.G[i], f.B[i]))
+		} else {
+			write(f.R[i])
+			write(f.G[i])
+			write(f.B[i])
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,117 @@
+package image_utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDecodeNetpbm(t *testing.T) {
+	tests := []struct {
+		name, file string
+		want       []uint16 // samples scaled to 16 bits, line by line
+	}{
+		{"P5 8 bits", "P5\n3 1\n255\n\x00\x80\xff", []uint16{0, 0x8080, 0xffff}},
+		{"P5 16 bits", "P5 2 1 65535\n\x12\x34\xff\xfe", []uint16{0x1234, 0xfffe}},
+		{"P5 maxval 1000", "P5 2 1 1000\n\x01\xf4\x03\xe8", []uint16{32768, 65535}},
+		{"P2 comments", "P2\n# a comment\n2 2 # size\n15\n0 5\n10\n15", []uint16{0, 21845, 43690, 65535}},
+		{"P6 16 bits", "P6 1 1 65535\n\x00\x01\x80\x00\xff\xff", []uint16{1, 0x8000, 0xffff}},
+		{"P3", "P3 2 1 255\n255 0 0  0 0 255\n", []uint16{0xffff, 0, 0, 0, 0, 0xffff}},
+		{"sample above maxval", "P2 1 1 255\n300\n", []uint16{0xffff}},
+	}
+	for _, test := range tests {
+		img, err := DecodeNetpbm(strings.NewReader(test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		var got []uint16
+		bounds := img.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				switch c := img.At(x, y).(type) {
+				case color.Gray16:
+					got = append(got, c.Y)
+				case color.RGBA64:
+					got = append(got, c.R, c.G, c.B)
+				}
+			}
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s: samples %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// TestDecodeNetpbmErrors checks that the headers not matching the raster that
+// follows are rejected, the huge ones before the image is allocated.
+func TestDecodeNetpbmErrors(t *testing.T) {
+	for name, file := range map[string]string{
+		"magic":            "P4 1 1\n\x00",
+		"width":            "P5 0 1 255\n",
+		"maxval":           "P5 1 1 65536\n\x00\x00",
+		"not a number":     "P5 1 x 255\n\x00",
+		"no maxval":        "P5 1 1",
+		"one byte short":   "P5 4 4 255\n" + strings.Repeat("\x00", 15),
+		"8 bits for 16":    "P5 4 1 65535\n\x00\x00\x00\x00",
+		"P6 one sample":    "P6 2 1 255\n\x00\x00\x00\x00\x00",
+		"P2 one sample":    "P2 2 2 255\n1 2 3\n",
+		"P2 negative":      "P2 1 1 255\n-1\n",
+		"P3 word":          "P3 1 1 255\n1 2 three\n",
+		"huge":             "P6 1000000 1000000 65535\n\x00\x00\x00\x00\x00\x00",
+		"huge ascii":       "P3 1000000 1000000 255\n0 0 0\n",
+		"overflowing size": "P5 9223372036854775807 9223372036854775807 255\n\x00",
+		"no raster":        "P5 1 1 255\n",
+	} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := DecodeNetpbm(strings.NewReader(file))
+		runtime.ReadMemStats(&after)
+		if err == nil {
+			t.Errorf("%s: no error", name)
+		}
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+			t.Errorf("%s: %d bytes allocated for a file of %d bytes", name, allocated, len(file))
+		}
+	}
+
+	// the same through image.Decode, which finds the format from the magic number
+	if _, _, err := image.Decode(strings.NewReader("P5 2 2 255\n\x00\x00\x00")); err == nil {
+		t.Errorf("image.Decode of a truncated PGM: no error")
+	}
+	config, format, err := image.DecodeConfig(strings.NewReader("P6\n640 480\n255\n"))
+	if err != nil || format != "netpbm" || config.Width != 640 || config.Height != 480 {
+		t.Errorf("image.DecodeConfig = %+v, %q, %v", config, format, err)
+	}
+}
+
+func TestEncodeNetpbm(t *testing.T) {
+	f := newFields(2, 1)
+	f.R[0], f.G[0], f.B[0] = 1, 0.5, 0
+	f.R[1], f.G[1], f.B[1] = 0, 0, 1
+	tests := []struct {
+		gray bool
+		bits int
+		want string
+	}{
+		{false, 8, "P6\n2 1\n255\n\xff\x80\x00\x00\x00\xff"},
+		{false, 16, "P6\n2 1\n65535\n\xff\xff\x80\x00\x00\x00\x00\x00\x00\x00\xff\xff"},
+		{true, 8, "P5\n2 1\n255\n\x91\x12"}, // the Rec. 709 luminance
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		if err := EncodeNetpbm(&out, f, test.gray, test.bits); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != test.want {
+			t.Errorf("gray %v, %d bits: %q, want %q", test.gray, test.bits, out.String(), test.want)
+		}
+	}
+	if err := EncodeNetpbm(new(bytes.Buffer), f, false, 12); err == nil {
+		t.Errorf("12 bits: no error")
+	}
+}
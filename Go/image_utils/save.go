@@ -0,0 +1,90 @@
+package image_utils
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Export of the float fields, symmetrical to the loading : a 16 bits PNG, PGM or PPM
+// written by Save is read back by Load with the exact same 16 bits values.
+
+// Save writes f to path, the format is chosen from the extension (.png, .pgm, .ppm,
+// .jpg, .gif). bits (8 or 16) is the sample size for PNG and Netpbm.
+func Save(path string, f *Fields, bits int) error {
+	if bits != 8 && bits != 16 {
+		return fmt.Errorf("%d bits images are not supported", bits)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		err = png.Encode(file, f.Image(bits))
+	case ".pgm":
+		err = EncodeNetpbm(file, f, true, bits)
+	case ".ppm":
+		err = EncodeNetpbm(file, f, false, bits)
+	case ".jpg", ".jpeg":
+		err = jpeg.Encode(file, f.Image(8), &jpeg.Options{Quality: 95})
+	case ".gif":
+		paletted := image.NewPaletted(image.Rect(0, 0, f.Width, f.Height), palette.Plan9)
+		draw.FloydSteinberg.Draw(paletted, paletted.Bounds(), f.Image(8), image.Point{})
+		err = gif.Encode(file, paletted, nil)
+	default:
+		err = fmt.Errorf("unknown image format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Image converts f to an image with 8 or 16 bits per channel.
+// A nil alpha channel is fully opaque.
+func (f *Fields) Image(bits int) image.Image {
+	quantize := func(v float64, maxval float64) float64 { return math.Round(clamp(v) * maxval) }
+	alpha := func(i int) float64 {
+		if f.A == nil {
+			return 1
+		}
+		return f.A[i]
+	}
+	rect := image.Rect(0, 0, f.Width, f.Height)
+
+	if bits == 16 {
+		img := image.NewNRGBA64(rect)
+		for i := range f.R {
+			img.SetNRGBA64(i%f.Width, i/f.Width, color.NRGBA64{
+				R: uint16(quantize(f.R[i], 65535)),
+				G: uint16(quantize(f.G[i], 65535)),
+				B: uint16(quantize(f.B[i], 65535)),
+				A: uint16(quantize(alpha(i), 65535)),
+			})
+		}
+		return img
+	}
+
+	img := image.NewNRGBA(rect)
+	for i := range f.R {
+		img.SetNRGBA(i%f.Width, i/f.Width, color.NRGBA{
+			R: uint8(quantize(f.R[i], 255)),
+			G: uint8(quantize(f.G[i], 255)),
+			B: uint8(quantize(f.B[i], 255)),
+			A: uint8(quantize(alpha(i), 255)),
+		})
+	}
+	return img
+}
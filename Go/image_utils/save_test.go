@@ -0,0 +1,134 @@
+package image_utils
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testFields returns fields whose values are multiples of 1/65535, all different,
+// so a 16 bits image holds them exactly.
+func testFields(width, height int, gray bool) *Fields {
+	f := newFields(width, height)
+	for i := range f.R {
+		f.R[i] = float64(i*977%65536) / 65535
+		f.G[i] = float64(i*7919%65536) / 65535
+		f.B[i] = float64(65535-i*31%65536) / 65535
+		if gray {
+			f.G[i], f.B[i] = f.R[i], f.R[i]
+		}
+		f.A[i] = 1
+	}
+	return f
+}
+
+// TestSave16BitsRoundTrip checks that the 16 bits images written by Save are read
+// back by Load with the exact same values.
+func TestSave16BitsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"fields.png", "fields.ppm", "fields.pgm"} {
+		gray := filepath.Ext(name) == ".pgm"
+		f := testFields(37, 11, gray)
+		path := filepath.Join(dir, name)
+		if err := Save(path, f, 16); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		loaded, err := Load(path, LoadOptions{})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if loaded.Width != f.Width || loaded.Height != f.Height {
+			t.Fatalf("%s: %d x %d, want %d x %d", name, loaded.Width, loaded.Height, f.Width, f.Height)
+		}
+		for i := range f.R {
+			if loaded.R[i] != f.R[i] || loaded.G[i] != f.G[i] || loaded.B[i] != f.B[i] || loaded.A[i] != 1 {
+				t.Fatalf("%s, pixel %d: %g %g %g %g, want %g %g %g 1", name, i, loaded.R[i], loaded.G[i], loaded.B[i], loaded.A[i], f.R[i], f.G[i], f.B[i])
+			}
+		}
+	}
+
+	// 8 bits keep the values to 1/255
+	f := testFields(16, 16, false)
+	path := filepath.Join(dir, "fields8.png")
+	if err := Save(path, f, 8); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range f.R {
+		if math.Abs(loaded.R[i]-f.R[i]) > 0.5/255+1e-12 {
+			t.Fatalf("8 bits, pixel %d: %g, want %g to 1/255", i, loaded.R[i], f.R[i])
+		}
+	}
+	if err := Save(filepath.Join(dir, "fields.png"), f, 12); err == nil {
+		t.Errorf("12 bits: no error")
+	}
+	if err := Save(filepath.Join(dir, "fields.tiff"), f, 8); err == nil {
+		t.Errorf(".tiff: no error")
+	}
+}
+
+// TestGifDisposal checks the frames of an animation as they are displayed: each
+// frame only draws a pixel of a 4 x 1 image, over what the disposal of the
+// previous frame left.
+func TestGifDisposal(t *testing.T) {
+	transparent, red, green, blue := color.RGBA{}, color.RGBA{255, 0, 0, 255}, color.RGBA{0, 255, 0, 255}, color.RGBA{0, 0, 255, 255}
+	palette := color.Palette{transparent, red, green, blue}
+	frame := func(x0, x1 int, index uint8) *image.Paletted {
+		img := image.NewPaletted(image.Rect(x0, 0, x1, 1), palette)
+		for i := range img.Pix {
+			img.Pix[i] = index
+		}
+		return img
+	}
+	animation := &gif.GIF{
+		Image: []*image.Paletted{
+			frame(0, 4, 1), // red background, kept
+			frame(1, 2, 2), // green, cleared to the background by the next frame
+			frame(2, 3, 3), // blue, removed by the next frame
+			frame(3, 4, 0), // transparent, nothing changes
+		},
+		Delay:    []int{10, 10, 10, 10},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalPrevious, gif.DisposalNone},
+		Config:   image.Config{ColorModel: palette, Width: 4, Height: 1},
+	}
+	path := filepath.Join(t.TempDir(), "animation.gif")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.EncodeAll(file, animation); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	want := [][4]color.RGBA{
+		{red, red, red, red},
+		{red, green, red, red},
+		{red, transparent, blue, red},
+		{red, transparent, red, red},
+	}
+	for i, pixels := range want {
+		img, err := Decode(path, i)
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		for x, c := range pixels {
+			if got := color.RGBAModel.Convert(img.At(x, 0)); got != c {
+				t.Errorf("frame %d, pixel %d: %v, want %v", i, x, got, c)
+			}
+		}
+	}
+	if _, err := Decode(path, 4); err == nil {
+		t.Errorf("frame 4 of 4: no error")
+	}
+	if _, err := Decode(path, -1); err == nil {
+		t.Errorf("frame -1: no error")
+	}
+}
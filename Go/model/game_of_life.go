@@ -0,0 +1,482 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+
+	"main/boundary"
+	"main/game_of_life"
+)
+
+// GameOfLife is Conway's game of life, or another Life-like rule (see SetRule), the
+// cells are alive or dead. The generations are computed by one of LifeEngines, a
+// step advances 2^k generations (see SetStep).
+type GameOfLife struct {
+	engine     string
+	step       int  // log2 of the generations of a step
+	zoom       int  // log2 of the cells of a pixel, unbounded engines only
+	follow     bool // the view follows the cells, unbounded engines only
+	generation uint64
+	Model
+}
+
+// LifeEngines are the implementations of the game of life. cells and bits give the
+// same results on the grid, with the same boundary (see SetBoundary), hashlife and sparse run on an unbounded
+// plane of which the grid shows a part.
+var LifeEngines = map[string]func() Model{
+	"cells":    func() Model { return &lifeCells{} },  // the first one, a slice per cell
+	"bits":     func() Model { return &lifeBits{} },   // 64 cells per word, see game_of_life.Board
+	"hashlife": func() Model { return &lifeHash{} },   // unbounded, see game_of_life.Universe
+	"sparse":   func() Model { return &lifeSparse{} }, // unbounded, see game_of_life.Sparse
+}
+
+// unbounded is an engine on an unbounded plane, shown through a viewport.
+type unbounded interface {
+	view() *viewport
+}
+
+// IsUnboundedLifeEngine returns whether the engine called name runs on an unbounded plane.
+func IsUnboundedLifeEngine(name string) bool {
+	engine, ok := LifeEngines[name]
+	if !ok {
+		return false
+	}
+	_, isUnbounded := engine().(unbounded)
+	return isUnbounded
+}
+
+// lifeJumper is an engine advancing 2^k generations at once.
+type lifeJumper interface {
+	jump(k int)
+}
+
+// MaxLifeStep is the biggest k of SetStep for the engines computing the 2^k
+// generations of a step one by one, the jumping ones go up to game_of_life.MaxStep.
+const MaxLifeStep = 10
+
+// LifeStepLimit returns the biggest k of SetStep for the engine called name.
+func LifeStepLimit(name string) int {
+	if engine, ok := LifeEngines[name]; ok {
+		if _, jumps := engine().(lifeJumper); jumps {
+			return game_of_life.MaxStep
+		}
+	}
+	return MaxLifeStep
+}
+
+// DefaultLifeEngine is the engine used when none is chosen.
+const DefaultLifeEngine = "bits"
+
+func NewGameOfLife() *GameOfLife {
+	return &GameOfLife{engine: DefaultLifeEngine, Model: LifeEngines[DefaultLifeEngine]()}
+}
+
+// LifeEngineNames returns the names of LifeEngines, sorted.
+func LifeEngineNames() []string {
+	names := make([]string, 0, len(LifeEngines))
+	for name := range LifeEngines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetEngine changes the engine, the grid is set by the next Init. The step, the
+// zoom, the following of the view and the boundary are reset.
+func (m *GameOfLife) SetEngine(name string) error {
+	engine, ok := LifeEngines[name]
+	if !ok {
+		return fmt.Errorf("unknown game of life engine %q (available: %v)", name, LifeEngineNames())
+	}
+	m.engine, m.Model, m.step, m.zoom, m.follow = name, engine(), 0, 0, false
+	game_of_life.SetBoundary(boundary.Boundary{})
+	return nil
+}
+
+func (m *GameOfLife) Engine() string {
+	return m.engine
+}
+
+// SetStep sets the generations of a step to 2^k. HashLife computes them at once,
+// the other engines one by one so k is at most MaxLifeStep for them.
+func (m *GameOfLife) SetStep(k int) error {
+	if limit := LifeStepLimit(m.engine); k < 0 || k > limit {
+		return fmt.Errorf("the generations of a step must be 2^k with 0 <= k <= %d for the %s engine", limit, m.engine)
+	}
+	m.step = k
+	return nil
+}
+
+// SetZoom shows 2^k x 2^k cells per pixel, a pixel is white when one of them is
+// alive. Only the unbounded engines zoom out, the others show their whole grid.
+func (m *GameOfLife) SetZoom(k int) error {
+	engine, ok := m.Model.(unbounded)
+	if k != 0 && !ok {
+		return fmt.Errorf("only the unbounded engines (hashlife, sparse) can zoom out")
+	}
+	if k < 0 || k > MaxLifeZoom {
+		return fmt.Errorf("the zoom must be in [0,%d]", MaxLifeZoom)
+	}
+	if ok {
+		engine.view().zoom = k
+	}
+	m.zoom = k
+	return nil
+}
+
+// SetFollow makes the view follow the cells, it moves when they leave its middle.
+// Only the unbounded engines have a view that moves.
+func (m *GameOfLife) SetFollow(follow bool) error {
+	engine, ok := m.Model.(unbounded)
+	if follow && !ok {
+		return fmt.Errorf("only the unbounded engines (hashlife, sparse) can follow the cells")
+	}
+	if ok {
+		engine.view().follow = follow
+	}
+	m.follow = follow
+	return nil
+}
+
+func (m *GameOfLife) Boundary() boundary.Boundary {
+	return game_of_life.GetBoundary()
+}
+
+// SetBoundary sets what the cells next to the edges of the grid see beyond them.
+// The unbounded engines have no edges, they only accept the torus (the default).
+func (m *GameOfLife) SetBoundary(b boundary.Boundary) error {
+	if _, ok := m.Model.(unbounded); ok && b.Mode != boundary.Torus {
+		return fmt.Errorf("only the bounded engines (bits, cells) have a boundary")
+	}
+	game_of_life.SetBoundary(b)
+	return nil
+}
+
+// MaxLifeZoom is the biggest zoom of SetZoom.
+const MaxLifeZoom = 40
+
+func (m *GameOfLife) Zoom() int {
+	return m.zoom
+}
+
+func (m *GameOfLife) Init(opts Options) error {
+	m.generation = 0
+	return m.Model.Init(opts)
+}
+
+func (m *GameOfLife) Step() {
+	if jumper, ok := m.Model.(lifeJumper); ok {
+		jumper.jump(m.step)
+	} else {
+		for i := 0; i < 1<<m.step; i++ {
+			m.Model.Step()
+		}
+	}
+	m.generation += 1 << m.step
+}
+
+// Generation returns the number of generations since Init.
+func (m *GameOfLife) Generation() uint64 {
+	return m.generation
+}
+
+// Population returns the number of alive cells.
+func (m *GameOfLife) Population() int {
+	return m.Model.(interface{ Population() int }).Population()
+}
+
+// Universe returns the cells of the hashlife engine, nil for the other engines.
+func (m *GameOfLife) Universe() *game_of_life.Universe {
+	if hash, ok := m.Model.(*lifeHash); ok {
+		return hash.universe
+	}
+	return nil
+}
+
+// Box returns the box around the cells of the unbounded engines, ok is false for
+// the other engines.
+func (m *GameOfLife) Box() (x, y, width, height int64, ok bool) {
+	engine, ok := m.Model.(interface {
+		Box() (x, y, width, height int64)
+	})
+	if !ok {
+		return 0, 0, 0, 0, false
+	}
+	x, y, width, height = engine.Box()
+	return x, y, width, height, true
+}
+
+// Pattern returns the alive cells with the rule. The pattern of the grid engines
+// is the whole grid, the one of the unbounded engines is the box around the cells.
+func (m *GameOfLife) Pattern() *game_of_life.Pattern {
+	cells := m.Model.(interface{ Cells() []game_of_life.Cell }).Cells()
+	p := &game_of_life.Pattern{Cells: cells}
+	if _, isUnbounded := m.Model.(unbounded); isUnbounded {
+		p = game_of_life.NewPattern(cells)
+	} else {
+		p.Width, p.Height = m.Size()
+	}
+	p.Rule = m.Rule()
+	return p
+}
+
+func (m *GameOfLife) Rule() string {
+	return game_of_life.GetRule().String()
+}
+
+func (m *GameOfLife) SetRule(rule string) error {
+	r, err := game_of_life.ParseRule(rule)
+	if err != nil {
+		return err
+	}
+	game_of_life.SetRule(r)
+	return nil
+}
+
+// lifeInit checks the options of the game of life engines.
+func lifeInit(opts Options) error {
+	game_of_life.SetSeed(opts.Seed)
+	if opts.Image == nil && opts.Generator != "" {
+		return fmt.Errorf("game_of_life has no generator, use a random grid or an image")
+	}
+	return nil
+}
+
+// lifeCells is the engine of UpdateGrid, each cell is a slice of 3 bytes.
+type lifeCells struct {
+	cells [][][]uint8
+}
+
+func (m *lifeCells) Init(opts Options) error {
+	if err := lifeInit(opts); err != nil {
+		return err
+	}
+	switch {
+	case opts.Image != nil:
+		m.cells = game_of_life.LoadCells(opts.Image.Luminance(), opts.Image.Width, opts.Image.Height, 0.5)
+	case opts.Pattern != nil:
+		m.cells = game_of_life.LoadCells(game_of_life.CellValues(opts.Pattern, opts.Width, opts.Height), opts.Width, opts.Height, 0.5)
+	case opts.Universe != nil:
+		m.cells = game_of_life.LoadCells(game_of_life.CellValues(opts.Universe.Cells(), opts.Width, opts.Height), opts.Width, opts.Height, 0.5)
+	default:
+		m.cells = game_of_life.GenerateRandomPixels(opts.Width, opts.Height, float32(opts.Threshold))
+	}
+	return nil
+}
+
+func (m *lifeCells) Step() {
+	m.cells = game_of_life.UpdateGrid(m.cells)
+}
+
+func (m *lifeCells) Render(pixels []uint8) []uint8 {
+	width, height := m.Size()
+	if len(pixels) != width*height*3 {
+		pixels = make([]uint8, width*height*3)
+	}
+	for y, line := range m.cells {
+		for x, cell := range line {
+			copy(pixels[(y*width+x)*3:], cell)
+		}
+	}
+	return pixels
+}
+
+func (m *lifeCells) Size() (int, int) {
+	return game_of_life.Size()
+}
+
+func (m *lifeCells) Cells() []game_of_life.Cell {
+	var cells []game_of_life.Cell
+	for y, line := range m.cells {
+		for x, cell := range line {
+			if cell[0] == 255 {
+				cells = append(cells, game_of_life.Cell{X: x, Y: y})
+			}
+		}
+	}
+	return cells
+}
+
+func (m *lifeCells) Population() int {
+	count := 0
+	for _, line := range m.cells {
+		for _, cell := range line {
+			if cell[0] == 255 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// lifeBits is the bit-packed engine.
+type lifeBits struct {
+	*game_of_life.Board
+}
+
+func (m *lifeBits) Init(opts Options) error {
+	if err := lifeInit(opts); err != nil {
+		return err
+	}
+	switch {
+	case opts.Image != nil:
+		m.Board = game_of_life.LoadBoard(opts.Image.Luminance(), opts.Image.Width, opts.Image.Height, 0.5)
+	case opts.Pattern != nil:
+		m.Board = game_of_life.LoadBoard(game_of_life.CellValues(opts.Pattern, opts.Width, opts.Height), opts.Width, opts.Height, 0.5)
+	case opts.Universe != nil:
+		m.Board = game_of_life.LoadBoard(game_of_life.CellValues(opts.Universe.Cells(), opts.Width, opts.Height), opts.Width, opts.Height, 0.5)
+	default:
+		m.Board = game_of_life.RandomBoard(opts.Width, opts.Height, float32(opts.Threshold))
+	}
+	return nil
+}
+
+// viewport is the part of an unbounded plane shown on the grid: width x height
+// pixels of 2^zoom x 2^zoom cells, whose top left cell is (x,y).
+type viewport struct {
+	x, y          int64
+	width, height int
+	zoom          int
+	follow        bool
+}
+
+func (v *viewport) view() *viewport {
+	return v
+}
+
+// unboundedInit checks the options of the unbounded engines and puts the viewport
+// on the grid of opts, the cells out of it are kept but hidden.
+func (v *viewport) unboundedInit(opts Options) error {
+	if err := lifeInit(opts); err != nil {
+		return err
+	}
+	if game_of_life.GetRule().Birth[0] {
+		return fmt.Errorf("an unbounded plane can not run a rule with B0, the empty space would be born")
+	}
+	v.x, v.y = 0, 0
+	v.width, v.height = opts.Width, opts.Height
+	if opts.Image != nil {
+		v.width, v.height = opts.Image.Width, opts.Image.Height
+	}
+	return nil
+}
+
+// track moves the view when following the cells whose box is given: just enough
+// to bring the box back in the middle of the view (without an eighth on each
+// side), or on the center of the box when it does not fit there.
+func (v *viewport) track(x, y, width, height int64) {
+	if !v.follow || width == 0 {
+		return
+	}
+	axis := func(view *int64, pixels int, start, size int64) {
+		length := int64(pixels) << v.zoom
+		margin := length / 8
+		switch {
+		case size > length-2*margin:
+			*view = start + size/2 - length/2
+		case start < *view+margin:
+			*view = start - margin
+		case start+size > *view+length-margin:
+			*view = start + size - length + margin
+		}
+	}
+	axis(&v.x, v.width, x, width)
+	axis(&v.y, v.height, y, height)
+}
+
+func (v *viewport) Size() (int, int) {
+	return v.width, v.height
+}
+
+// lifeHash is the HashLife engine.
+type lifeHash struct {
+	universe *game_of_life.Universe
+	viewport
+}
+
+func (m *lifeHash) Init(opts Options) error {
+	if err := m.unboundedInit(opts); err != nil {
+		return err
+	}
+	switch {
+	case opts.Image != nil:
+		values := opts.Image.Luminance()
+		m.universe = game_of_life.LoadUniverse(m.width, m.height, func(x, y int) bool {
+			return values[y*m.width+x] >= 0.5
+		})
+	case opts.Pattern != nil:
+		m.universe = game_of_life.NewUniverse()
+		for _, c := range opts.Pattern {
+			m.universe.Set(int64(c.X), int64(c.Y), true)
+		}
+	case opts.Universe != nil:
+		// A copy, opts is used again by reset
+		m.universe = opts.Universe.Clone()
+	default:
+		// The same cells as the other engines for the same seed
+		board := game_of_life.RandomBoard(opts.Width, opts.Height, float32(opts.Threshold))
+		m.universe = game_of_life.LoadUniverse(m.width, m.height, board.Get)
+	}
+	return nil
+}
+
+func (m *lifeHash) Step() {
+	m.jump(0)
+}
+
+func (m *lifeHash) jump(k int) {
+	m.universe.Step(k)
+}
+
+func (m *lifeHash) Render(pixels []uint8) []uint8 {
+	if m.follow {
+		m.track(m.universe.Box())
+	}
+	return m.universe.Render(pixels, m.x, m.y, m.width, m.height, m.zoom)
+}
+
+func (m *lifeHash) Box() (x, y, width, height int64) {
+	return m.universe.Box()
+}
+
+func (m *lifeHash) Population() int {
+	return m.universe.Population()
+}
+
+func (m *lifeHash) Cells() []game_of_life.Cell {
+	return m.universe.Cells()
+}
+
+// lifeSparse is the engine of game_of_life.Sparse, tiles of cells on an unbounded plane.
+type lifeSparse struct {
+	*game_of_life.Sparse
+	viewport
+}
+
+func (m *lifeSparse) Init(opts Options) error {
+	if err := m.unboundedInit(opts); err != nil {
+		return err
+	}
+	var cells []game_of_life.Cell
+	switch {
+	case opts.Image != nil:
+		cells = game_of_life.LoadPattern(opts.Image.Luminance(), m.width, m.height, 0.5).Cells
+	case opts.Pattern != nil:
+		cells = opts.Pattern
+	case opts.Universe != nil:
+		cells = opts.Universe.Cells()
+	default:
+		// The same cells as the other engines for the same seed
+		cells = game_of_life.RandomBoard(opts.Width, opts.Height, float32(opts.Threshold)).Cells()
+	}
+	m.Sparse = game_of_life.NewSparse()
+	for _, c := range cells {
+		m.Set(int64(c.X), int64(c.Y), true)
+	}
+	return nil
+}
+
+func (m *lifeSparse) Render(pixels []uint8) []uint8 {
+	m.track(m.Box())
+	return m.Sparse.Render(pixels, m.x, m.y, m.width, m.height, m.zoom)
+}
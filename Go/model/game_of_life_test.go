@@ -0,0 +1,85 @@
+package model
+
+import (
+	"testing"
+
+	"main/game_of_life"
+)
+
+func TestTrack(t *testing.T) {
+	tests := []struct {
+		name         string
+		follow       bool
+		zoom         int
+		box          [4]int64 // x, y, width, height
+		wantX, wantY int64
+	}{
+		// the view of 64 x 48 cells from (0,0) keeps margins of 8 x 6 cells
+		{"inside", true, 0, [4]int64{10, 10, 3, 3}, 0, 0},
+		{"not followed", false, 0, [4]int64{100, 100, 3, 3}, 0, 0},
+		{"no cells", true, 0, [4]int64{100, 100, 0, 0}, 0, 0},
+		{"east margin", true, 0, [4]int64{54, 10, 3, 3}, 1, 0},
+		{"south east", true, 0, [4]int64{100, 100, 3, 3}, 47, 61},
+		{"north west", true, 0, [4]int64{-20, -20, 3, 3}, -28, -26},
+		{"too big", true, 0, [4]int64{-10, 0, 100, 20}, -10 + 50 - 32, -6},
+		// a pixel of 4 x 4 cells, the view has 256 x 192 cells and margins of 32 x 24
+		{"zoomed inside", true, 2, [4]int64{200, 150, 3, 3}, 0, 0},
+		{"zoomed south east", true, 2, [4]int64{300, 200, 3, 3}, 300 + 3 - 256 + 32, 200 + 3 - 192 + 24},
+	}
+	for _, test := range tests {
+		v := viewport{width: 64, height: 48, zoom: test.zoom, follow: test.follow}
+		v.track(test.box[0], test.box[1], test.box[2], test.box[3])
+		if v.x != test.wantX || v.y != test.wantY {
+			t.Errorf("%s: view at (%d,%d), want (%d,%d)", test.name, v.x, v.y, test.wantX, test.wantY)
+		}
+	}
+}
+
+// TestFollowGlider checks that the view of the unbounded engines follows a glider
+// far from the grid, and that it is drawn.
+func TestFollowGlider(t *testing.T) {
+	before := game_of_life.GetRule()
+	game_of_life.SetRule(game_of_life.Conway)
+	t.Cleanup(func() { game_of_life.SetRule(before) })
+	glider := []game_of_life.Cell{{X: 11, Y: 10}, {X: 12, Y: 11}, {X: 10, Y: 12}, {X: 11, Y: 12}, {X: 12, Y: 12}}
+	for _, engine := range []string{"hashlife", "sparse"} {
+		m := NewGameOfLife()
+		if err := m.SetEngine(engine); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Init(Options{Width: 64, Height: 48, Pattern: glider}); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.SetFollow(true); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.SetStep(2); err != nil {
+			t.Fatal(err)
+		}
+		var pixels []uint8
+		for i := 1; i <= 200; i++ {
+			m.Step()
+			pixels = m.Render(pixels)
+			v := m.Model.(unbounded).view()
+			x, y, width, height, _ := m.Box()
+			if x != 10+int64(i) || y != 10+int64(i) || width != 3 || height != 3 {
+				t.Fatalf("%s, step %d: box (%d,%d) %d x %d, want the glider at (%d,%d)", engine, i, x, y, width, height, 10+i, 10+i)
+			}
+			if x < v.x+8 || y < v.y+6 || x+width > v.x+64-8 || y+height > v.y+48-6 {
+				t.Fatalf("%s, step %d: glider at (%d,%d) out of the middle of the view at (%d,%d)", engine, i, x, y, v.x, v.y)
+			}
+			white := 0
+			for j := 0; j < len(pixels); j += 3 {
+				if pixels[j] == 255 {
+					white++
+				}
+			}
+			if white != 5 {
+				t.Fatalf("%s, step %d: %d cells drawn, want 5", engine, i, white)
+			}
+		}
+		if m.Generation() != 800 {
+			t.Errorf("%s: generation %d, want 800", engine, m.Generation())
+		}
+	}
+}
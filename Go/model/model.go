@@ -0,0 +1,126 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+
+	"main/boundary"
+	"main/game_of_life"
+	"main/image_utils"
+)
+
+// Simulations of the repository behind a single interface, so every model is run
+// by the same loop, shown by the same renderers and driven by the same commands.
+
+// Model is a simulation on a grid of cells.
+type Model interface {
+	// Init sets the initial grid from opts, it is called again by reset and reseed.
+	Init(opts Options) error
+	// Step advances the simulation by one step.
+	Step()
+	// Render writes the grid as 8 bits R,G,B pixels, pixels is reused when it has the right size.
+	Render(pixels []uint8) []uint8
+	// Size returns the width and height of the grid.
+	Size() (int, int)
+}
+
+// Options is the initial condition of a model: the image when it is given, then
+// the pattern, then the generator, then random cells.
+type Options struct {
+	Width, Height int
+	Seed          int64 // seed of the random source of the model
+	Threshold     float64
+	Image         *image_utils.Fields    // values in [0,1], it sets the size of the grid
+	Pattern       []game_of_life.Cell    // alive cells of game_of_life, placed on the grid
+	Universe      *game_of_life.Universe // the same as a quadtree, for the patterns too big to list their cells
+	Generator     string
+	Params        map[string]float64 // parameters of the generator
+}
+
+// Tunable is a model with numeric parameters, changed by -rules, the keys and the HTTP API.
+type Tunable interface {
+	// Params returns the current value of each parameter.
+	Params() map[string]float64
+	// SetParam changes a parameter, it fails for unknown names and invalid values.
+	SetParam(name string, value float64) error
+}
+
+// SetParams changes several parameters of t in the order of their names. When one
+// fails, the ones already changed are set back and its error is returned.
+func SetParams(t Tunable, params map[string]float64) error {
+	before := t.Params()
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		if err := t.SetParam(name, params[name]); err != nil {
+			for _, changed := range names[:i] {
+				t.SetParam(changed, before[changed])
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Ruled is a model whose rules are given as a rulestring, such as B3/S23 for game_of_life.
+type Ruled interface {
+	Rule() string
+	// SetRule parses and sets rule, used from the next step.
+	SetRule(rule string) error
+}
+
+// Bounded is a model whose grid has edges, what the cells see beyond them is set by
+// -boundary: the other side of the grid (the torus, by default), dead cells, the
+// grid mirrored or cells of a fixed value.
+type Bounded interface {
+	Boundary() boundary.Boundary
+	// SetBoundary changes the boundary, used from the next step.
+	SetBoundary(b boundary.Boundary) error
+}
+
+var models = map[string]func() Model{
+	"game_of_life": func() Model { return NewGameOfLife() },
+	"smoothlife":   func() Model { return &SmoothLife{} },
+	"smoothlife3d": func() Model { return NewSmoothLife3D() },
+}
+
+// thresholds are the thresholds of the random cells by model, 1 for the others.
+var thresholds = map[string]float64{
+	"game_of_life": 0.33, // a third of the cells alive
+}
+
+// DefaultThreshold returns the threshold of the random cells of the model called
+// name, used when none is given.
+func DefaultThreshold(name string) float64 {
+	if t, ok := thresholds[name]; ok {
+		return t
+	}
+	return 1
+}
+
+// Names returns the names of the models, sorted.
+func Names() []string {
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns the model called name.
+func New(name string) (Model, error) {
+	model, ok := models[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown model %q (available: %v)", name, Names())
+	}
+	return model(), nil
+}
+
+// isPowerOfTwo returns true if n is a power of two, the FFT of smoothlife3d needs it.
+func isPowerOfTwo(n int) bool {
+	return n > 0 && (n&(n-1)) == 0
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// fakeTunable has positive parameters.
+type fakeTunable map[string]float64
+
+func (f fakeTunable) Params() map[string]float64 {
+	params := make(map[string]float64, len(f))
+	for name, value := range f {
+		params[name] = value
+	}
+	return params
+}
+
+func (f fakeTunable) SetParam(name string, value float64) error {
+	if _, ok := f[name]; !ok {
+		return fmt.Errorf("unknown parameter %q", name)
+	}
+	if value <= 0 {
+		return fmt.Errorf("invalid value %g for %s", value, name)
+	}
+	f[name] = value
+	return nil
+}
+
+func TestSetParams(t *testing.T) {
+	before := fakeTunable{"b1": 0.278, "dt": 0.15, "ra": 11}
+	tests := []struct {
+		params map[string]float64
+		want   fakeTunable
+		fails  bool
+	}{
+		{map[string]float64{"dt": 0.1, "ra": 15}, fakeTunable{"b1": 0.278, "dt": 0.1, "ra": 15}, false},
+		{map[string]float64{"b1": 0.3, "dt": 0.1, "ra": -1}, before, true}, // ra is the last one changed
+		{map[string]float64{"b1": 0.3, "dt": 0.1, "x": 1}, before, true},
+		{nil, before, false},
+	}
+	for _, test := range tests {
+		tunable := fakeTunable(before.Params())
+		err := SetParams(tunable, test.params)
+		if (err != nil) != test.fails {
+			t.Errorf("SetParams(%v): error %v", test.params, err)
+		}
+		if !reflect.DeepEqual(tunable, test.want) {
+			t.Errorf("SetParams(%v): parameters %v, want %v", test.params, tunable, test.want)
+		}
+	}
+}
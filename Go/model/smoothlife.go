@@ -0,0 +1,57 @@
+package model
+
+import (
+	"fmt"
+
+	"main/boundary"
+	"main/smoothlife"
+)
+
+// SmoothLife is the first implementation of smoothlife, with one channel and the
+// kernels computed cell by cell. It is much slower than smoothlife3d, use small grids.
+type SmoothLife struct {
+	pixels []uint8
+	world  [][]float64
+}
+
+func (m *SmoothLife) Init(opts Options) error {
+	smoothlife.SetSeed(opts.Seed)
+	switch {
+	case opts.Image != nil:
+		m.pixels, m.world = smoothlife.LoadWorld(opts.Image.Luminance(), opts.Image.Width, opts.Image.Height)
+	case opts.Generator != "":
+		return fmt.Errorf("smoothlife has no generator, use a random grid or an image")
+	default:
+		m.pixels, m.world = smoothlife.GenerateRandomPixels(opts.Width, opts.Height, int(smoothlife.Params()["ra"]), float32(opts.Threshold))
+	}
+	return nil
+}
+
+func (m *SmoothLife) Step() {
+	m.pixels, m.world = smoothlife.UpdateGrid(m.pixels, m.world)
+}
+
+func (m *SmoothLife) Render(pixels []uint8) []uint8 {
+	return append(pixels[:0], m.pixels...)
+}
+
+func (m *SmoothLife) Size() (int, int) {
+	return smoothlife.Size()
+}
+
+func (m *SmoothLife) Boundary() boundary.Boundary {
+	return smoothlife.GetBoundary()
+}
+
+func (m *SmoothLife) SetBoundary(b boundary.Boundary) error {
+	smoothlife.SetBoundary(b)
+	return nil
+}
+
+func (m *SmoothLife) Params() map[string]float64 {
+	return smoothlife.Params()
+}
+
+func (m *SmoothLife) SetParam(name string, value float64) error {
+	return smoothlife.SetParam(name, value)
+}
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"fmt"
+
+	"main/boundary"
+	"main/smoothlife3d"
+)
+
+// SmoothLife3D is smoothlife on three channels with FFT convolutions. The grid
+// sides must be powers of two. Its float fields are also used directly through the
+// smoothlife3d package (exports, snapshots, brush), there is a single grid.
+type SmoothLife3D struct {
+	radius float64 // radius of the outer kernel, used by Init
+	pixels []uint8 // buffer of UpdateGrid
+}
+
+func NewSmoothLife3D() *SmoothLife3D {
+	return &SmoothLife3D{radius: smoothlife3d.Radius()}
+}
+
+func (m *SmoothLife3D) Init(opts Options) error {
+	width, height := opts.Width, opts.Height
+	if opts.Image != nil {
+		width, height = opts.Image.Width, opts.Image.Height
+	}
+	if !isPowerOfTwo(width) || !isPowerOfTwo(height) {
+		return fmt.Errorf("provided dimensions (%d x %d) are not powers of two", width, height)
+	}
+	smoothlife3d.SetSeed(opts.Seed)
+	switch {
+	case opts.Image != nil:
+		m.pixels = smoothlife3d.LoadImageFields(opts.Image.R, opts.Image.G, opts.Image.B, width, height, m.radius)
+	case opts.Generator != "":
+		pixels, err := smoothlife3d.GenerateInitPixels(opts.Generator, opts.Params, width, height, m.radius)
+		if err != nil {
+			return err
+		}
+		m.pixels = pixels
+	default:
+		m.pixels = smoothlife3d.GenerateRandomPixels(width, height, m.radius, float32(opts.Threshold))
+	}
+	return nil
+}
+
+func (m *SmoothLife3D) Step() {
+	m.pixels = smoothlife3d.UpdateGrid(m.pixels)
+}
+
+// Render draws the worlds, so the changes made by the brush or a snapshot are shown.
+func (m *SmoothLife3D) Render(pixels []uint8) []uint8 {
+	return smoothlife3d.RenderPixels(pixels)
+}
+
+func (m *SmoothLife3D) Size() (int, int) {
+	return smoothlife3d.Size()
+}
+
+func (m *SmoothLife3D) Boundary() boundary.Boundary {
+	return smoothlife3d.GetBoundary()
+}
+
+// SetBoundary changes the boundary, the FFT of the grids other than the torus are
+// twice as large in each direction and slower.
+func (m *SmoothLife3D) SetBoundary(b boundary.Boundary) error {
+	smoothlife3d.SetBoundary(b)
+	return nil
+}
+
+// Params returns the rules and the kernel radius ra.
+func (m *SmoothLife3D) Params() map[string]float64 {
+	params := smoothlife3d.GetRules().Values()
+	params["ra"] = m.radius
+	return params
+}
+
+func (m *SmoothLife3D) SetParam(name string, value float64) error {
+	if name == "ra" {
+		if value < 1 {
+			return fmt.Errorf("kernel radius %g is too small", value)
+		}
+		m.radius = value
+		if m.pixels != nil {
+			smoothlife3d.SetRadius(value) // the kernels are generated by Init otherwise
+		}
+		return nil
+	}
+	rules := smoothlife3d.GetRules()
+	if err := rules.Set(name, value); err != nil {
+		return err
+	}
+	smoothlife3d.SetRules(rules)
+	return nil
+}
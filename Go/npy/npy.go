@@ -0,0 +1,125 @@
+package npy
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// This package writes arrays in the NumPy .npy format (version 1.0) and bundles
+// several of them in .npz archives, so the simulation fields can be loaded with
+// numpy.load() in a notebook without losing the float precision.
+// Format reference : https://numpy.org/doc/stable/reference/generated/numpy.lib.format.html
+
+// Array is a named array to be stored in a .npz archive.
+// Data must be a []float64, []float32, []int64 or []uint8 slice.
+type Array struct {
+	Name  string
+	Data  interface{}
+	Shape []int
+}
+
+// descr returns the numpy dtype string of data and its length in elements.
+func descr(data interface{}) (string, int, error) {
+	switch d := data.(type) {
+	case []float64:
+		return "<f8", len(d), nil
+	case []float32:
+		return "<f4", len(d), nil
+	case []int64:
+		return "<i8", len(d), nil
+	case []uint8:
+		return "|u1", len(d), nil
+	}
+	return "", 0, fmt.Errorf("npy: unsupported data type %T", data)
+}
+
+// header builds the magic string, version and padded header dictionary.
+func header(dtype string, shape []int) []byte {
+	dims := make([]string, len(shape))
+	for i, s := range shape {
+		dims[i] = fmt.Sprint(s)
+	}
+	shapeStr := strings.Join(dims, ", ")
+	if len(shape) == 1 {
+		shapeStr += "," // (n,) is a tuple, (n) is not
+	}
+	dict := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%s), }", dtype, shapeStr)
+
+	// magic (6) + version (2) + header length (2) + dict + '\n' must be a multiple of 64
+	total := 10 + len(dict) + 1
+	padding := (64 - total%64) % 64
+	dict += strings.Repeat(" ", padding) + "\n"
+
+	buf := make([]byte, 10, 10+len(dict))
+	copy(buf, "\x93NUMPY")
+	buf[6], buf[7] = 1, 0
+	binary.LittleEndian.PutUint16(buf[8:], uint16(len(dict)))
+	return append(buf, dict...)
+}
+
+// Write writes data as a C-ordered .npy array with the given shape.
+func Write(w io.Writer, data interface{}, shape []int) error {
+	dtype, n, err := descr(data)
+	if err != nil {
+		return err
+	}
+	count := 1
+	for _, s := range shape {
+		count *= s
+	}
+	if count != n {
+		return fmt.Errorf("npy: shape %v does not match %d elements", shape, n)
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(header(dtype, shape)); err != nil {
+		return err
+	}
+	if err := binary.Write(bw, binary.LittleEndian, data); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// WriteFile writes a single array to a .npy file.
+func WriteFile(path string, data interface{}, shape []int) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Write(file, data, shape); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WriteNPZ writes several arrays to a .npz archive, one "<name>.npy" entry per array.
+// Entries are stored uncompressed, like numpy.savez does.
+func WriteNPZ(path string, arrays []Array) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+	for _, a := range arrays {
+		entry, err := zw.CreateHeader(&zip.FileHeader{Name: a.Name + ".npy", Method: zip.Store})
+		if err != nil {
+			return err
+		}
+		if err := Write(entry, a.Data, a.Shape); err != nil {
+			return fmt.Errorf("npy: writing %s: %w", a.Name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,178 @@
+package npy
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// numpyHeader is the header written by numpy.save for a float64 array of shape
+// (3,): the dictionary is padded with spaces so the data starts at 128 bytes.
+var numpyHeader = "\x93NUMPY\x01\x00v\x00{'descr': '<f8', 'fortran_order': False, 'shape': (3,), }" + strings.Repeat(" ", 60) + "\n"
+
+func TestHeader(t *testing.T) {
+	if got := string(header("<f8", []int{3})); got != numpyHeader {
+		t.Errorf("header %q, want the one of numpy %q", got, numpyHeader)
+	}
+	tests := []struct {
+		dtype string
+		shape []int
+		dict  string
+	}{
+		{"<f8", []int{2, 3}, "{'descr': '<f8', 'fortran_order': False, 'shape': (2, 3), }"},
+		{"|u1", []int{480, 640, 3}, "{'descr': '|u1', 'fortran_order': False, 'shape': (480, 640, 3), }"},
+		{"<i8", []int{}, "{'descr': '<i8', 'fortran_order': False, 'shape': (), }"},
+		// a dictionary longer than 64 bytes, the header takes 192 bytes
+		{"<f4", []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30}, ""},
+	}
+	for _, test := range tests {
+		h := header(test.dtype, test.shape)
+		if len(h)%64 != 0 {
+			t.Errorf("%v: header of %d bytes, not aligned on 64 bytes", test.shape, len(h))
+		}
+		if n := int(binary.LittleEndian.Uint16(h[8:])); n != len(h)-10 {
+			t.Errorf("%v: header length %d, want %d", test.shape, n, len(h)-10)
+		}
+		if !strings.HasSuffix(string(h), "\n") {
+			t.Errorf("%v: header %q does not end with a newline", test.shape, h)
+		}
+		if test.dict != "" && !strings.HasPrefix(string(h[10:]), test.dict+" ") {
+			t.Errorf("%v: dictionary %q, want %q", test.shape, h[10:], test.dict)
+		}
+	}
+}
+
+// TestReadNumPy reads arrays as written by numpy, in the versions 1.0 and 2.0 of
+// the format, and rejects the ones it can not read.
+func TestReadNumPy(t *testing.T) {
+	data := new(bytes.Buffer)
+	data.WriteString(numpyHeader)
+	binary.Write(data, binary.LittleEndian, []float64{0.5, -1, 1e300})
+	a, err := Read(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a.Data, []float64{0.5, -1, 1e300}) || !reflect.DeepEqual(a.Shape, []int{3}) {
+		t.Errorf("array %v of shape %v", a.Data, a.Shape)
+	}
+
+	// version 2.0 has a header length of 4 bytes, numpy writes it for the big headers
+	dict := "{'descr': '<u1', 'fortran_order': False, 'shape': (2, 2), }"
+	dict += strings.Repeat(" ", 64-(12+len(dict)+1)%64) + "\n"
+	v2 := []byte("\x93NUMPY\x02\x00")
+	v2 = binary.LittleEndian.AppendUint32(v2, uint32(len(dict)))
+	v2 = append(append(v2, dict...), 1, 2, 3, 4)
+	if a, err := Read(bytes.NewReader(v2)); err != nil {
+		t.Errorf("version 2.0: %v", err)
+	} else if !reflect.DeepEqual(a.Data, []uint8{1, 2, 3, 4}) || !reflect.DeepEqual(a.Shape, []int{2, 2}) {
+		t.Errorf("version 2.0: array %v of shape %v", a.Data, a.Shape)
+	}
+
+	replace := func(old, new string) string {
+		return strings.Replace(numpyHeader, old, new, 1) + strings.Repeat("\x00", 24)
+	}
+	for name, file := range map[string]string{
+		"fortran order": replace("False", "True "),
+		"big endian":    replace("<f8", ">f8"),
+		"complex":       replace("<f8", "<c8"),
+		"version 4":     replace("\x01\x00v", "\x04\x00v"),
+		"bad magic":     replace("NUMPY", "NUMPX"),
+		"no shape":      replace("'shape'", "'shaaa'"),
+		"bad shape":     replace("(3,)", "(x,)"),
+		"truncated":     numpyHeader + strings.Repeat("\x00", 23),
+		"empty":         "",
+	} {
+		if _, err := Read(strings.NewReader(file)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	arrays := []Array{
+		{"world1", []float64{0, 0.25, 1, 1.0 / 3, -2, 5e-324}, []int{2, 3}},
+		{"world2", []float32{0.5, -1, 3.25}, []int{3}},
+		{"step", []int64{1 << 40}, []int{}},
+		{"pixels", []uint8{0, 1, 127, 255, 3, 4, 5, 6}, []int{2, 2, 2}},
+	}
+	for _, a := range arrays {
+		var buf bytes.Buffer
+		if err := Write(&buf, a.Data, a.Shape); err != nil {
+			t.Fatalf("%s: %v", a.Name, err)
+		}
+		got, err := Read(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", a.Name, err)
+		}
+		if !reflect.DeepEqual(got.Data, a.Data) || !reflect.DeepEqual(got.Shape, a.Shape) {
+			t.Errorf("%s: read %v of shape %v, want %v of shape %v", a.Name, got.Data, got.Shape, a.Data, a.Shape)
+		}
+	}
+	if err := Write(new(bytes.Buffer), []float64{1, 2, 3}, []int{2, 2}); err == nil {
+		t.Errorf("shape (2, 2) of 3 values: no error")
+	}
+	if err := Write(new(bytes.Buffer), []int32{1}, []int{1}); err == nil {
+		t.Errorf("int32: no error")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "run.npz")
+	if err := WriteNPZ(path, arrays); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadNPZ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, arrays) {
+		t.Errorf("ReadNPZ = %v, want %v", got, arrays)
+	}
+	single := filepath.Join(dir, "world1.npy")
+	if err := WriteFile(single, arrays[0].Data, arrays[0].Shape); err != nil {
+		t.Fatal(err)
+	}
+	if a, err := ReadFile(single); err != nil || !reflect.DeepEqual(a.Float64s(), arrays[0].Data) {
+		t.Errorf("ReadFile = %v, %v", a.Data, err)
+	}
+}
+
+// TestReadCompressedNPZ reads an archive compressed like numpy.savez_compressed does.
+func TestReadCompressedNPZ(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "compressed.npz")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(file)
+	entry, err := zw.CreateHeader(&zip.FileHeader{Name: "values.npy", Method: zip.Deflate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make([]float32, 1000)
+	for i := range values {
+		values[i] = float32(i % 7)
+	}
+	if err := Write(entry, values, []int{10, 100}); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	arrays, err := ReadNPZ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(arrays) != 1 || arrays[0].Name != "values" || !reflect.DeepEqual(arrays[0].Data, values) {
+		t.Errorf("ReadNPZ = %v", arrays)
+	}
+	if floats := arrays[0].Float64s(); len(floats) != 1000 || floats[13] != 6 {
+		t.Errorf("Float64s: %d values, the 14th %g", len(floats), floats[13])
+	}
+}
@@ -0,0 +1,158 @@
+package npy
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Reading of the arrays written by this package or by numpy.save / numpy.savez,
+// for the same dtypes (little endian, C order).
+
+var (
+	descrPattern   = regexp.MustCompile(`'descr':\s*'([^']*)'`)
+	fortranPattern = regexp.MustCompile(`'fortran_order':\s*(True|False)`)
+	shapePattern   = regexp.MustCompile(`'shape':\s*\(([^)]*)\)`)
+)
+
+// Read reads a .npy array, its Name is empty.
+func Read(r io.Reader) (Array, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, 8)
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return Array{}, err
+	}
+	if string(magic[:6]) != "\x93NUMPY" {
+		return Array{}, fmt.Errorf("npy: not a .npy file")
+	}
+	var headerLen int
+	switch magic[6] {
+	case 1:
+		var n uint16
+		if err := binary.Read(br, binary.LittleEndian, &n); err != nil {
+			return Array{}, err
+		}
+		headerLen = int(n)
+	case 2, 3:
+		var n uint32
+		if err := binary.Read(br, binary.LittleEndian, &n); err != nil {
+			return Array{}, err
+		}
+		headerLen = int(n)
+	default:
+		return Array{}, fmt.Errorf("npy: unsupported version %d.%d", magic[6], magic[7])
+	}
+	dict := make([]byte, headerLen)
+	if _, err := io.ReadFull(br, dict); err != nil {
+		return Array{}, err
+	}
+
+	descr := descrPattern.FindSubmatch(dict)
+	fortran := fortranPattern.FindSubmatch(dict)
+	shapeMatch := shapePattern.FindSubmatch(dict)
+	if descr == nil || fortran == nil || shapeMatch == nil {
+		return Array{}, fmt.Errorf("npy: invalid header %q", dict)
+	}
+	if string(fortran[1]) == "True" {
+		return Array{}, fmt.Errorf("npy: fortran ordered arrays are not supported")
+	}
+	shape := []int{}
+	count := 1
+	for _, dim := range strings.Split(string(shapeMatch[1]), ",") {
+		if dim = strings.TrimSpace(dim); dim == "" {
+			continue
+		}
+		n, err := strconv.Atoi(dim)
+		if err != nil {
+			return Array{}, fmt.Errorf("npy: invalid shape %q", shapeMatch[1])
+		}
+		shape = append(shape, n)
+		count *= n
+	}
+
+	var data interface{}
+	switch string(descr[1]) {
+	case "<f8":
+		data = make([]float64, count)
+	case "<f4":
+		data = make([]float32, count)
+	case "<i8":
+		data = make([]int64, count)
+	case "|u1", "<u1":
+		data = make([]uint8, count)
+	default:
+		return Array{}, fmt.Errorf("npy: unsupported dtype %s", descr[1])
+	}
+	if err := binary.Read(br, binary.LittleEndian, data); err != nil {
+		return Array{}, err
+	}
+	return Array{Data: data, Shape: shape}, nil
+}
+
+// ReadFile reads a single array from a .npy file.
+func ReadFile(path string) (Array, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Array{}, err
+	}
+	defer file.Close()
+	return Read(file)
+}
+
+// ReadNPZ reads the arrays of a .npz archive, compressed or not, in the order of the archive.
+func ReadNPZ(path string) ([]Array, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	arrays := make([]Array, 0, len(zr.File))
+	for _, f := range zr.File {
+		entry, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		a, err := Read(entry)
+		entry.Close()
+		if err != nil {
+			return nil, fmt.Errorf("npy: reading %s: %w", f.Name, err)
+		}
+		a.Name = strings.TrimSuffix(f.Name, ".npy")
+		arrays = append(arrays, a)
+	}
+	return arrays, nil
+}
+
+// Float64s returns the values of the array converted to float64.
+func (a Array) Float64s() []float64 {
+	switch d := a.Data.(type) {
+	case []float64:
+		return d
+	case []float32:
+		values := make([]float64, len(d))
+		for i, v := range d {
+			values[i] = float64(v)
+		}
+		return values
+	case []int64:
+		values := make([]float64, len(d))
+		for i, v := range d {
+			values[i] = float64(v)
+		}
+		return values
+	case []uint8:
+		values := make([]float64, len(d))
+		for i, v := range d {
+			values[i] = float64(v)
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package opengl_utils
+
+import (
+	"main/commands"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// Keys of the window. The GLFW callback only translates keys to commands with
+// commands.KeyCommand and pushes them in the queue given to BindKeys. The keys of
+// the overlay, of the display and of the view (see hud.go, display.go and view.go)
+// are handled before and never reach the simulation.
+
+var keyQueue *commands.Queue // nil until BindKeys is called
+
+func BindKeys(queue *commands.Queue) {
+	// Sends the commands of the keys pressed in the window to queue
+	keyQueue = queue
+}
+
+func handleKey(key glfw.Key, action glfw.Action, mods glfw.ModifierKey) {
+	if handleHUDKey(key, action, mods) || handleDisplayKey(key, action, mods) || (mods == 0 && handleViewKey(key, action)) {
+		return
+	}
+	if keyQueue == nil || action == glfw.Release {
+		return
+	}
+	// the key codes and modifiers of commands are the ones of GLFW
+	if c, ok := commands.KeyCommand(commands.Key(key), commands.Mods(mods), action == glfw.Repeat); ok {
+		keyQueue.Push(c)
+	}
+}
@@ -0,0 +1,196 @@
+package opengl_utils
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// Display settings applied by the fragment shader : contrast, gamma and a colormap
+// read from a 1D lookup texture. They only change uniforms, so they apply at once
+// without touching the simulation. C cycles through the colormaps, V through the
+// channel that is mapped, G / shift G change the gamma and K / shift K the contrast.
+
+const lutSize = 256
+
+// Colormaps are given by evenly spaced control points, interpolated in the lookup table.
+var Colormaps = map[string][][3]float32{
+	"gray": {{0, 0, 0}, {1, 1, 1}},
+	"viridis": {
+		{0.267, 0.005, 0.329}, {0.283, 0.141, 0.458}, {0.254, 0.265, 0.530}, {0.207, 0.372, 0.553},
+		{0.164, 0.471, 0.558}, {0.128, 0.567, 0.551}, {0.135, 0.659, 0.518}, {0.267, 0.749, 0.441},
+		{0.478, 0.821, 0.318}, {0.741, 0.873, 0.150}, {0.993, 0.906, 0.144},
+	},
+	"magma": {
+		{0.001, 0.000, 0.014}, {0.079, 0.054, 0.212}, {0.232, 0.060, 0.437}, {0.390, 0.100, 0.502},
+		{0.550, 0.161, 0.506}, {0.716, 0.215, 0.475}, {0.868, 0.288, 0.409}, {0.967, 0.439, 0.360},
+		{0.994, 0.624, 0.427}, {0.995, 0.812, 0.573}, {0.987, 0.991, 0.750},
+	},
+	"inferno": {
+		{0.001, 0.000, 0.014}, {0.087, 0.045, 0.225}, {0.258, 0.039, 0.406}, {0.416, 0.090, 0.433},
+		{0.578, 0.148, 0.404}, {0.735, 0.216, 0.330}, {0.865, 0.317, 0.226}, {0.954, 0.469, 0.099},
+		{0.988, 0.645, 0.040}, {0.964, 0.843, 0.273}, {0.988, 0.998, 0.645},
+	},
+	"hot":  {{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {1, 1, 1}},
+	"cool": {{0, 1, 1}, {1, 0, 1}},
+	"twilight": {
+		{0.886, 0.851, 0.888}, {0.537, 0.635, 0.776}, {0.370, 0.309, 0.685}, {0.284, 0.103, 0.379},
+		{0.469, 0.107, 0.268}, {0.737, 0.388, 0.366}, {0.886, 0.851, 0.888},
+	},
+}
+
+var (
+	colormapNames = ColormapNames()
+	colormapIndex = -1 // index in colormapNames, -1 shows the RGB channels
+	mapChannel    = 3  // 0, 1, 2 or 3 for the luminance
+	displayGamma  = 1.0
+	contrast      = 1.0
+	colormapTex   uint32
+
+	useColormapLoc, channelLoc, gammaLoc, contrastLoc int32
+)
+
+// ColormapNames returns the names of the colormaps, sorted.
+func ColormapNames() []string {
+	names := make([]string, 0, len(Colormaps))
+	for name := range Colormaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ColormapLUT interpolates the control points of a colormap in a lookup table of n RGB colors.
+func ColormapLUT(points [][3]float32, n int) []float32 {
+	lut := make([]float32, n*3)
+	for i := 0; i < n; i++ {
+		pos := float64(i) / float64(n-1) * float64(len(points)-1)
+		j := int(math.Min(math.Floor(pos), float64(len(points)-2)))
+		t := float32(pos - float64(j))
+		if len(points) == 1 {
+			j, t = 0, 0
+		}
+		for c := 0; c < 3; c++ {
+			next := points[min(j+1, len(points)-1)][c]
+			lut[i*3+c] = points[j][c]*(1-t) + next*t
+		}
+	}
+	return lut
+}
+
+// SetDisplay chooses the colormap ("" or "rgb" shows the RGB channels), the channel it maps
+// (0, 1, 2 or 3 for the luminance), the gamma and the contrast. It can be called before InitWindow.
+func SetDisplay(colormap string, channel int, gamma, contrastValue float64) error {
+	index := -1
+	if colormap != "" && colormap != "rgb" {
+		index = sort.SearchStrings(colormapNames, colormap)
+		if index == len(colormapNames) || colormapNames[index] != colormap {
+			return fmt.Errorf("unknown colormap %q (available: rgb %v)", colormap, colormapNames)
+		}
+	}
+	if channel < 0 || channel > 3 {
+		return fmt.Errorf("channel %d out of range (0, 1, 2 or 3 for the luminance)", channel)
+	}
+	if gamma <= 0 || contrastValue <= 0 {
+		return fmt.Errorf("gamma and contrast must be positive")
+	}
+	colormapIndex, mapChannel, displayGamma, contrast = index, channel, gamma, contrastValue
+	if colormapTex != 0 {
+		uploadColormap()
+	}
+	return nil
+}
+
+func initDisplay() {
+	// Creates the lookup texture of the colormap and gets the uniforms of the display
+	useColormapLoc = gl.GetUniformLocation(program, gl.Str("useColormap\x00"))
+	channelLoc = gl.GetUniformLocation(program, gl.Str("channel\x00"))
+	gammaLoc = gl.GetUniformLocation(program, gl.Str("gamma\x00"))
+	contrastLoc = gl.GetUniformLocation(program, gl.Str("contrast\x00"))
+
+	gl.GenTextures(1, &colormapTex)
+	gl.BindTexture(gl.TEXTURE_1D, colormapTex)
+	gl.TexParameteri(gl.TEXTURE_1D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_1D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_1D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexImage1D(gl.TEXTURE_1D, 0, gl.RGB32F, lutSize, 0, gl.RGB, gl.FLOAT, nil)
+	uploadColormap()
+
+	gl.UseProgram(program)
+	gl.Uniform1i(gl.GetUniformLocation(program, gl.Str("screenTexture\x00")), 0)
+	gl.Uniform1i(gl.GetUniformLocation(program, gl.Str("colormap\x00")), 1)
+}
+
+func uploadColormap() {
+	if colormapIndex < 0 {
+		return
+	}
+	lut := ColormapLUT(Colormaps[colormapNames[colormapIndex]], lutSize)
+	gl.BindTexture(gl.TEXTURE_1D, colormapTex)
+	gl.TexSubImage1D(gl.TEXTURE_1D, 0, 0, lutSize, gl.RGB, gl.FLOAT, gl.Ptr(lut))
+}
+
+func setDisplayUniforms() {
+	// Sends the display settings to the fragment shader, called before each draw
+	useColormap := int32(0)
+	if colormapIndex >= 0 {
+		useColormap = 1
+	}
+	gl.Uniform1i(useColormapLoc, useColormap)
+	gl.Uniform1i(channelLoc, int32(mapChannel))
+	gl.Uniform1f(gammaLoc, float32(displayGamma))
+	gl.Uniform1f(contrastLoc, float32(contrast))
+
+	gl.ActiveTexture(gl.TEXTURE1)
+	gl.BindTexture(gl.TEXTURE_1D, colormapTex)
+}
+
+func handleDisplayKey(key glfw.Key, action glfw.Action, mods glfw.ModifierKey) bool {
+	// Applies the keys of the display, returns false if key is not one of them
+	if action == glfw.Release || mods&^glfw.ModShift != 0 {
+		return false
+	}
+	shift := mods&glfw.ModShift != 0
+	switch key {
+	case glfw.KeyC:
+		if action != glfw.Press {
+			return true
+		}
+		colormapIndex++
+		if colormapIndex == len(colormapNames) {
+			colormapIndex = -1
+		}
+		uploadColormap()
+		if colormapIndex < 0 {
+			log.Printf("Colormap: rgb")
+		} else {
+			log.Printf("Colormap: %s", colormapNames[colormapIndex])
+		}
+	case glfw.KeyV:
+		if action == glfw.Press {
+			mapChannel = (mapChannel + 1) % 4
+			log.Printf("Colormap channel: %s", []string{"R", "G", "B", "luminance"}[mapChannel])
+		}
+	case glfw.KeyG:
+		if shift {
+			displayGamma /= 1.1
+		} else {
+			displayGamma *= 1.1
+		}
+		log.Printf("Gamma: %.2f", displayGamma)
+	case glfw.KeyK:
+		if shift {
+			contrast /= 1.1
+		} else {
+			contrast *= 1.1
+		}
+		log.Printf("Contrast: %.2f", contrast)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,129 @@
+package opengl_utils
+
+import (
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// Overlay in the top left corner of the window showing lines of text given by the
+// simulation with SetHUD. The text is rendered with the bitmap font into a texture
+// that is only uploaded again when the text changes. H shows or hides it.
+
+const (
+	hudVertexShaderSource = `
+		#version 410
+		layout(location = 0) in vec3 position;
+		layout(location = 1) in vec2 texCoord;
+
+		out vec2 fragTexCoord;
+
+		uniform vec4 rect; // x0, y0, x1, y1 of the overlay in normalized device coordinates
+
+		void main() {
+			fragTexCoord = vec2(texCoord.x, 1.0 - texCoord.y); // the text buffer starts at the top
+			gl_Position = vec4(mix(rect.xy, rect.zw, texCoord), 0.0, 1.0);
+		}
+	` + "\x00"
+
+	hudFragmentShaderSource = `
+		#version 410
+		in vec2 fragTexCoord;
+		out vec4 fragColor;
+
+		uniform sampler2D hudTexture;
+
+		void main() {
+			fragColor = texture(hudTexture, fragTexCoord);
+		}
+	` + "\x00"
+
+	hudScale  = 2 // size of a font pixel on screen
+	hudMargin = 8 // distance to the corner of the window, in pixels
+)
+
+var (
+	hudVisible = true
+	hudLines   []string
+	hudText    string // text of the texture, to know when to upload it again
+	hudWidth   int
+	hudHeight  int
+	hudProgram uint32
+	hudTexture uint32
+	hudRectLoc int32
+)
+
+func SetHUD(lines []string) {
+	// Changes the text of the overlay, it is drawn with the next frame
+	hudLines = lines
+}
+
+func SetHUDVisible(visible bool) {
+	hudVisible = visible
+}
+
+func initHUD() {
+	// Compiles the overlay shaders and creates its texture
+	vertexShader, err := compileShader(hudVertexShaderSource, gl.VERTEX_SHADER)
+	if err != nil {
+		panic(err)
+	}
+	fragmentShader, err := compileShader(hudFragmentShaderSource, gl.FRAGMENT_SHADER)
+	if err != nil {
+		panic(err)
+	}
+	hudProgram = gl.CreateProgram()
+	gl.AttachShader(hudProgram, vertexShader)
+	gl.AttachShader(hudProgram, fragmentShader)
+	gl.LinkProgram(hudProgram)
+	hudRectLoc = gl.GetUniformLocation(hudProgram, gl.Str("rect\x00"))
+
+	gl.GenTextures(1, &hudTexture)
+	gl.BindTexture(gl.TEXTURE_2D, hudTexture)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+}
+
+func drawHUD() {
+	// Draws the overlay over the grid, called at the end of draw
+	if !hudVisible || len(hudLines) == 0 {
+		return
+	}
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, hudTexture)
+	if text := strings.Join(hudLines, "\n"); text != hudText {
+		var buffer []uint8
+		buffer, hudWidth, hudHeight = RenderText(hudLines, hudScale)
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, int32(hudWidth), int32(hudHeight), 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(buffer))
+		hudText = text
+	}
+
+	windowWidth, windowHeight := window.GetSize()
+	x0 := -1 + 2*float32(hudMargin)/float32(windowWidth)
+	y1 := 1 - 2*float32(hudMargin)/float32(windowHeight)
+	x1 := x0 + 2*float32(hudWidth)/float32(windowWidth)
+	y0 := y1 - 2*float32(hudHeight)/float32(windowHeight)
+
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	gl.UseProgram(hudProgram)
+	gl.Uniform4f(hudRectLoc, x0, y0, x1, y1)
+	gl.BindVertexArray(vao)
+	gl.DrawArrays(gl.TRIANGLES, 0, 6)
+	gl.Disable(gl.BLEND)
+}
+
+func handleHUDKey(key glfw.Key, action glfw.Action, mods glfw.ModifierKey) bool {
+	// H shows or hides the overlay, returns false for the other keys
+	if key != glfw.KeyH || mods != 0 {
+		return false
+	}
+	if action == glfw.Press {
+		hudVisible = !hudVisible
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package opengl_utils
+
+import (
+	"main/commands"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// Painting with the mouse : the left button paints and the right button erases.
+// The callbacks send the stroke as commands in grid coordinates, the simulation
+// applies the brush itself.
+
+var (
+	mouseQueue  *commands.Queue       // nil until BindMouse is called
+	paintButton glfw.MouseButton = -1 // button of the current stroke, -1 when not painting
+)
+
+func paintCommand(w *glfw.Window, xpos, ypos float64) commands.Command {
+	windowWidth, windowHeight := w.GetSize()
+	x, y := view.WindowToGrid(xpos, ypos, windowWidth, windowHeight, width, height)
+	return commands.Command{Kind: commands.Paint, X: x, Y: y}
+}
+
+func BindMouse(queue *commands.Queue) {
+	// Sends the strokes painted in the window to queue
+	mouseQueue = queue
+}
+
+func handleMouseButton(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+	xpos, ypos := w.GetCursorPos()
+	if handlePan(button, action, xpos, ypos) || mouseQueue == nil {
+		return
+	}
+	if button != glfw.MouseButtonLeft && button != glfw.MouseButtonRight {
+		return
+	}
+	if action == glfw.Press && paintButton == -1 {
+		paintButton = button
+		mouseQueue.Push(commands.Command{Kind: commands.StrokeBegin, Erase: button == glfw.MouseButtonRight})
+		mouseQueue.Push(paintCommand(w, xpos, ypos))
+	} else if action == glfw.Release && button == paintButton {
+		paintButton = -1
+		mouseQueue.Push(commands.Command{Kind: commands.StrokeEnd})
+	}
+}
+
+func handleCursorPos(w *glfw.Window, xpos, ypos float64) {
+	handlePanMove(xpos, ypos)
+	if paintButton != -1 {
+		mouseQueue.Push(paintCommand(w, xpos, ypos))
+	}
+}
@@ -2,6 +2,7 @@ package opengl_utils
 
 import (
   "fmt"
+  "math"
   "strings"
 
   "github.com/go-gl/gl/v4.1-core/gl"
@@ -16,8 +17,13 @@ const (
 
 		out vec2 fragTexCoord; // Pass texture coordinates to fragment shader
 
+		uniform vec2 viewCenter; // Texture coordinates shown at the center of the window
+		uniform vec2 viewSize;   // Size of the window in texture coordinates
+
 		void main() {
-			fragTexCoord = texCoord;
+			// Outside of [0,1] the texture repeats, so the torus wraps around on screen.
+			// The first line of the grid is at the top, as in the images and the terminal
+			fragTexCoord = viewCenter + vec2(texCoord.x - 0.5, 0.5 - texCoord.y) * viewSize;
 			gl_Position = vec4(position, 1.0);
 		}
 	` + "\x00"
@@ -28,9 +34,22 @@ const (
 		out vec4 fragColor; // Output color
 
 		uniform sampler2D screenTexture; // Texture containing pixel data
+		uniform sampler1D colormap;      // Lookup table of the colormap
+		uniform int useColormap;         // 0 shows the RGB texture, 1 maps a channel through the colormap
+		uniform int channel;             // Channel mapped by the colormap : 0, 1, 2 or 3 for the luminance
+		uniform float gamma;
+		uniform float contrast;
 
 		void main() {
-			fragColor = texture(screenTexture, fragTexCoord);
+			vec3 color = texture(screenTexture, fragTexCoord).rgb;
+			color = clamp((color - 0.5) * contrast + 0.5, 0.0, 1.0);
+			color = pow(color, vec3(1.0 / gamma));
+
+			if (useColormap == 1) {
+				float value = channel == 3 ? dot(color, vec3(0.2126, 0.7152, 0.0722)) : color[channel];
+				color = texture(colormap, value).rgb;
+			}
+			fragColor = vec4(color, 1.0);
 		}
 	` + "\x00"
 )
@@ -47,23 +66,34 @@ var (
 		 1.0,  1.0, 0.0,  1.0, 1.0,
 	}
 
-  width = 1000
+  width = 1000 // size of the grid, and of the texture
   height = 1000
+  windowWidth = 1000 // size of the window, it can be resized
+  windowHeight = 1000
   
   window *glfw.Window
   vao uint32
   program uint32
   texture uint32
+  floatTexture uint32 // created by the first UpdateFloatTexture
+  shown uint32 // texture drawn by Draw, the last one updated
 
 )
 
-func InitWindow(window_width int, window_height int) *glfw.Window {
-  // Init an OpenGL window with specific size
-  width = window_width
-  height = window_height
+func InitWindow(grid_width int, grid_height int) *glfw.Window {
+  // Init an OpenGL window showing a grid of a specific size. The window is at most
+  // maxWindowSize pixels wide or high, then it can be resized, zoomed and panned.
+  width = grid_width
+  height = grid_height
+  scale := math.Min(1, math.Min(maxWindowSize/float64(width), maxWindowSize/float64(height)))
+  windowWidth = int(float64(width) * scale)
+  windowHeight = int(float64(height) * scale)
   window = initGlfw()
 
 	program = initOpenGL()
+	initView()
+	initDisplay()
+	initHUD()
 
 	// Create a texture
 	texture = createTexture()
@@ -78,13 +108,41 @@ func UpdateTexture(pixels []uint8) {
   // Edit the texture with pixels as argument
 	gl.BindTexture(gl.TEXTURE_2D, texture)
 	gl.TexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, int32(width), int32(height), gl.RGB, gl.UNSIGNED_BYTE, gl.Ptr(pixels))
+  shown = texture
   draw(window, program, vao, texture)
 }
 
+func UpdateFloatTexture(values []float32, channels int) {
+  // Edit the float texture, values are in [0,1] with 1 (gray) or 3 (RGB) channels per cell.
+  // The simulation doesn't need to convert its fields to bytes, and the colormap keeps their precision.
+  if floatTexture == 0 {
+    floatTexture = createFloatTexture(channels)
+  }
+  format := uint32(gl.RGB)
+  if channels == 1 {
+    format = gl.RED
+  }
+	gl.BindTexture(gl.TEXTURE_2D, floatTexture)
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, int32(width), int32(height), format, gl.FLOAT, gl.Ptr(values))
+  shown = floatTexture
+  draw(window, program, vao, floatTexture)
+}
+
+func Draw() {
+  // Draw the last updated texture again, so the view and the overlay follow the
+  // input while the simulation has no new frame
+  if shown == 0 {
+    shown = texture
+  }
+  draw(window, program, vao, shown)
+}
+
 func draw(window *glfw.Window, program, vao, texture uint32) {
   // Draw the texture + shader to the screen
 	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
 	gl.UseProgram(program)
+	setViewUniforms()
+	setDisplayUniforms()
 
 	// Bind the texture
 	gl.ActiveTexture(gl.TEXTURE0)
@@ -93,27 +151,34 @@ func draw(window *glfw.Window, program, vao, texture uint32) {
 	// Render the quad
 	gl.BindVertexArray(vao)
 	gl.DrawArrays(gl.TRIANGLES, 0, 6)
+	drawHUD()
 
-	glfw.PollEvents()
 	window.SwapBuffers()
 }
 
+func PollEvents() bool {
+  // Handles the pending events of the window, returns false once it is closed
+	glfw.PollEvents()
+	return !window.ShouldClose()
+}
+
 func initGlfw() *glfw.Window {
   // create a new GlFW instance to handle OpenGL
 	if err := glfw.Init(); err != nil {
 		panic(err)
 	}
-	glfw.WindowHint(glfw.Resizable, glfw.False)
+	glfw.WindowHint(glfw.Resizable, glfw.True)
 	glfw.WindowHint(glfw.ContextVersionMajor, 4)
 	glfw.WindowHint(glfw.ContextVersionMinor, 1)
 	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
 	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
 
-	window, err := glfw.CreateWindow(width, height, "smoothlife", nil, nil)
+	window, err := glfw.CreateWindow(windowWidth, windowHeight, "smoothlife", nil, nil)
 	if err != nil {
 		panic(err)
 	}
 	window.MakeContextCurrent()
+	glfw.SwapInterval(1) // SwapBuffers waits for the screen, so drawing runs at display rate
 
 	return window
 }
@@ -149,8 +214,8 @@ func createTexture() uint32 {
 	gl.BindTexture(gl.TEXTURE_2D, texture)
 
 	// Set texture parameters
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.REPEAT)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.REPEAT)
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
 
@@ -160,6 +225,32 @@ func createTexture() uint32 {
 	return texture
 }
 
+func createFloatTexture(channels int) uint32 {
+  // Same as createTexture with 32 bits float values, R32F textures are shown in gray
+	var texture uint32
+	gl.GenTextures(1, &texture)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.REPEAT)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.REPEAT)
+	filter := int32(gl.NEAREST)
+	if linearFilter {
+		filter = gl.LINEAR
+	}
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, filter)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, filter)
+
+	if channels == 1 {
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_SWIZZLE_G, gl.RED)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_SWIZZLE_B, gl.RED)
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.R32F, int32(width), int32(height), 0, gl.RED, gl.FLOAT, nil)
+	} else {
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGB32F, int32(width), int32(height), 0, gl.RGB, gl.FLOAT, nil)
+	}
+
+	return texture
+}
+
 func makeVao(vertices []float32) uint32 {
 	var vbo uint32
 	gl.GenBuffers(1, &vbo)
@@ -0,0 +1,172 @@
+package opengl_utils
+
+import (
+	"math"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// Viewport of the window on the grid : the scroll wheel zooms around the cursor,
+// dragging with the middle button (or the arrow keys) pans, F toggles between
+// nearest and linear filtering and Home resets the view. Since the texture repeats,
+// panning past a border shows the other side of the torus.
+
+const maxWindowSize = 1024 // size of the window when it opens, for big grids
+
+// View is the part of the grid shown in the window.
+type View struct {
+	CenterX, CenterY float64 // texture coordinates at the center of the window, in [0,1[
+	Zoom             float64 // 1 fits the whole grid in the window
+}
+
+var (
+	view          = View{CenterX: 0.5, CenterY: 0.5, Zoom: 1}
+	linearFilter  = true
+	panning       bool
+	lastX, lastY  float64 // cursor position when panning
+	viewCenterLoc int32
+	viewSizeLoc   int32
+)
+
+// Size returns the size of a window of windowWidth x windowHeight pixels in texture
+// coordinates. Cells stay square whatever the shape of the window.
+func (v View) Size(windowWidth, windowHeight, gridWidth, gridHeight int) (float64, float64) {
+	cell := v.Zoom * math.Min(float64(windowWidth)/float64(gridWidth), float64(windowHeight)/float64(gridHeight))
+	return float64(windowWidth) / (float64(gridWidth) * cell), float64(windowHeight) / (float64(gridHeight) * cell)
+}
+
+// TexCoord converts a cursor position in the window to texture coordinates (not wrapped).
+// The first line of the texture is drawn at the top of the window, like the other renderers.
+func (v View) TexCoord(xpos, ypos float64, windowWidth, windowHeight, gridWidth, gridHeight int) (float64, float64) {
+	sizeX, sizeY := v.Size(windowWidth, windowHeight, gridWidth, gridHeight)
+	u := v.CenterX + (xpos/float64(windowWidth)-0.5)*sizeX
+	t := v.CenterY + (ypos/float64(windowHeight)-0.5)*sizeY
+	return u, t
+}
+
+// WindowToGrid converts a cursor position in the window to grid coordinates,
+// wrapped around the torus.
+func (v View) WindowToGrid(xpos, ypos float64, windowWidth, windowHeight, gridWidth, gridHeight int) (float64, float64) {
+	u, t := v.TexCoord(xpos, ypos, windowWidth, windowHeight, gridWidth, gridHeight)
+	return wrap(u) * float64(gridWidth), wrap(t) * float64(gridHeight)
+}
+
+// ZoomAt multiplies the zoom by factor, keeping the point under the cursor in place.
+func (v View) ZoomAt(factor, xpos, ypos float64, windowWidth, windowHeight, gridWidth, gridHeight int) View {
+	u, t := v.TexCoord(xpos, ypos, windowWidth, windowHeight, gridWidth, gridHeight)
+	v.Zoom = math.Max(0.1, math.Min(v.Zoom*factor, 256))
+	sizeX, sizeY := v.Size(windowWidth, windowHeight, gridWidth, gridHeight)
+	v.CenterX = wrap(u - (xpos/float64(windowWidth)-0.5)*sizeX)
+	v.CenterY = wrap(t - (ypos/float64(windowHeight)-0.5)*sizeY)
+	return v
+}
+
+// Pan moves the view by dx, dy window pixels, the grid follows the cursor.
+func (v View) Pan(dx, dy float64, windowWidth, windowHeight, gridWidth, gridHeight int) View {
+	sizeX, sizeY := v.Size(windowWidth, windowHeight, gridWidth, gridHeight)
+	v.CenterX = wrap(v.CenterX - dx/float64(windowWidth)*sizeX)
+	v.CenterY = wrap(v.CenterY - dy/float64(windowHeight)*sizeY)
+	return v
+}
+
+func wrap(x float64) float64 {
+	// Wraps a texture coordinate in [0,1[
+	return x - math.Floor(x)
+}
+
+func initView() {
+	// Gets the uniforms of the view and registers the callbacks that don't send commands
+	viewCenterLoc = gl.GetUniformLocation(program, gl.Str("viewCenter\x00"))
+	viewSizeLoc = gl.GetUniformLocation(program, gl.Str("viewSize\x00"))
+
+	window.SetFramebufferSizeCallback(func(w *glfw.Window, fbWidth, fbHeight int) {
+		gl.Viewport(0, 0, int32(fbWidth), int32(fbHeight))
+	})
+	window.SetScrollCallback(func(w *glfw.Window, xoff, yoff float64) {
+		xpos, ypos := w.GetCursorPos()
+		windowWidth, windowHeight := w.GetSize()
+		view = view.ZoomAt(math.Pow(1.2, yoff), xpos, ypos, windowWidth, windowHeight, width, height)
+	})
+	window.SetMouseButtonCallback(func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+		handleMouseButton(w, button, action, mods)
+	})
+	window.SetCursorPosCallback(func(w *glfw.Window, xpos, ypos float64) {
+		handleCursorPos(w, xpos, ypos)
+	})
+	window.SetKeyCallback(func(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+		handleKey(key, action, mods)
+	})
+}
+
+func setViewUniforms() {
+	// Sends the view to the vertex shader, called before each draw
+	windowWidth, windowHeight := window.GetSize()
+	sizeX, sizeY := view.Size(windowWidth, windowHeight, width, height)
+	gl.Uniform2f(viewCenterLoc, float32(view.CenterX), float32(view.CenterY))
+	gl.Uniform2f(viewSizeLoc, float32(sizeX), float32(sizeY))
+}
+
+func handleViewKey(key glfw.Key, action glfw.Action) bool {
+	// Applies the keys of the view, returns false if key is not one of them
+	if action == glfw.Release {
+		return false
+	}
+	windowWidth, windowHeight := window.GetSize()
+	step := 0.1 * float64(min(windowWidth, windowHeight))
+	switch key {
+	case glfw.KeyF:
+		if action == glfw.Press {
+			setLinearFilter(!linearFilter)
+		}
+	case glfw.KeyHome:
+		view = View{CenterX: 0.5, CenterY: 0.5, Zoom: 1}
+	case glfw.KeyLeft:
+		view = view.Pan(step, 0, windowWidth, windowHeight, width, height)
+	case glfw.KeyRight:
+		view = view.Pan(-step, 0, windowWidth, windowHeight, width, height)
+	case glfw.KeyUp:
+		view = view.Pan(0, step, windowWidth, windowHeight, width, height)
+	case glfw.KeyDown:
+		view = view.Pan(0, -step, windowWidth, windowHeight, width, height)
+	default:
+		return false
+	}
+	return true
+}
+
+func setLinearFilter(linear bool) {
+	// Nearest shows the cells as squares when zooming, linear smooths them
+	linearFilter = linear
+	filter := int32(gl.NEAREST)
+	if linear {
+		filter = gl.LINEAR
+	}
+	for _, t := range []uint32{texture, floatTexture} {
+		if t == 0 {
+			continue
+		}
+		gl.BindTexture(gl.TEXTURE_2D, t)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, filter)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, filter)
+	}
+}
+
+func handlePan(button glfw.MouseButton, action glfw.Action, xpos, ypos float64) bool {
+	// Starts or stops panning with the middle button, returns false for other buttons
+	if button != glfw.MouseButtonMiddle {
+		return false
+	}
+	panning = action == glfw.Press
+	lastX, lastY = xpos, ypos
+	return true
+}
+
+func handlePanMove(xpos, ypos float64) {
+	if !panning {
+		return
+	}
+	windowWidth, windowHeight := window.GetSize()
+	view = view.Pan(xpos-lastX, ypos-lastY, windowWidth, windowHeight, width, height)
+	lastX, lastY = xpos, ypos
+}
@@ -0,0 +1,132 @@
+package opengl_utils
+
+import (
+	"math"
+	"testing"
+)
+
+// near returns whether a and b are equal but for the rounding errors.
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// nearWrapped compares texture coordinates around the torus.
+func nearWrapped(a, b float64) bool {
+	d := wrap(a - b)
+	return d < 1e-9 || d > 1-1e-9
+}
+
+func TestSize(t *testing.T) {
+	tests := []struct {
+		name                                             string
+		zoom                                             float64
+		windowWidth, windowHeight, gridWidth, gridHeight int
+		sizeX, sizeY                                     float64
+	}{
+		{"square", 1, 512, 512, 128, 128, 1, 1},
+		{"wide window", 1, 800, 600, 100, 100, 4.0 / 3, 1},
+		{"tall window", 1, 600, 800, 100, 100, 1, 4.0 / 3},
+		{"wide grid", 1, 800, 600, 200, 100, 1, 1.5},
+		{"zoomed in", 2, 800, 600, 100, 100, 2.0 / 3, 0.5},
+		{"zoomed out", 0.5, 800, 600, 100, 100, 8.0 / 3, 2},
+		// a resized window shows more of the grid, the cells keep their size
+		{"resized", 1, 1600, 600, 100, 100, 8.0 / 3, 1},
+	}
+	for _, test := range tests {
+		v := View{CenterX: 0.5, CenterY: 0.5, Zoom: test.zoom}
+		sizeX, sizeY := v.Size(test.windowWidth, test.windowHeight, test.gridWidth, test.gridHeight)
+		if !near(sizeX, test.sizeX) || !near(sizeY, test.sizeY) {
+			t.Errorf("%s: size %g x %g, want %g x %g", test.name, sizeX, sizeY, test.sizeX, test.sizeY)
+		}
+	}
+}
+
+func TestWindowToGrid(t *testing.T) {
+	tests := []struct {
+		name       string
+		view       View
+		xpos, ypos float64
+		u, v       float64 // texture coordinates, not wrapped
+		x, y       float64 // cell of the grid of 100 x 100
+	}{
+		{"center", View{0.5, 0.5, 1}, 400, 300, 0.5, 0.5, 50, 50},
+		{"top left", View{0.5, 0.5, 1}, 0, 0, 0.5 - 2.0/3, 0, 100 - 100.0/6, 0},
+		{"bottom right", View{0.5, 0.5, 1}, 800, 600, 0.5 + 2.0/3, 1, 100.0 / 6, 0},
+		{"first line at the top", View{0.5, 0.5, 1}, 400, 30, 0.5, 0.05, 50, 5},
+		{"zoomed", View{0.25, 0.75, 4}, 600, 150, 0.25 + 1.0/12, 0.75 - 1.0/16, 100.0/3, 68.75},
+		{"across the border", View{0.95, 0.02, 2}, 700, 100, 0.95 + 0.25, 0.02 - 1.0/6, 20, 100*(1.02-1.0/6)},
+	}
+	for _, test := range tests {
+		u, v := test.view.TexCoord(test.xpos, test.ypos, 800, 600, 100, 100)
+		if !near(u, test.u) || !near(v, test.v) {
+			t.Errorf("%s: texture (%g,%g), want (%g,%g)", test.name, u, v, test.u, test.v)
+		}
+		x, y := test.view.WindowToGrid(test.xpos, test.ypos, 800, 600, 100, 100)
+		if !near(x, test.x) || !near(y, test.y) {
+			t.Errorf("%s: cell (%g,%g), want (%g,%g)", test.name, x, y, test.x, test.y)
+		}
+		if x < 0 || x >= 100 || y < 0 || y >= 100 {
+			t.Errorf("%s: cell (%g,%g) out of the grid", test.name, x, y)
+		}
+	}
+}
+
+// TestZoomAt checks that the point under the cursor stays in place, and the
+// bounds of the zoom.
+func TestZoomAt(t *testing.T) {
+	tests := []struct {
+		name       string
+		view       View
+		factor     float64
+		xpos, ypos float64
+		zoom       float64
+	}{
+		{"in at the center", View{0.5, 0.5, 1}, 1.2, 400, 300, 1.2},
+		{"in at a corner", View{0.5, 0.5, 1}, 2, 0, 0, 2},
+		{"out", View{0.3, 0.6, 3}, 1 / 1.2, 100, 500, 2.5},
+		{"across the border", View{0.02, 0.98, 4}, 1.5, 50, 580, 6},
+		{"clamped in", View{0.5, 0.5, 200}, 2, 123, 456, 256},
+		{"clamped out", View{0.5, 0.5, 0.15}, 0.5, 700, 10, 0.1},
+	}
+	for _, test := range tests {
+		zoomed := test.view.ZoomAt(test.factor, test.xpos, test.ypos, 800, 600, 100, 100)
+		if !near(zoomed.Zoom, test.zoom) {
+			t.Errorf("%s: zoom %g, want %g", test.name, zoomed.Zoom, test.zoom)
+		}
+		if zoomed.CenterX < 0 || zoomed.CenterX >= 1 || zoomed.CenterY < 0 || zoomed.CenterY >= 1 {
+			t.Errorf("%s: center (%g,%g) not wrapped", test.name, zoomed.CenterX, zoomed.CenterY)
+		}
+		u, v := test.view.TexCoord(test.xpos, test.ypos, 800, 600, 100, 100)
+		zoomedU, zoomedV := zoomed.TexCoord(test.xpos, test.ypos, 800, 600, 100, 100)
+		if !nearWrapped(u, zoomedU) || !nearWrapped(v, zoomedV) {
+			t.Errorf("%s: (%g,%g) under the cursor, want (%g,%g)", test.name, zoomedU, zoomedV, u, v)
+		}
+	}
+}
+
+// TestPan checks that the point under the cursor follows it.
+func TestPan(t *testing.T) {
+	tests := []struct {
+		name             string
+		view             View
+		dx, dy           float64
+		centerX, centerY float64
+	}{
+		{"none", View{0.5, 0.5, 1}, 0, 0, 0.5, 0.5},
+		{"right", View{0.5, 0.5, 1}, 60, 0, 0.4, 0.5},
+		{"down zoomed", View{0.5, 0.5, 2}, 0, 120, 0.5, 0.4},
+		{"across the border", View{0.05, 0.95, 1}, 120, -60, 0.85, 0.05},
+		{"whole turn", View{0.3, 0.7, 1}, 800, 0, 0.3 - 4.0/3 + 2, 0.7},
+	}
+	for _, test := range tests {
+		panned := test.view.Pan(test.dx, test.dy, 800, 600, 100, 100)
+		if !near(panned.CenterX, test.centerX) || !near(panned.CenterY, test.centerY) || panned.Zoom != test.view.Zoom {
+			t.Errorf("%s: view %+v, want the center at (%g,%g)", test.name, panned, test.centerX, test.centerY)
+		}
+		u, v := test.view.TexCoord(200, 100, 800, 600, 100, 100)
+		pannedU, pannedV := panned.TexCoord(200+test.dx, 100+test.dy, 800, 600, 100, 100)
+		if !nearWrapped(u, pannedU) || !nearWrapped(v, pannedV) {
+			t.Errorf("%s: (%g,%g) under the cursor, want (%g,%g)", test.name, pannedU, pannedV, u, v)
+		}
+	}
+}
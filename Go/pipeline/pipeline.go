@@ -0,0 +1,94 @@
+package pipeline
+
+import (
+	"sync"
+	"time"
+)
+
+// The simulation and the renderer run at their own pace : the simulation goroutine
+// writes each step in the back frame of a FrameBuffer and publishes it, the renderer
+// takes the latest published frame whenever it draws. With three frames neither side
+// ever waits for the other, frames the renderer is too slow to show are dropped.
+
+// Frame is a state of the simulation ready to be shown.
+type Frame struct {
+	Pixels []uint8   // 8 bits RGB pixels
+	Floats []float32 // float RGB values, used instead of Pixels by the float texture
+	Step   int
+	HUD    []string // text of the overlay, computed with the state
+}
+
+// FrameBuffer is a triple buffer of frames shared by one producer and one consumer.
+type FrameBuffer struct {
+	mutex              sync.Mutex
+	frames             [3]*Frame
+	back, ready, front int  // index of the frame written, of the last published one and of the one shown
+	fresh              bool // a frame was published since the last Latest
+}
+
+func NewFrameBuffer() *FrameBuffer {
+	return &FrameBuffer{
+		frames: [3]*Frame{{}, {}, {}},
+		back:   0,
+		ready:  1,
+		front:  2,
+	}
+}
+
+// Back returns the frame the producer writes, it belongs to the producer until Publish.
+func (b *FrameBuffer) Back() *Frame {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.frames[b.back]
+}
+
+// Publish makes the back frame the latest one, and gives the producer the previous
+// latest frame to write, which the consumer never took.
+func (b *FrameBuffer) Publish() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.back, b.ready = b.ready, b.back
+	b.fresh = true
+}
+
+// Latest returns the last published frame and true if it was not returned before.
+// The frame belongs to the consumer until the next call.
+func (b *FrameBuffer) Latest() (*Frame, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if !b.fresh {
+		return b.frames[b.front], false
+	}
+	b.front, b.ready = b.ready, b.front
+	b.fresh = false
+	return b.frames[b.front], true
+}
+
+// Limiter spaces calls to Wait so they happen at most rate times per second.
+type Limiter struct {
+	interval time.Duration
+	next     time.Time
+}
+
+// NewLimiter returns a limiter of rate calls per second, 0 does not limit.
+func NewLimiter(rate float64) *Limiter {
+	l := &Limiter{}
+	if rate > 0 {
+		l.interval = time.Duration(float64(time.Second) / rate)
+	}
+	return l
+}
+
+// Wait sleeps until the next call is allowed. A late call does not make the next
+// ones faster to catch up, so a slow step is not followed by a burst.
+func (l *Limiter) Wait() {
+	if l.interval == 0 {
+		return
+	}
+	now := time.Now()
+	if l.next.After(now) {
+		time.Sleep(l.next.Sub(now))
+		now = l.next
+	}
+	l.next = now.Add(l.interval)
+}
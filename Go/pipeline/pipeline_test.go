@@ -0,0 +1,103 @@
+package pipeline
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestFrameBuffer runs a producer and a consumer concurrently, best with -race:
+// each frame is filled with its step, so a frame written while it is shown would
+// mix two steps, and the steps shown must increase.
+func TestFrameBuffer(t *testing.T) {
+	const shownSteps, size = 1000, 256
+	b := NewFrameBuffer()
+	stop, done := make(chan struct{}), make(chan int)
+	go func() {
+		for step := 1; ; step++ {
+			f := b.Back()
+			if f.Pixels == nil {
+				f.Pixels = make([]uint8, size)
+			}
+			for i := range f.Pixels {
+				f.Pixels[i] = uint8(step)
+			}
+			f.Step = step
+			b.Publish()
+			if step%3 == 0 { // on a single core, the consumer misses two frames out of three
+				runtime.Gosched()
+			}
+			select {
+			case <-stop:
+				done <- step
+				return
+			default:
+			}
+		}
+	}()
+
+	last, shown := 0, 0
+	check := func(f *Frame, fresh bool) {
+		switch {
+		case fresh && f.Step <= last:
+			t.Fatalf("step %d shown after step %d", f.Step, last)
+		case !fresh && f.Step != last:
+			t.Fatalf("step %d returned again as step %d", last, f.Step)
+		}
+		for i, v := range f.Pixels {
+			if v != uint8(f.Step) {
+				t.Fatalf("step %d: pixel %d of step %d, the frame is torn", f.Step, i, v)
+			}
+		}
+		if fresh {
+			last = f.Step
+			shown++
+		}
+	}
+	for shown < shownSteps {
+		check(b.Latest())
+		check(b.Latest()) // usually no new frame yet
+		runtime.Gosched()
+	}
+	close(stop)
+	steps := <-done
+	// the last step is published once the producer is done
+	check(b.Latest())
+	if last != steps {
+		t.Errorf("last step shown %d, want %d", last, steps)
+	}
+	t.Logf("%d of %d steps shown", shown, steps)
+}
+
+func TestLimiter(t *testing.T) {
+	const rate, calls = 200, 21
+	interval := time.Second / rate
+
+	l := NewLimiter(rate)
+	start := time.Now()
+	for i := 0; i < calls; i++ {
+		l.Wait()
+	}
+	// the first call does not wait
+	if elapsed := time.Since(start); elapsed < (calls-1)*interval || elapsed > 4*(calls-1)*interval {
+		t.Errorf("%d calls at %d per second in %v, want about %v", calls, rate, elapsed, (calls-1)*interval)
+	}
+
+	// after a late call the next one still waits a whole interval
+	time.Sleep(5 * interval)
+	l.Wait()
+	start = time.Now()
+	l.Wait()
+	if elapsed := time.Since(start); elapsed < interval*9/10 {
+		t.Errorf("call after a late one in %v, want %v", elapsed, interval)
+	}
+
+	l = NewLimiter(0)
+	start = time.Now()
+	for i := 0; i < 1000; i++ {
+		l.Wait()
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("1000 calls without limit in %v", elapsed)
+	}
+}
@@ -0,0 +1,67 @@
+package renderer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"main/commands"
+	"main/image_utils"
+	"main/pipeline"
+)
+
+// Image writes the frames in a directory without opening any display, for batch runs.
+// A frame is written every Every steps, when the simulation is faster than the
+// writing some steps are skipped and the next frame is written instead.
+type Image struct {
+	Options
+	width, height int
+	next          int // step of the next image
+}
+
+func (r *Image) Init(width, height int, queue *commands.Queue) error {
+	r.width, r.height = width, height
+	if r.Dir == "" {
+		r.Dir = "."
+	}
+	if r.Format == "" {
+		r.Format = "png"
+	}
+	if r.Every <= 0 {
+		r.Every = 1
+	}
+	return os.MkdirAll(r.Dir, 0755)
+}
+
+func (r *Image) Present(frame *pipeline.Frame, fresh bool) error {
+	if !fresh || frame.Step < r.next {
+		time.Sleep(time.Millisecond) // waits for the next frame without spinning
+		return nil
+	}
+	r.next = frame.Step - frame.Step%r.Every + r.Every
+
+	fields := &image_utils.Fields{Width: r.width, Height: r.height}
+	channels := []*[]float64{&fields.R, &fields.G, &fields.B}
+	for c, channel := range channels {
+		*channel = make([]float64, r.width*r.height)
+		for i := range *channel {
+			if frame.Floats != nil {
+				(*channel)[i] = float64(frame.Floats[i*3+c])
+			} else if frame.Pixels != nil {
+				(*channel)[i] = float64(frame.Pixels[i*3+c]) / 255
+			}
+		}
+	}
+	path := filepath.Join(r.Dir, fmt.Sprintf("frame_%06d.%s", frame.Step, r.Format))
+	return image_utils.Save(path, fields, 8)
+}
+
+func (r *Image) PollInput() bool {
+	// There is no input, the run ends with -steps or Ctrl-C
+	return true
+}
+
+func (r *Image) Close() error {
+	return nil
+}
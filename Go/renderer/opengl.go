@@ -0,0 +1,58 @@
+package renderer
+
+import (
+	"fmt"
+	"time"
+
+	"main/commands"
+	"main/opengl_utils"
+	"main/pipeline"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// OpenGL shows the frames in a GLFW window, see opengl_utils. It must be used from
+// the thread that called Init, which has to be locked with runtime.LockOSThread.
+type OpenGL struct {
+	frames     int // frames drawn since start, for the display rate
+	start      time.Time
+	displayFps float64
+}
+
+func (r *OpenGL) Init(width, height int, queue *commands.Queue) error {
+	opengl_utils.InitWindow(width, height)
+	opengl_utils.BindKeys(queue)
+	opengl_utils.BindMouse(queue)
+	r.start = time.Now()
+	return nil
+}
+
+func (r *OpenGL) Present(frame *pipeline.Frame, fresh bool) error {
+	hud := append([]string{}, frame.HUD...)
+	opengl_utils.SetHUD(append(hud, fmt.Sprintf("display %.0f fps", r.displayFps)))
+	switch {
+	case fresh && frame.Floats != nil:
+		opengl_utils.UpdateFloatTexture(frame.Floats, 3)
+	case fresh && frame.Pixels != nil:
+		opengl_utils.UpdateTexture(frame.Pixels)
+	default:
+		opengl_utils.Draw() // the view and the overlay follow the input between frames
+	}
+
+	// the display rate is averaged over a second so the overlay stays readable
+	r.frames++
+	if elapsed := time.Since(r.start); elapsed >= time.Second {
+		r.displayFps = float64(r.frames) / elapsed.Seconds()
+		r.frames, r.start = 0, time.Now()
+	}
+	return nil
+}
+
+func (r *OpenGL) PollInput() bool {
+	return opengl_utils.PollEvents()
+}
+
+func (r *OpenGL) Close() error {
+	glfw.Terminate()
+	return nil
+}
@@ -0,0 +1,71 @@
+package renderer
+
+import (
+	"fmt"
+	"sort"
+
+	"main/commands"
+	"main/pipeline"
+)
+
+// Backends showing the frames of the simulation. The main loop only talks to a
+// Renderer, so the same loop runs in a window, in a terminal over SSH or without
+// any display to write the frames as images.
+
+// Renderer shows frames and turns the input of the user into commands.
+type Renderer interface {
+	// Init opens the output for a grid of width x height cells, the input is pushed in queue.
+	Init(width, height int, queue *commands.Queue) error
+	// Present shows frame, fresh is false when it was already presented.
+	Present(frame *pipeline.Frame, fresh bool) error
+	// PollInput handles the pending input, it returns false once the user closed the output.
+	PollInput() bool
+	// Close releases the output.
+	Close() error
+}
+
+// Options are the settings of the backends, each one uses some of them.
+type Options struct {
+	Dir    string // directory of the images
+	Format string // extension of the images: png, ppm, pgm, jpg or gif
+	Every  int    // write an image every Every steps
+}
+
+var backends = map[string]func(opts Options) Renderer{
+	"opengl":   func(Options) Renderer { return &OpenGL{} },
+	"terminal": func(Options) Renderer { return &Terminal{} },
+	"image":    func(opts Options) Renderer { return &Image{Options: opts} },
+}
+
+// Names returns the names of the backends, sorted.
+func Names() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns the backend called name.
+func New(name string, opts Options) (Renderer, error) {
+	backend, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown renderer %q (available: %v)", name, Names())
+	}
+	return backend(opts), nil
+}
+
+// rgb returns the 8 bits RGB pixels of frame, converting its float values when it has no pixels.
+func rgb(frame *pipeline.Frame, pixels []uint8) []uint8 {
+	if frame.Floats == nil {
+		return frame.Pixels
+	}
+	if len(pixels) != len(frame.Floats) {
+		pixels = make([]uint8, len(frame.Floats))
+	}
+	for i, v := range frame.Floats {
+		pixels[i] = uint8(255 * min(max(v, 0), 1))
+	}
+	return pixels
+}
@@ -0,0 +1,182 @@
+package renderer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"main/commands"
+	"main/pipeline"
+)
+
+// Terminal draws the frames in a terminal with 24 bits ANSI colors, for remote runs
+// where no window can be opened. Each character is an upper half block whose
+// foreground is the top pixel and background the bottom one, so a character shows
+// two pixels. The grid is downsampled (averaging the cells) to fit the terminal,
+// whose size is read again at each frame. The keys are read from stdin without
+// waiting for Enter, see terminalKeys.
+
+const (
+	terminalFps = 15 // the terminal is redrawn at most this many times per second
+	hudRows     = 1  // lines below the grid for the step and the speed
+	upperHalf   = "▀"
+)
+
+// terminalKeys are the commands sent by the keys in the terminal.
+var terminalKeys = map[byte]commands.Command{
+	' ':  {Kind: commands.Pause},
+	'n':  {Kind: commands.Step},
+	'r':  {Kind: commands.Reseed},
+	0x7f: {Kind: commands.Reset}, // backspace
+	0x08: {Kind: commands.Reset},
+	's':  {Kind: commands.Snapshot},
+	'+':  {Kind: commands.DtUp},
+	'=':  {Kind: commands.DtUp},
+	'-':  {Kind: commands.DtDown},
+	'>':  {Kind: commands.RadiusUp},
+	'<':  {Kind: commands.RadiusDown},
+	'q':  {Kind: commands.Quit},
+}
+
+type Terminal struct {
+	width, height int // size of the grid
+	columns, rows int // size of the terminal, in characters
+	pixels        []uint8
+	out           *bufio.Writer
+	limiter       *pipeline.Limiter
+	restore       func() // gives the terminal back its mode, nil if stdin is not a terminal
+}
+
+func (r *Terminal) Init(width, height int, queue *commands.Queue) error {
+	r.width, r.height = width, height
+	r.out = bufio.NewWriter(os.Stdout)
+	r.limiter = pipeline.NewLimiter(terminalFps)
+
+	// Without a terminal on stdin (a pipe, a batch job) the frames are still drawn
+	if restore, err := makeRaw(int(os.Stdin.Fd())); err == nil {
+		r.restore = restore
+		go readKeys(queue)
+	}
+	r.out.WriteString("\x1b[?25l") // hides the cursor
+	return r.out.Flush()
+}
+
+func readKeys(queue *commands.Queue) {
+	// Sends the command of each key read on stdin until it is closed
+	in := bufio.NewReader(os.Stdin)
+	for {
+		key, err := in.ReadByte()
+		if err != nil {
+			return
+		}
+		if c, ok := terminalKeys[key]; ok {
+			queue.Push(c)
+		}
+	}
+}
+
+func (r *Terminal) size() (int, int) {
+	// Size of the terminal, from the tty or from COLUMNS and LINES, 80 x 24 by default
+	if columns, rows, ok := terminalSize(int(os.Stdout.Fd())); ok {
+		return columns, rows
+	}
+	return envSize("COLUMNS", 80), envSize("LINES", 24)
+}
+
+func envSize(name string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n > 1 {
+		return n
+	}
+	return fallback
+}
+
+func (r *Terminal) Present(frame *pipeline.Frame, fresh bool) error {
+	r.limiter.Wait()
+	columns, rows := r.size()
+	resized := columns != r.columns || rows != r.rows
+	r.columns, r.rows = columns, rows
+	if !fresh && !resized {
+		return nil
+	}
+	r.pixels = rgb(frame, r.pixels)
+	if r.pixels == nil {
+		return nil
+	}
+	if resized {
+		r.out.WriteString("\x1b[2J") // clears what the previous size left
+	}
+
+	// The cells stay square: a character is one pixel wide and two high
+	outWidth, outHeight := fitSize(r.width, r.height, r.columns, 2*max(r.rows-hudRows, 1))
+	image := Downsample(r.pixels, r.width, r.height, outWidth, outHeight)
+
+	r.out.WriteString("\x1b[H") // top left corner
+	for y := 0; y+1 < outHeight; y += 2 {
+		for x := 0; x < outWidth; x++ {
+			top := image[(y*outWidth+x)*3:]
+			bottom := image[((y+1)*outWidth+x)*3:]
+			fmt.Fprintf(r.out, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm%s",
+				top[0], top[1], top[2], bottom[0], bottom[1], bottom[2], upperHalf)
+		}
+		r.out.WriteString("\x1b[0m\x1b[K\r\n")
+	}
+	if len(frame.HUD) > 0 {
+		line := strings.TrimSpace(frame.HUD[0]) + "  [space] pause [n] step [r] reseed [+/-] dt [q] quit"
+		r.out.WriteString(line[:min(len(line), r.columns)])
+	}
+	r.out.WriteString("\x1b[K")
+	return r.out.Flush()
+}
+
+// fitSize returns the largest size with the aspect ratio of width x height that fits
+// in maxWidth x maxHeight, the height is even so it fills whole characters.
+func fitSize(width, height, maxWidth, maxHeight int) (int, int) {
+	outWidth, outHeight := maxWidth, maxWidth*height/width
+	if outHeight > maxHeight {
+		outWidth, outHeight = maxHeight*width/height, maxHeight
+	}
+	outWidth = min(max(outWidth, 1), width)
+	outHeight = min(max(outHeight, 2), height)
+	return outWidth, outHeight &^ 1
+}
+
+// Downsample averages the RGB pixels of a width x height image into an image of
+// outWidth x outHeight, each output pixel is the mean of the cells it covers.
+func Downsample(pixels []uint8, width, height, outWidth, outHeight int) []uint8 {
+	out := make([]uint8, outWidth*outHeight*3)
+	for oy := 0; oy < outHeight; oy++ {
+		y0, y1 := oy*height/outHeight, max((oy+1)*height/outHeight, oy*height/outHeight+1)
+		for ox := 0; ox < outWidth; ox++ {
+			x0, x1 := ox*width/outWidth, max((ox+1)*width/outWidth, ox*width/outWidth+1)
+			var sum [3]int
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					index := (y*width + x) * 3
+					sum[0] += int(pixels[index])
+					sum[1] += int(pixels[index+1])
+					sum[2] += int(pixels[index+2])
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			for c := 0; c < 3; c++ {
+				out[(oy*outWidth+ox)*3+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return out
+}
+
+func (r *Terminal) PollInput() bool {
+	// The keys are read by their own goroutine, q sends the quit command
+	return true
+}
+
+func (r *Terminal) Close() error {
+	if r.restore != nil {
+		r.restore()
+	}
+	r.out.WriteString("\x1b[0m\x1b[?25h\r\n") // default colors, shows the cursor again
+	return r.out.Flush()
+}
@@ -0,0 +1,41 @@
+//go:build linux
+
+package renderer
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// Terminal size and mode with the ioctls of Linux, see tty_ioctl(4).
+
+type winsize struct {
+	rows, columns, xpixel, ypixel uint16
+}
+
+func terminalSize(fd int) (int, int, bool) {
+	var ws winsize
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 || ws.columns == 0 || ws.rows == 0 {
+		return 0, 0, false
+	}
+	return int(ws.columns), int(ws.rows), true
+}
+
+func makeRaw(fd int) (func(), error) {
+	// Reads the keys one by one without echo. The signals are kept so Ctrl-C still stops the run
+	var old syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TCGETS, uintptr(unsafe.Pointer(&old))); errno != 0 {
+		return nil, errno
+	}
+	raw := old
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TCSETS, uintptr(unsafe.Pointer(&raw))); errno != 0 {
+		return nil, errno
+	}
+	return func() {
+		syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TCSETS, uintptr(unsafe.Pointer(&old)))
+	}, nil
+}
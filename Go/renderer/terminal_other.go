@@ -0,0 +1,15 @@
+//go:build !linux
+
+package renderer
+
+import "errors"
+
+// Other systems use COLUMNS and LINES for the size and have no keyboard controls.
+
+func terminalSize(fd int) (int, int, bool) {
+	return 0, 0, false
+}
+
+func makeRaw(fd int) (func(), error) {
+	return nil, errors.New("keyboard controls are only supported on linux")
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"main/commands"
+)
+
+// JSON API controlling the simulation, enabled with Control. Every change is a
+// command pushed in the queue of the simulation, the request waits for the
+// simulation to handle it and returns its error:
+//
+//	GET  /api/stats          statistics of the last step (Stats)
+//	GET  /api/params         parameters of the rules and kernel radius
+//	POST /api/params         {"dt": 0.1, "b1": 0.27} changes some of them, all or none
+//	POST /api/pause, /api/resume, /api/step, /api/reset, /api/reseed
+//	POST /api/snapshot/save  {"name": "run.npz"} saves in the snapshot directory (name is optional)
+//	POST /api/snapshot/load  {"name": "run.npz"} loads a snapshot of the snapshot directory
+//
+// The answers are the requested values, {"ok": true} or {"error": "..."}.
+
+const replyTimeout = 30 * time.Second // a step of a big grid can be slow
+
+// Stats are the statistics of the simulation, set by the simulation with SetStats.
+type Stats struct {
+	Step           int                `json:"step"`
+	Paused         bool               `json:"paused"`
+	StepsPerSecond float64            `json:"steps_per_second"`
+	Seed           int64              `json:"seed"`
+	Width          int                `json:"width"`
+	Height         int                `json:"height"`
+	Params         map[string]float64 `json:"params"`
+	Rule           string             `json:"rule,omitempty"`       // rulestring of the models having one
+	Generation     uint64             `json:"generation,omitempty"` // of game_of_life, steps of 2^life_step generations
+	Population     int                `json:"population,omitempty"`
+	Means          [3]float64         `json:"means"`      // mean value of each world
+	Timings        map[string]float64 `json:"timings_ms"` // duration of the phases of the last step
+}
+
+type api struct {
+	queue *commands.Queue
+	mutex sync.Mutex
+	stats Stats
+}
+
+// Control enables the API, the commands are pushed in queue. It must be called before Handler.
+func (s *Server) Control(queue *commands.Queue) {
+	s.api = &api{queue: queue}
+}
+
+// SetStats changes the statistics returned by the API, it does nothing if the API is disabled.
+func (s *Server) SetStats(stats Stats) {
+	if s.api == nil {
+		return
+	}
+	s.api.mutex.Lock()
+	defer s.api.mutex.Unlock()
+	s.api.stats = stats
+}
+
+func (a *api) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/stats", a.handleStats)
+	mux.HandleFunc("GET /api/params", a.handleGetParams)
+	mux.HandleFunc("POST /api/params", a.handleSetParams)
+	mux.HandleFunc("POST /api/pause", a.simple(commands.Command{Kind: commands.SetPaused, Value: 1}))
+	mux.HandleFunc("POST /api/resume", a.simple(commands.Command{Kind: commands.SetPaused, Value: 0}))
+	mux.HandleFunc("POST /api/step", a.simple(commands.Command{Kind: commands.Step}))
+	mux.HandleFunc("POST /api/reset", a.simple(commands.Command{Kind: commands.Reset}))
+	mux.HandleFunc("POST /api/reseed", a.simple(commands.Command{Kind: commands.Reseed}))
+	mux.HandleFunc("POST /api/snapshot/save", a.handleSnapshot(commands.Snapshot))
+	mux.HandleFunc("POST /api/snapshot/load", a.handleSnapshot(commands.Load))
+}
+
+// send pushes c in the queue and waits for its result.
+func (a *api) send(r *http.Request, c commands.Command) error {
+	c.Reply = make(chan error, 1)
+	a.queue.Push(c)
+	select {
+	case err := <-c.Reply:
+		return err
+	case <-r.Context().Done():
+		return r.Context().Err()
+	case <-time.After(replyTimeout):
+		return errTimeout
+	}
+}
+
+var errTimeout = errors.New("the simulation did not answer in time")
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeResult answers {"ok": true}, or the error of the command.
+func writeResult(w http.ResponseWriter, err error) {
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	case errors.Is(err, errTimeout):
+		writeJSON(w, http.StatusGatewayTimeout, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+}
+
+func (a *api) simple(c commands.Command) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, a.send(r, c))
+	}
+}
+
+func (a *api) handleStats(w http.ResponseWriter, r *http.Request) {
+	a.mutex.Lock()
+	stats := a.stats
+	a.mutex.Unlock()
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (a *api) handleGetParams(w http.ResponseWriter, r *http.Request) {
+	a.mutex.Lock()
+	params := a.stats.Params
+	a.mutex.Unlock()
+	writeJSON(w, http.StatusOK, params)
+}
+
+func (a *api) handleSetParams(w http.ResponseWriter, r *http.Request) {
+	var params map[string]float64
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeResult(w, fmt.Errorf("invalid parameters: %v", err))
+		return
+	}
+	// a single command, so a parameter that fails leaves the others unchanged
+	writeResult(w, a.send(r, commands.Command{Kind: commands.SetParams, Params: params}))
+}
+
+func (a *api) handleSnapshot(kind commands.Kind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Name string `json:"name"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeResult(w, fmt.Errorf("invalid body: %v", err))
+				return
+			}
+		}
+		writeResult(w, a.send(r, commands.Command{Kind: kind, Name: body.Name}))
+	}
+}
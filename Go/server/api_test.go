@@ -0,0 +1,214 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"main/commands"
+)
+
+// fakeSimulation answers the commands of the API like the loop of the simulation:
+// it drains the queue and dispatches the commands to handlers changing its fields.
+type fakeSimulation struct {
+	queue *commands.Queue
+
+	mutex    sync.Mutex
+	params   map[string]float64
+	paused   bool
+	received []commands.Command
+}
+
+func newFakeSimulation(t *testing.T) *fakeSimulation {
+	f := &fakeSimulation{queue: &commands.Queue{}, params: map[string]float64{"dt": 0.15, "b1": 0.278, "ra": 11}}
+	d := commands.NewDispatcher()
+	d.Handle(commands.SetParams, func(c commands.Command) error {
+		for name, value := range c.Params {
+			if _, ok := f.params[name]; !ok {
+				return fmt.Errorf("unknown parameter %q", name)
+			} else if value <= 0 {
+				return fmt.Errorf("invalid value %g for %s", value, name)
+			}
+		}
+		for name, value := range c.Params {
+			f.params[name] = value
+		}
+		return nil
+	})
+	d.Handle(commands.SetPaused, func(c commands.Command) error {
+		f.paused = c.Value != 0
+		return nil
+	})
+	d.Handle(commands.Snapshot, func(c commands.Command) error {
+		if strings.Contains(c.Name, "/") {
+			return errors.New("the name of a snapshot is a file of the snapshot directory")
+		}
+		return nil
+	})
+
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				for _, c := range f.queue.Drain() {
+					f.mutex.Lock()
+					f.received = append(f.received, c)
+					d.Dispatch(c)
+					f.mutex.Unlock()
+				}
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		close(done)
+		<-stopped
+	})
+	return f
+}
+
+// commands returns the kinds of the commands received since the last call.
+func (f *fakeSimulation) commands() []commands.Kind {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var kinds []commands.Kind
+	for _, c := range f.received {
+		kinds = append(kinds, c.Kind)
+	}
+	f.received = nil
+	return kinds
+}
+
+// serve answers a request of method on path with body by the handler of s.
+func serve(s *Server, method, path, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	s.Handler().ServeHTTP(recorder, request)
+	return recorder
+}
+
+func decode(t *testing.T, recorder *httptest.ResponseRecorder, value interface{}) {
+	t.Helper()
+	if err := json.NewDecoder(recorder.Body).Decode(value); err != nil {
+		t.Fatalf("invalid JSON answer: %v", err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	s := New(8, 8)
+	s.Control(newFakeSimulation(t).queue)
+	stats := Stats{Step: 12, Paused: true, Seed: 3, Width: 8, Height: 8, Params: map[string]float64{"dt": 0.1}, Means: [3]float64{0.5, 0.25, 0}}
+	s.SetStats(stats)
+
+	recorder := serve(s, "GET", "/api/stats", "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d", recorder.Code)
+	}
+	var got Stats
+	decode(t, recorder, &got)
+	if !reflect.DeepEqual(got, stats) {
+		t.Errorf("stats %+v, want %+v", got, stats)
+	}
+
+	recorder = serve(s, "GET", "/api/params", "")
+	var params map[string]float64
+	decode(t, recorder, &params)
+	if !reflect.DeepEqual(params, stats.Params) {
+		t.Errorf("params %v, want %v", params, stats.Params)
+	}
+}
+
+func TestSetParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   map[string]float64 // parameters of the simulation after the request
+	}{
+		{"valid", `{"dt": 0.1, "b1": 0.27}`, http.StatusOK, map[string]float64{"dt": 0.1, "b1": 0.27, "ra": 11}},
+		{"unknown name", `{"dt": 0.1, "b1": 0.27, "x": 1}`, http.StatusBadRequest, map[string]float64{"dt": 0.15, "b1": 0.278, "ra": 11}},
+		{"invalid value", `{"b1": 0.27, "dt": 0.1, "ra": -1}`, http.StatusBadRequest, map[string]float64{"dt": 0.15, "b1": 0.278, "ra": 11}},
+		{"bad JSON", `{"dt": }`, http.StatusBadRequest, map[string]float64{"dt": 0.15, "b1": 0.278, "ra": 11}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFakeSimulation(t)
+			s := New(8, 8)
+			s.Control(f.queue)
+			recorder := serve(s, "POST", "/api/params", test.body)
+			if recorder.Code != test.status {
+				t.Errorf("status %d, want %d", recorder.Code, test.status)
+			}
+			var answer map[string]interface{}
+			decode(t, recorder, &answer)
+			if _, failed := answer["error"]; failed != (test.status != http.StatusOK) {
+				t.Errorf("answer %v", answer)
+			}
+
+			// every parameter is sent in the same command
+			kinds := f.commands()
+			if test.name == "bad JSON" {
+				if len(kinds) != 0 {
+					t.Errorf("commands %v sent for an invalid body", kinds)
+				}
+			} else if !reflect.DeepEqual(kinds, []commands.Kind{commands.SetParams}) {
+				t.Errorf("commands %v, want a single %v", kinds, commands.SetParams)
+			}
+			f.mutex.Lock()
+			defer f.mutex.Unlock()
+			if !reflect.DeepEqual(f.params, test.want) {
+				t.Errorf("parameters %v, want %v", f.params, test.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotAndPause(t *testing.T) {
+	f := newFakeSimulation(t)
+	s := New(8, 8)
+	s.Control(f.queue)
+	tests := []struct {
+		path, body string
+		status     int
+		want       commands.Command
+	}{
+		{"/api/snapshot/save", `{"name": "run.npz"}`, http.StatusOK, commands.Command{Kind: commands.Snapshot, Name: "run.npz"}},
+		{"/api/snapshot/save", "", http.StatusOK, commands.Command{Kind: commands.Snapshot}},
+		{"/api/snapshot/save", `{"name": "../run.npz"}`, http.StatusBadRequest, commands.Command{Kind: commands.Snapshot, Name: "../run.npz"}},
+		{"/api/pause", "", http.StatusOK, commands.Command{Kind: commands.SetPaused, Value: 1}},
+		{"/api/resume", "", http.StatusOK, commands.Command{Kind: commands.SetPaused, Value: 0}},
+	}
+	for _, test := range tests {
+		recorder := serve(s, "POST", test.path, test.body)
+		if recorder.Code != test.status {
+			t.Errorf("%s %s: status %d, want %d", test.path, test.body, recorder.Code, test.status)
+		}
+		f.mutex.Lock()
+		received, paused := f.received, f.paused
+		f.received = nil
+		f.mutex.Unlock()
+		if len(received) != 1 {
+			t.Fatalf("%s: %d commands, want 1", test.path, len(received))
+		}
+		got := received[0]
+		got.Reply = nil
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: command %+v, want %+v", test.path, got, test.want)
+		}
+		if test.path == "/api/pause" && !paused {
+			t.Errorf("not paused by /api/pause")
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"main/pipeline"
+)
+
+// HTTP server showing the simulation in a browser: an MJPEG stream of the frames,
+// the current frame as PNG or JPEG, and a small page showing the stream.
+// The simulation offers its frames with Offer, which never waits: a frame is only
+// copied when the encoder is idle, and each client is sent the latest encoded frame
+// when it is ready for one, so slow clients and a slow encoder only drop frames.
+
+const (
+	maxFps      = 25 // frames encoded per second at most
+	jpegQuality = 85
+	boundary    = "smoothlife-frame"
+)
+
+// encoded is a frame ready to be sent.
+type encoded struct {
+	image   *image.RGBA
+	jpeg    []byte
+	step    int
+	version int
+}
+
+type Server struct {
+	width, height int
+	busy          atomic.Bool         // the encoder has a frame to encode
+	pending       chan pipeline.Frame // frame copied by Offer, read by the encoder
+	pixels        []uint8
+	floats        []float32
+
+	mutex   sync.Mutex
+	current *encoded
+	updated chan struct{} // closed when current changes
+
+	api *api // nil unless Control was called, see api.go
+}
+
+// New returns a server of frames of width x height cells, its encoder runs in its own goroutine.
+func New(width, height int) *Server {
+	s := &Server{
+		width:   width,
+		height:  height,
+		pending: make(chan pipeline.Frame, 1),
+		updated: make(chan struct{}),
+	}
+	go s.encode()
+	return s
+}
+
+// Offer gives frame to the server if the encoder is idle, otherwise it is dropped.
+// The frame is copied, it can be changed once Offer returns.
+func (s *Server) Offer(frame *pipeline.Frame) {
+	if !s.busy.CompareAndSwap(false, true) {
+		return
+	}
+	// only one frame is ever being encoded, so the buffers are not in use
+	s.pixels = append(s.pixels[:0], frame.Pixels...)
+	s.floats = append(s.floats[:0], frame.Floats...)
+	s.pending <- pipeline.Frame{Pixels: s.pixels, Floats: s.floats, Step: frame.Step}
+}
+
+func (s *Server) encode() {
+	// Encodes the offered frames, at most maxFps times per second
+	limiter := pipeline.NewLimiter(maxFps)
+	for frame := range s.pending {
+		img := image.NewRGBA(image.Rect(0, 0, s.width, s.height))
+		for i := 0; i < s.width*s.height; i++ {
+			var c color.RGBA
+			if len(frame.Floats) >= (i+1)*3 {
+				c = color.RGBA{toByte(frame.Floats[i*3]), toByte(frame.Floats[i*3+1]), toByte(frame.Floats[i*3+2]), 255}
+			} else if len(frame.Pixels) >= (i+1)*3 {
+				c = color.RGBA{frame.Pixels[i*3], frame.Pixels[i*3+1], frame.Pixels[i*3+2], 255}
+			}
+			img.SetRGBA(i%s.width, i/s.width, c)
+		}
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			log.Printf("Error encoding frame: %v", err)
+		} else {
+			s.mutex.Lock()
+			version := 1
+			if s.current != nil {
+				version = s.current.version + 1
+			}
+			s.current = &encoded{image: img, jpeg: buf.Bytes(), step: frame.Step, version: version}
+			close(s.updated)
+			s.updated = make(chan struct{})
+			s.mutex.Unlock()
+		}
+
+		limiter.Wait()
+		s.busy.Store(false)
+	}
+}
+
+func toByte(v float32) uint8 {
+	return uint8(255 * min(max(v, 0), 1))
+}
+
+// latest returns the last encoded frame if it is newer than version, or waits for
+// one until the client goes away (nil).
+func (s *Server) latest(r *http.Request, version int) *encoded {
+	for {
+		s.mutex.Lock()
+		current, updated := s.current, s.updated
+		s.mutex.Unlock()
+		if current != nil && current.version > version {
+			return current
+		}
+		select {
+		case <-updated:
+		case <-r.Context().Done():
+			return nil
+		}
+	}
+}
+
+// Handler returns the routes of the server:
+// / the page, /stream.mjpg the MJPEG stream, /frame.jpg and /frame.png the current frame,
+// and /api/ when the API is enabled.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handlePage)
+	mux.HandleFunc("/stream.mjpg", s.handleStream)
+	mux.HandleFunc("/frame.jpg", s.handleFrame)
+	mux.HandleFunc("/frame.png", s.handleFrame)
+	if s.api != nil {
+		s.api.register(mux)
+	}
+	return mux
+}
+
+// ListenAndServe serves Handler on addr, e.g. ":8080".
+func (s *Server) ListenAndServe(addr string) error {
+	return http.ListenAndServe(addr, s.Handler())
+}
+
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+boundary)
+	w.Header().Set("Cache-Control", "no-cache")
+
+	version := 0
+	for {
+		frame := s.latest(r, version)
+		if frame == nil {
+			return
+		}
+		version = frame.version
+		_, err := fmt.Fprintf(w, "--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\nX-Step: %d\r\n\r\n",
+			boundary, len(frame.jpeg), frame.step)
+		if err == nil {
+			_, err = w.Write(frame.jpeg)
+		}
+		if err == nil {
+			_, err = w.Write([]byte("\r\n"))
+		}
+		if err != nil {
+			return // the client is gone
+		}
+		flusher.Flush()
+	}
+}
+
+func (s *Server) handleFrame(w http.ResponseWriter, r *http.Request) {
+	frame := s.latest(r, 0)
+	if frame == nil {
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Step", strconv.Itoa(frame.step))
+	if r.URL.Path == "/frame.png" {
+		w.Header().Set("Content-Type", "image/png")
+		png.Encode(w, frame.image)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Write(frame.jpeg)
+}
+
+func (s *Server) handlePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, page)
+}
+
+// page shows the MJPEG stream, or reloads the PNG frame every half second with ?png
+// (for browsers that don't show MJPEG).
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>smoothlife</title>
+<style>
+	body { margin: 0; background: #111; color: #ccc; font-family: monospace; }
+	img { display: block; margin: auto; max-width: 100vw; max-height: 95vh; image-rendering: pixelated; }
+	p { text-align: center; }
+</style>
+</head>
+<body>
+<img id="frame" src="/stream.mjpg" alt="simulation">
+<p><a href="/">mjpeg</a> · <a href="/?png">png</a> · <a href="/frame.png">current frame</a></p>
+<script>
+	if (location.search === "?png") {
+		const img = document.getElementById("frame");
+		const reload = () => { img.src = "/frame.png?" + Date.now(); };
+		img.onload = () => setTimeout(reload, 500);
+		img.onerror = () => setTimeout(reload, 2000);
+		reload();
+	}
+</script>
+</body>
+</html>
+`
@@ -1,9 +1,13 @@
 package smoothlife
 
 import (
+	"fmt"
 	"math"
 	"math/rand"
+  "sort"
   "sync"
+
+  "main/boundary"
 )
 
 var (
@@ -18,6 +22,10 @@ var (
 	b2 float64 = 0.365
 	d1 float64 = 0.267
 	d2 float64 = 0.445
+
+  rng = rand.New(rand.NewSource(1)) // random source of this engine, see SetSeed
+
+  edges boundary.Boundary // what the kernels see beyond the edges, see SetBoundary
 )
 
 func modulo(a, b int) int {
@@ -25,6 +33,19 @@ func modulo(a, b int) int {
   return (a%b + b) % b
 }
 
+func cell(world [][]float64, x, y int) float64 {
+  // Returns the value at (x,y), the coordinates out of the grid follow the boundary
+  x, insideX := edges.Index(x, width)
+  y, insideY := edges.Index(y, height)
+  if !insideX || !insideY {
+    if edges.Mode == boundary.Fixed {
+      return edges.Value
+    }
+    return 0
+  }
+  return world[y][x]
+}
+
 func clamp(x, min, max float64) float64 {
   // Make sure our values are not out of bound
   if x > max { 
@@ -68,7 +89,7 @@ func convolve(world [][]float64, x, y, radius int, noCenter bool) float64 {
       dist := math.Sqrt(float64(i-y)*float64(i-y) + float64(j-x)*float64(j-x))
       wheight := math.Exp(-0.5 * math.Pow(dist/float64(radius), 2))
 
-      sum += wheight * world[modulo(i, width)][modulo(j, height)]
+      sum += wheight * cell(world, j, i)
       total += wheight
     }
     }
@@ -80,6 +101,11 @@ func convolve(world [][]float64, x, y, radius int, noCenter bool) float64 {
   return 0.0
 }
 
+func SetSeed(seed int64) {
+  // Reseeds the random source used by GenerateRandomPixels so a run can be reproduced
+  rng = rand.New(rand.NewSource(seed))
+}
+
 // genere des pixels avec une couleur random
 func GenerateRandomPixels(grid_width, grid_height, kernelRadius int, threshold float32) ([]uint8, [][]float64) {
   width = grid_width
@@ -89,14 +115,14 @@ func GenerateRandomPixels(grid_width, grid_height, kernelRadius int, threshold f
   for y := range world {
     world[y] = make([]float64, width)
 		for x := range world[y] {
-      if rand.Float32() < threshold {
-        world[y][x] = rand.Float64()
+      if rng.Float32() < threshold {
+        world[y][x] = rng.Float64()
         for c := 0; c < 3; c++ {
-          nestedPixels[(y*height+x)*3+c] = uint8(255 * world[y][x])
+          nestedPixels[(y*width+x)*3+c] = uint8(255 * world[y][x])
         }
       } else {
         for c := 0; c < 3; c++ {
-          nestedPixels[(y*height+x)*3+c] = uint8(0)
+          nestedPixels[(y*width+x)*3+c] = uint8(0)
         } 
       }
 		}
@@ -105,6 +131,71 @@ func GenerateRandomPixels(grid_width, grid_height, kernelRadius int, threshold f
 	return nestedPixels, world
 }
 
+func LoadWorld(values []float64, grid_width, grid_height int) ([]uint8, [][]float64) {
+  // Init function from values in [0,1], e.g. the luminance of an image
+  width = grid_width
+  height = grid_height
+  world := make([][]float64, height)
+  nestedPixels := make([]uint8, height*width*3)
+  for y := range world {
+    world[y] = make([]float64, width)
+    for x := range world[y] {
+      world[y][x] = clamp(values[y*width+x], 0, 1)
+      for c := 0; c < 3; c++ {
+        nestedPixels[(y*width+x)*3+c] = uint8(255 * world[y][x])
+      }
+    }
+  }
+  return nestedPixels, world
+}
+
+func Size() (int, int) {
+  // Returns the width and height of the current grid
+  return width, height
+}
+
+func GetBoundary() boundary.Boundary {
+  return edges
+}
+
+func SetBoundary(b boundary.Boundary) {
+  // Changes the boundary of the kernels, used from the next update
+  edges = b
+}
+
+// parameters of the model by name, see Params and SetParam
+var params = map[string]*float64{
+  "ra": &ra, "alpha": &alpha, "dt": &dt, "b1": &b1, "b2": &b2, "d1": &d1, "d2": &d2,
+}
+
+func Params() map[string]float64 {
+  // Returns the current value of each parameter
+  values := make(map[string]float64, len(params))
+  for name, p := range params {
+    values[name] = *p
+  }
+  return values
+}
+
+func SetParam(name string, value float64) error {
+  // Changes a parameter, used from the next update. ra must be at least 4 so the
+  // inner kernel is not empty
+  p, ok := params[name]
+  if !ok {
+    names := make([]string, 0, len(params))
+    for n := range params {
+      names = append(names, n)
+    }
+    sort.Strings(names)
+    return fmt.Errorf("unknown parameter %q (available: %v)", name, names)
+  }
+  if (name == "ra" && value < 4) || ((name == "alpha" || name == "dt") && value <= 0) {
+    return fmt.Errorf("invalid value %g for %s", value, name)
+  }
+  *p = value
+  return nil
+}
+
 func updateLine(world [][]float64, y int, buffer []float64) {
 	for x := range buffer {
 		outer := outerKernel(world, x, y, int(ra-1))
@@ -135,7 +226,7 @@ func UpdateGrid(pixels []uint8, world [][]float64) ([]uint8, [][]float64) {
 			world[i][j] = clamp(world[i][j], 0, 1)
 			val := uint8(255 * world[i][j])
 			for c := 0; c < 3; c++ {
-				pixels[(i*height+j)*3+c] = val
+				pixels[(i*width+j)*3+c] = val
 			}
 		}
 	}
@@ -0,0 +1,37 @@
+package smoothlife
+
+import (
+	"math"
+	"testing"
+)
+
+// TestSeedReplays checks that two runs with the same seed are bit-identical after
+// a few steps, and that another seed gives another world.
+func TestSeedReplays(t *testing.T) {
+	run := func(seed int64) [][]float64 {
+		SetSeed(seed)
+		pixels, world := GenerateRandomPixels(24, 16, 6, 0.5)
+		for i := 0; i < 3; i++ {
+			pixels, world = UpdateGrid(pixels, world)
+		}
+		return world
+	}
+	world, again := run(42), run(42)
+	for y := range world {
+		for x := range world[y] {
+			if math.Float64bits(world[y][x]) != math.Float64bits(again[y][x]) {
+				t.Fatalf("cell (%d,%d): %v then %v with the same seed", x, y, world[y][x], again[y][x])
+			}
+		}
+	}
+	other := run(43)
+	same := true
+	for y := range world {
+		for x := range world[y] {
+			same = same && world[y][x] == other[y][x]
+		}
+	}
+	if same {
+		t.Errorf("the seeds 42 and 43 give the same world")
+	}
+}
@@ -0,0 +1,125 @@
+package smoothlife3d
+
+import (
+	"math"
+)
+
+// Painting on the worlds with a soft brush. A stroke is a list of segments painted
+// between BeginStroke and EndStroke, the values it changed are kept so the last
+// strokes can be undone.
+
+// Brush describes how the worlds are painted.
+type Brush struct {
+	Radius   float64 // in cells
+	Hardness float64 // fraction of the radius painted at full strength, the rest fades out
+	Value    float64 // value painted, erasing always paints 0
+	Flow     float64 // how much of the value is applied at each dab, in [0,1]
+	Channels [3]bool // worlds painted (R, G, B)
+}
+
+var DefaultBrush = Brush{Radius: 11, Hardness: 0.5, Value: 1, Flow: 1, Channels: [3]bool{true, true, true}}
+
+const maxUndo = 32 // number of strokes that can be undone
+
+type stroke struct {
+	before         map[int][3]float64 // values of the cells before the stroke
+	lastX, lastY   float64
+	started, erase bool
+}
+
+var (
+	current *stroke
+	strokes []*stroke // finished strokes, the last one is undone first
+)
+
+func BeginStroke(erase bool) {
+	// Starts a new stroke, the next PaintTo only paints a dab
+	current = &stroke{before: make(map[int][3]float64), erase: erase}
+}
+
+func PaintTo(x, y float64, brush Brush) {
+	// Paints from the last point of the stroke to (x, y), in grid coordinates
+	if current == nil {
+		BeginStroke(false)
+	}
+	if !current.started {
+		dab(x, y, brush)
+		current.lastX, current.lastY, current.started = x, y, true
+		return
+	}
+
+	// dabs are spaced by a quarter of the radius so the line looks continuous
+	length := math.Hypot(x-current.lastX, y-current.lastY)
+	spacing := math.Max(1, brush.Radius/4)
+	n := int(math.Ceil(length / spacing))
+	for i := 1; i <= n; i++ {
+		t := float64(i) / float64(n)
+		dab(current.lastX+(x-current.lastX)*t, current.lastY+(y-current.lastY)*t, brush)
+	}
+	current.lastX, current.lastY = x, y
+}
+
+func EndStroke() {
+	// Finishes the stroke so it can be undone
+	if current == nil || len(current.before) == 0 {
+		current = nil
+		return
+	}
+	strokes = append(strokes, current)
+	if len(strokes) > maxUndo {
+		strokes = strokes[1:]
+	}
+	current = nil
+}
+
+func Undo() bool {
+	// Restores the cells changed by the last stroke, returns false if there is nothing to undo
+	EndStroke()
+	if len(strokes) == 0 {
+		return false
+	}
+	last := strokes[len(strokes)-1]
+	strokes = strokes[:len(strokes)-1]
+	for index, values := range last.before {
+		if index < len(world1) {
+			world1[index], world2[index], world3[index] = values[0], values[1], values[2]
+		}
+	}
+	return true
+}
+
+func dab(cx, cy float64, brush Brush) {
+	// Paints a single disc of the brush, wrapping around the grid
+	worlds := [3][]float64{world1, world2, world3}
+	target := brush.Value
+	if current.erase {
+		target = 0
+	}
+	inner := brush.Radius * clamp(brush.Hardness, 0, 1)
+
+	for y := int(math.Floor(cy - brush.Radius)); y <= int(math.Ceil(cy+brush.Radius)); y++ {
+		for x := int(math.Floor(cx - brush.Radius)); x <= int(math.Ceil(cx+brush.Radius)); x++ {
+			dist := math.Hypot(float64(x)-cx, float64(y)-cy)
+			if dist > brush.Radius {
+				continue
+			}
+			// full strength up to the inner radius, then smoothstep to 0 on the border
+			weight := 1.0
+			if dist > inner {
+				t := (dist - inner) / (brush.Radius - inner)
+				weight = 1 - t*t*(3-2*t)
+			}
+			weight *= clamp(brush.Flow, 0, 1)
+
+			index := modulo(y, height)*width + modulo(x, width)
+			if _, ok := current.before[index]; !ok {
+				current.before[index] = [3]float64{world1[index], world2[index], world3[index]}
+			}
+			for c, world := range worlds {
+				if brush.Channels[c] {
+					world[index] = clamp(world[index]+(target-world[index])*weight, 0, 1)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package smoothlife3d
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Initial conditions for the simulation. Uniform noise (the -r flag) mostly gives
+// a soup, the generators below give the structures smoothlife is known for.
+// Each generator fills one channel and is called once per world, parameters
+// are given by name and fall back to a default depending on the kernel radius.
+
+type initGenerator func(world []float64, params map[string]float64, radius float64)
+
+var initGenerators = map[string]initGenerator{
+	"noise":     noiseInit,
+	"discs":     discsInit,
+	"value":     valueInit,
+	"perlin":    perlinInit,
+	"gaussian":  gaussianInit,
+	"stripes":   stripesInit,
+	"rings":     ringsInit,
+	"symmetric": symmetricInit,
+}
+
+// InitNames returns the names of the available initial condition generators.
+func InitNames() []string {
+	names := make([]string, 0, len(initGenerators))
+	for name := range initGenerators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// paramRanges are the valid values of the parameters of the generators, out of them
+// a generator would divide by zero or allocate a negative size
+var paramRanges = map[string]struct {
+	min, max  float64
+	strictMin bool // the minimum is excluded
+}{
+	"radius":  {0, math.MaxFloat64, true},
+	"sigma":   {0, math.MaxFloat64, true},
+	"scale":   {0, math.MaxFloat64, true},
+	"period":  {0, math.MaxFloat64, true},
+	"count":   {0, 1 << 20, false},
+	"octaves": {1, 16, false},
+	"order":   {1, 1 << 10, false},
+}
+
+func checkParams(params map[string]float64) error {
+	// Returns an error for the first parameter out of paramRanges, sorted by name
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := params[name]
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("invalid value %g for %s", value, name)
+		}
+		limits, ok := paramRanges[name]
+		if !ok {
+			continue
+		}
+		if value < limits.min || (limits.strictMin && value == limits.min) || value > limits.max {
+			if limits.strictMin {
+				return fmt.Errorf("%s must be positive, got %g", name, value)
+			}
+			return fmt.Errorf("%s must be in [%g,%g], got %g", name, limits.min, limits.max, value)
+		}
+	}
+	return nil
+}
+
+// maxSplatWork bounds the cells visited by the splats of discs and gaussian, in
+// number of grids: count alone allows 2^20 splats of the whole grid
+const maxSplatWork = 64
+
+func checkWork(name string, params map[string]float64, radius float64) error {
+	// Returns an error when the splats of name would visit more than maxSplatWork grids
+	var count, cells float64
+	switch name {
+	case "discs":
+		r := param(params, "radius", radius)
+		count = param(params, "count", float64(width*height)/(3*math.Pi*r*r))
+		cells = math.Min((2*r+3)*(2*r+3), float64(width*height))
+	case "gaussian":
+		count = param(params, "count", 8)
+		cells = float64(width * height)
+	default:
+		return nil
+	}
+	if count*cells > maxSplatWork*float64(width*height) {
+		return fmt.Errorf("count %g is too big for a %d x %d grid, the splats would cover it more than %d times", count, width, height, maxSplatWork)
+	}
+	return nil
+}
+
+func param(params map[string]float64, name string, def float64) float64 {
+	// Returns the parameter if it was given, the default value otherwise
+	if v, ok := params[name]; ok {
+		return v
+	}
+	return def
+}
+
+// GenerateInitPixels initialises the worlds with the generator called name.
+// Common parameters : "mono" (1 uses the same pattern for the 3 channels).
+func GenerateInitPixels(name string, params map[string]float64, gridWidth, gridHeight int, kernelRadius float64) ([]uint8, error) {
+	generator, ok := initGenerators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown initial condition %q (available: %v)", name, InitNames())
+	}
+	if err := checkParams(params); err != nil {
+		return nil, fmt.Errorf("initial condition %s: %v", name, err)
+	}
+	width = gridWidth
+	height = gridHeight
+	if err := checkWork(name, params, kernelRadius); err != nil {
+		return nil, fmt.Errorf("initial condition %s: %v", name, err)
+	}
+
+	world1 = make([]float64, height*width)
+	world2 = make([]float64, height*width)
+	world3 = make([]float64, height*width)
+
+	generator(world1, params, kernelRadius)
+	if param(params, "mono", 0) != 0 {
+		copy(world2, world1)
+		copy(world3, world1)
+	} else {
+		generator(world2, params, kernelRadius)
+		generator(world3, params, kernelRadius)
+	}
+
+	pixels := make([]uint8, height*width*3)
+	for i := range world1 {
+		world1[i] = clamp(world1[i], 0, 1)
+		world2[i] = clamp(world2[i], 0, 1)
+		world3[i] = clamp(world3[i], 0, 1)
+		pixels[i*3] = uint8(255 * world1[i])
+		pixels[i*3+1] = uint8(255 * world2[i])
+		pixels[i*3+2] = uint8(255 * world3[i])
+	}
+
+	convolutions = nil
+	generateKernels(kernelRadius)
+	return pixels, nil
+}
+
+func wrappedDistance(ax, ay, bx, by float64) float64 {
+	// Distance between two points on the torus
+	dx := math.Abs(ax - bx)
+	dy := math.Abs(ay - by)
+	dx = math.Min(dx, float64(width)-dx)
+	dy = math.Min(dy, float64(height)-dy)
+	return math.Sqrt(dx*dx + dy*dy)
+}
+
+func noiseInit(world []float64, params map[string]float64, radius float64) {
+	// Uniform noise, what -r does. "threshold" is the probability for a cell to be filled
+	threshold := param(params, "threshold", 1)
+	for i := range world {
+		if rng.Float64() < threshold {
+			world[i] = rng.Float64()
+		}
+	}
+}
+
+func discsInit(world []float64, params map[string]float64, radius float64) {
+	// Random filled discs ("splats") as in the smoothlife paper.
+	// "radius" (default ra), "count" (default fills about a third of the grid), "value"
+	r := param(params, "radius", radius)
+	count := int(param(params, "count", float64(width*height)/(3*math.Pi*r*r)))
+	value := param(params, "value", 1)
+
+	for n := 0; n < count; n++ {
+		cx := rng.Float64() * float64(width)
+		cy := rng.Float64() * float64(height)
+		// a disc larger than the grid visits each cell once, at its distance on the torus
+		x0, x1 := int(cx-r-1), int(cx+r+1)
+		y0, y1 := int(cy-r-1), int(cy+r+1)
+		wrapped := x1-x0 >= width || y1-y0 >= height
+		if wrapped {
+			x0, x1, y0, y1 = 0, width-1, 0, height-1
+		}
+		for y := y0; y <= y1; y++ {
+			for x := x0; x <= x1; x++ {
+				dist := math.Hypot(float64(x)-cx, float64(y)-cy)
+				if wrapped {
+					dist = wrappedDistance(float64(x), float64(y), cx, cy)
+				}
+				// one pixel wide antialiasing on the border
+				cover := clamp(r-dist+0.5, 0, 1)
+				if cover > 0 {
+					index := modulo(y, height)*width + modulo(x, width)
+					world[index] = math.Max(world[index], value*cover)
+				}
+			}
+		}
+	}
+}
+
+func gaussianInit(world []float64, params map[string]float64, radius float64) {
+	// Sum of gaussian blobs. "count" (default 8), "sigma" (default ra/2), "amplitude"
+	count := int(param(params, "count", 8))
+	sigma := param(params, "sigma", radius/2)
+	amplitude := param(params, "amplitude", 1)
+
+	for n := 0; n < count; n++ {
+		cx := rng.Float64() * float64(width)
+		cy := rng.Float64() * float64(height)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				dist := wrappedDistance(float64(x), float64(y), cx, cy)
+				world[y*width+x] += amplitude * math.Exp(-0.5*dist*dist/(sigma*sigma))
+			}
+		}
+	}
+}
+
+func stripesInit(world []float64, params map[string]float64, radius float64) {
+	// Smooth parallel stripes. "period" (default 2*ra), "angle" in degrees, "sharp" (1 for binary stripes)
+	period := param(params, "period", 2*radius)
+	angle := param(params, "angle", 0) * math.Pi / 180
+	sharp := param(params, "sharp", 0) != 0
+	phase := rng.Float64() * 2 * math.Pi
+	cos, sin := math.Cos(angle), math.Sin(angle)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := 0.5 + 0.5*math.Sin(2*math.Pi*(float64(x)*cos+float64(y)*sin)/period+phase)
+			if sharp {
+				v = math.Round(v)
+			}
+			world[y*width+x] = v
+		}
+	}
+}
+
+func ringsInit(world []float64, params map[string]float64, radius float64) {
+	// Concentric rings around the center. "period" (default 2*ra), "sharp" (1 for binary rings)
+	period := param(params, "period", 2*radius)
+	sharp := param(params, "sharp", 0) != 0
+	phase := rng.Float64() * 2 * math.Pi
+	cx, cy := float64(width)/2, float64(height)/2
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			dist := math.Hypot(float64(x)-cx, float64(y)-cy)
+			v := 0.5 + 0.5*math.Cos(2*math.Pi*dist/period+phase)
+			if sharp {
+				v = math.Round(v)
+			}
+			world[y*width+x] = v
+		}
+	}
+}
+
+// latticeNoise returns a noise function with about one lattice point every scale cells,
+// at least one cell so the lattice is never larger than the grid.
+// The lattice wraps around the grid so the noise is continuous on the torus.
+// With gradient it is Perlin noise, otherwise value noise.
+func latticeNoise(scale float64, gradient bool) func(x, y float64) float64 {
+	scale = math.Max(scale, 1)
+	cols := int(math.Max(1, math.Round(float64(width)/scale)))
+	rows := int(math.Max(1, math.Round(float64(height)/scale)))
+	cellW := float64(width) / float64(cols)
+	cellH := float64(height) / float64(rows)
+
+	values := make([]float64, cols*rows)
+	angles := make([]float64, cols*rows)
+	for i := range values {
+		values[i] = rng.Float64()
+		angles[i] = rng.Float64() * 2 * math.Pi
+	}
+
+	fade := func(t float64) float64 { return t * t * t * (t*(t*6-15) + 10) }
+	corner := func(i, j int, fx, fy float64) float64 {
+		index := modulo(j, rows)*cols + modulo(i, cols)
+		if gradient {
+			// dot product between the corner gradient and the offset, mapped to [0,1]
+			return 0.5 + 0.7*(math.Cos(angles[index])*fx+math.Sin(angles[index])*fy)
+		}
+		return values[index]
+	}
+
+	return func(x, y float64) float64 {
+		gx, gy := x/cellW, y/cellH
+		i, j := int(math.Floor(gx)), int(math.Floor(gy))
+		fx, fy := gx-float64(i), gy-float64(j)
+		u, v := fade(fx), fade(fy)
+
+		top := corner(i, j, fx, fy)*(1-u) + corner(i+1, j, fx-1, fy)*u
+		bottom := corner(i, j+1, fx, fy-1)*(1-u) + corner(i+1, j+1, fx-1, fy-1)*u
+		return top*(1-v) + bottom*v
+	}
+}
+
+func latticeInit(world []float64, params map[string]float64, radius float64, gradient bool) {
+	// Value or Perlin noise. "scale" in cells (default 2*ra), "octaves" (default 1),
+	// "threshold" (cells under it are set to 0, default 0)
+	scale := param(params, "scale", 2*radius)
+	octaves := int(param(params, "octaves", 1))
+	threshold := param(params, "threshold", 0)
+
+	// the octaves finer than a cell would all be the one of a cell
+	var noises []func(x, y float64) float64
+	for o := 0; o < octaves && (o == 0 || scale/math.Pow(2, float64(o)) >= 1); o++ {
+		noises = append(noises, latticeNoise(scale/math.Pow(2, float64(o)), gradient))
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v, amplitude, total := 0.0, 1.0, 0.0
+			for _, noise := range noises {
+				v += amplitude * noise(float64(x), float64(y))
+				total += amplitude
+				amplitude /= 2
+			}
+			v /= total
+			if v < threshold {
+				v = 0
+			}
+			world[y*width+x] = v
+		}
+	}
+}
+
+func valueInit(world []float64, params map[string]float64, radius float64) {
+	latticeInit(world, params, radius, false)
+}
+
+func perlinInit(world []float64, params map[string]float64, radius float64) {
+	latticeInit(world, params, radius, true)
+}
+
+func symmetricInit(world []float64, params map[string]float64, radius float64) {
+	// Kaleidoscope : value noise folded around the center with "order" mirror axes (default 6).
+	// "scale" (default ra), "threshold" (default 0.5, cells under it are set to 0)
+	order := math.Max(1, param(params, "order", 6))
+	scale := param(params, "scale", radius)
+	threshold := param(params, "threshold", 0.5)
+	noise := latticeNoise(scale, false)
+	wedge := math.Pi / order
+	cx, cy := float64(width)/2, float64(height)/2
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			dx, dy := float64(x)-cx, float64(y)-cy
+			r := math.Hypot(dx, dy)
+			theta := math.Mod(math.Atan2(dy, dx)+2*math.Pi, 2*wedge)
+			if theta > wedge {
+				theta = 2*wedge - theta // mirror inside the wedge
+			}
+			v := noise(cx+r*math.Cos(theta), cy+r*math.Sin(theta))
+			if v < threshold {
+				v = 0
+			}
+			world[y*width+x] = v
+		}
+	}
+}
+
+func modulo(a, b int) int {
+	// Used to wrap values arround the grid
+	return (a%b + b) % b
+}
@@ -8,6 +8,9 @@ import (
   "main/fft" 
   "time"
   "fmt"
+  "strings"
+
+  "main/boundary"
 )
 
 var (
@@ -23,12 +26,25 @@ var (
 	d1 float64 = 0.267
 	d2 float64 = 0.445
 
+  rng = rand.New(rand.NewSource(1)) // random source of this engine, see SetSeed
+
   world1, world2, world3 []float64 // world as floats
   worldFFT1, worldFFT2, worldFFT3 []complex128 // worlds in the frequency domain
 
+  ra float64 = 11 // radius of the outer kernel
+  innerRatio = 1.0 / 3 // radius of the inner kernel relative to ra
   bigKernelFFT []complex128
   smallKernelFFT []complex128
 
+  convolutions [][]float64 // outputs of the last convolutions, kept so they can be exported
+
+  LastTimings Timings // duration of each phase of the last UpdateGrid
+
+  edges boundary.Boundary // what the kernels see beyond the edges, see SetBoundary
+
+  // names of the fields that can be exported with Field
+  FieldNames = []string{"world1", "world2", "world3", "outer1", "inner1", "outer2", "inner2", "outer3", "inner3"}
+
   // used to be able to compute multiple convolutions at the same time
   kernelFunctions = []func() []float64{
     func() []float64 {return outerKernel(worldFFT1)},
@@ -93,10 +109,62 @@ func fftConvolve(worldFFT , kernelFFT []complex128, threads int) []float64 {
 
   wg.Wait() // wait for every goroutine to end
 
-	result := (fft.IFFT(resultFFT, height*width)) // Back to the time domain
+  fftWidth, fftHeight := fftSize()
+	result := (fft.IFFT(resultFFT, fftHeight*fftWidth)) // Back to the time domain
+  if edges.Mode != boundary.Torus {
+    result = crop(result)
+  }
 	return result
 }
 
+func fftSize() (int, int) {
+  // Returns the size of the grids given to the FFT: the world for the torus, the
+  // padded world twice as large in each direction otherwise (see pad)
+  if edges.Mode == boundary.Torus {
+    return width, height
+  }
+  return 2*width, 2*height
+}
+
+func pad(world []float64) []float64 {
+  // The FFT convolution is circular, it wraps around the grid. For the other
+  // boundaries the world is padded to twice its size in each direction and the
+  // padding holds the cells seen beyond the edges, so the convolution wraps around
+  // the padding and never reaches the other side of the world. The worlds are
+  // convolved as flat arrays: the cell (x,y), out of the world or not, is at
+  // y*2*width+x modulo the size. The torus needs no padding, world is returned
+  if edges.Mode == boundary.Torus {
+    return world
+  }
+  padWidth, padHeight := fftSize()
+  size := padWidth*padHeight
+  padded := make([]float64, size)
+  for y := -height/2; y < height+height/2; y++ {
+    iy, insideY := edges.Index(y, height)
+    for x := -width/2; x < width+width/2; x++ {
+      ix, insideX := edges.Index(x, width)
+      value := 0.0
+      if insideX && insideY {
+        value = world[iy*width+ix]
+      } else if edges.Mode == boundary.Fixed {
+        value = edges.Value
+      }
+      padded[((y*padWidth+x)%size+size)%size] = value
+    }
+  }
+  return padded
+}
+
+func crop(result []float64) []float64 {
+  // Returns the cells of the world from the result of a padded convolution
+  padWidth, _ := fftSize()
+  cropped := make([]float64, width*height)
+  for y := 0; y < height; y++ {
+    copy(cropped[y*width:(y+1)*width], result[y*padWidth:y*padWidth+width])
+  }
+  return cropped
+}
+
 func innerKernel(worldFFT []complex128) []float64 {
   // This is just an alias function
 	return fftConvolve(worldFFT, smallKernelFFT, 2)
@@ -110,6 +178,9 @@ func outerKernel(worldFFT []complex128) []float64 {
 func generateKernelFFT(radius float64, skipCenter bool) []complex128 {
   // Generates a grid the same size as world with a "smooth circle" in the center. 
   // Then it translates it in the frequency domain since we never need it in the "time" domain 
+  if edges.Mode != boundary.Torus {
+    return fft.FFT(paddedKernel(radius, skipCenter))
+  }
 	kernel := make([]float64, height*width)
 	centerX, centerY := width/2, height/2
   sum := 0.0 
@@ -139,6 +210,133 @@ func generateKernelFFT(radius float64, skipCenter bool) []complex128 {
 	return kernelFFT
 }
 
+func paddedKernel(radius float64, skipCenter bool) []float64 {
+  // Kernel of generateKernelFFT for the padded worlds (see pad). It is centred on the
+  // first cell so the convolution of a cell reads the cells around it and not the
+  // padding, and it is cut at half the size of the world
+  padWidth, padHeight := fftSize()
+  size := padWidth*padHeight
+  kernel := make([]float64, size)
+  sum := 0.0
+  for dy := -height/2; dy < height/2; dy++ {
+    for dx := -width/2; dx < width/2; dx++ {
+      dist := math.Sqrt(float64(dx*dx + dy*dy))
+      if dist <= radius {
+        index := ((dy*padWidth+dx)%size + size) % size
+        kernel[index] = math.Exp(-0.5 * (dist * dist) / (radius * radius))
+        sum += kernel[index]
+      }
+    }
+  }
+
+  if skipCenter {kernel[0] = 0.0}
+
+  if sum != 0 {
+    for i := range kernel {
+      kernel[i] /= sum
+    }
+  }
+  return kernel
+}
+
+// Timings are the durations of the phases of an update
+type Timings struct {
+  Precomputation, Convolutions, NewState, Copy time.Duration
+}
+
+// Rules are the parameters of the transition function
+type Rules struct {
+  Alpha float64 `json:"alpha"`
+  Dt    float64 `json:"dt"`
+  B1    float64 `json:"b1"`
+  B2    float64 `json:"b2"`
+  D1    float64 `json:"d1"`
+  D2    float64 `json:"d2"`
+}
+
+// RuleNames are the names of the rules accepted by Rules.Set.
+var RuleNames = []string{"alpha", "dt", "b1", "b2", "d1", "d2"}
+
+func (r *Rules) Set(name string, value float64) error {
+  // Changes the rule called name
+  fields := map[string]*float64{"alpha": &r.Alpha, "dt": &r.Dt, "b1": &r.B1, "b2": &r.B2, "d1": &r.D1, "d2": &r.D2}
+  field, ok := fields[name]
+  if !ok {
+    return fmt.Errorf("unknown rule %q (available: %s)", name, strings.Join(RuleNames, ", "))
+  }
+  if (name == "alpha" || name == "dt") && value <= 0 {
+    return fmt.Errorf("%s must be positive", name)
+  }
+  *field = value
+  return nil
+}
+
+func (r Rules) Values() map[string]float64 {
+  // Returns the rules by name, see RuleNames
+  return map[string]float64{"alpha": r.Alpha, "dt": r.Dt, "b1": r.B1, "b2": r.B2, "d1": r.D1, "d2": r.D2}
+}
+
+func GetRules() Rules {
+  return Rules{Alpha: alpha, Dt: dt, B1: b1, B2: b2, D1: d1, D2: d2}
+}
+
+func SetRules(rules Rules) {
+  alpha, dt, b1, b2, d1, d2 = rules.Alpha, rules.Dt, rules.B1, rules.B2, rules.D1, rules.D2
+}
+
+func generateKernels(radius float64) {
+  // Generates both kernels for an outer radius
+  ra = radius
+  bigKernelFFT = generateKernelFFT(radius, false)
+  smallKernelFFT = generateKernelFFT(radius*innerRatio, true)
+}
+
+func Dt() float64 {
+  return dt
+}
+
+func SetDt(value float64) {
+  // Changes the time step, the grid is not modified
+  dt = value
+}
+
+func Radius() float64 {
+  return ra
+}
+
+func SetRadius(radius float64) {
+  // Changes the kernel radius, the kernels have to be generated again
+  generateKernels(radius)
+}
+
+func InnerRatio() float64 {
+  return innerRatio
+}
+
+func SetInnerRatio(ratio float64) {
+  // Changes the radius of the inner kernel relative to the outer one, it is used
+  // by the next initialisation or SetRadius
+  innerRatio = ratio
+}
+
+func GetBoundary() boundary.Boundary {
+  return edges
+}
+
+func SetBoundary(b boundary.Boundary) {
+  // Changes the boundary, used from the next update. The kernels are generated
+  // again when there is a grid since their size depends on it (see pad)
+  edges = b
+  if world1 != nil {
+    generateKernels(ra)
+  }
+}
+
+func SetSeed(seed int64) {
+  // Reseeds the random source used by the generators so a run can be reproduced
+  rng = rand.New(rand.NewSource(seed))
+}
+
 // genere des pixels avec une couleur random
 func GenerateRandomPixels(grid_width, grid_height int, kernelRadius float64, threshold float32) []uint8 {
   // Init function when we run the -r flag
@@ -153,12 +351,12 @@ func GenerateRandomPixels(grid_width, grid_height int, kernelRadius float64, thr
   for y := range height {
 
     for x := range width {
-      if rand.Float32() < threshold {
+      if rng.Float32() < threshold {
         // Generates a random grid
         index := y*height+x
-        world1[index] = rand.Float64()
-        world2[index] = rand.Float64()
-        world3[index] = rand.Float64()
+        world1[index] = rng.Float64()
+        world2[index] = rng.Float64()
+        world3[index] = rng.Float64()
 
         nestedPixels[index*3] = uint8(255 * world1[index])
         nestedPixels[index*3+1] = uint8(255 * world2[index])
@@ -170,15 +368,16 @@ func GenerateRandomPixels(grid_width, grid_height int, kernelRadius float64, thr
       }
 		}
 	}
+  convolutions = nil
   // Generates our kernels
-  bigKernelFFT = generateKernelFFT(kernelRadius, false)
-  smallKernelFFT = generateKernelFFT(kernelRadius/3, true)
+  generateKernels(kernelRadius)
 	return nestedPixels
 }
 
-func LoadImagePixels(pixels []uint8, gridWidth, gridHeight int, kernelRadius float64) []uint8 {
-	// Init funciton when we run the -i flag
-  width = gridWidth
+func LoadImageFields(r, g, b []float64, gridWidth, gridHeight int, kernelRadius float64) []uint8 {
+	// Init function when the image was already converted to floats (see image_utils),
+	// the values are used as they are so no precision is lost
+	width = gridWidth
 	height = gridHeight
 
 	world1 = make([]float64, gridWidth*gridHeight)
@@ -186,22 +385,17 @@ func LoadImagePixels(pixels []uint8, gridWidth, gridHeight int, kernelRadius flo
 	world3 = make([]float64, gridWidth*gridHeight)
 	nestedPixels := make([]uint8, gridWidth*gridHeight*3)
 
-	for y := 0; y < gridHeight; y++ {
-		for x := 0; x < gridWidth; x++ {
-			index := y*gridWidth + x
-			// Copy the image pixel and normalize to [0,1] for the simulation state.
-			nestedPixels[index*3] = pixels[index*3]
-			nestedPixels[index*3+1] = pixels[index*3+1]
-			nestedPixels[index*3+2] = pixels[index*3+2]
-			world1[index] = float64(pixels[index*3]) / 255.0
-			world2[index] = float64(pixels[index*3+1]) / 255.0
-			world3[index] = float64(pixels[index*3+2]) / 255.0
-		}
+	for index := range world1 {
+		world1[index] = clamp(r[index], 0, 1)
+		world2[index] = clamp(g[index], 0, 1)
+		world3[index] = clamp(b[index], 0, 1)
+		nestedPixels[index*3] = uint8(255 * world1[index])
+		nestedPixels[index*3+1] = uint8(255 * world2[index])
+		nestedPixels[index*3+2] = uint8(255 * world3[index])
 	}
 
-	// Initialize the kernel FFTs as in the random generator.
-	bigKernelFFT = generateKernelFFT(kernelRadius, false)
-	smallKernelFFT = generateKernelFFT(kernelRadius/3, true)
+	convolutions = nil
+	generateKernels(kernelRadius)
 	return nestedPixels
 }
 
@@ -215,13 +409,13 @@ func UpdateGrid(pixels []uint8) []uint8 {
   newWorld3 := make([]float64, len(world1))
 
   // Precomputing our current worlds in the frequency domain
-  worldFFT1 = fft.FFT(world1)
-  worldFFT2 = fft.FFT(world2)
-  worldFFT3 = fft.FFT(world3)
-  fmt.Println("Precomputation took ", time.Since(t))
+  worldFFT1 = fft.FFT(pad(world1))
+  worldFFT2 = fft.FFT(pad(world2))
+  worldFFT3 = fft.FFT(pad(world3))
+  LastTimings.Precomputation = time.Since(t)
 
   t = time.Now()
-  convolutions := make([][]float64, 6) // We have 6 convolutions in total : 2 for each RGB Channel
+  convolutions = make([][]float64, 6) // We have 6 convolutions in total : 2 for each RGB Channel
 
   for i, fun := range kernelFunctions {
     // One goroutine per convolution
@@ -233,7 +427,7 @@ func UpdateGrid(pixels []uint8) []uint8 {
   }
 
   wg.Wait()
-  fmt.Println("Convolutions took ", time.Since(t))
+  LastTimings.Convolutions = time.Since(t)
  
   thread := runtime.NumCPU()*2
   linesPerThread := height/thread
@@ -269,14 +463,85 @@ func UpdateGrid(pixels []uint8) []uint8 {
 		}(startLine, endLine)
 	}
 	wg.Wait()
-  fmt.Println("Calculation new state took ", time.Since(t))
+  LastTimings.NewState = time.Since(t)
 
   t = time.Now()
   // Copying newWord to world so we "update" the world
   copy(world1, newWorld1)
   copy(world2, newWorld2)
   copy(world3, newWorld3)
-  fmt.Println("Copying took ", time.Since(t))
+  LastTimings.Copy = time.Since(t)
 
 	return pixels // returning the pixels for OpenGL
 }
+
+func Size() (int, int) {
+  // Returns the width and height of the current grid
+  return width, height
+}
+
+func Means() [3]float64 {
+  // Returns the mean value of each world, the share of the grid that is alive
+  var sums [3]float64
+  for index := range world1 {
+    sums[0] += world1[index]
+    sums[1] += world2[index]
+    sums[2] += world3[index]
+  }
+  if len(world1) > 0 {
+    for c := range sums {
+      sums[c] /= float64(len(world1))
+    }
+  }
+  return sums
+}
+
+func Field(name string) ([]float64, error) {
+  // Returns one of the float fields of the simulation (see FieldNames), without quantisation.
+  // The convolution outputs (outerN/innerN) are the ones used for the last update.
+  switch name {
+  case "world1":
+    return world1, nil
+  case "world2":
+    return world2, nil
+  case "world3":
+    return world3, nil
+  }
+  for i, fieldName := range FieldNames[3:] {
+    if fieldName == name {
+      if convolutions == nil || convolutions[i] == nil {
+        return nil, fmt.Errorf("field %s is not available before the first update", name)
+      }
+      return convolutions[i], nil
+    }
+  }
+  return nil, fmt.Errorf("unknown field %s", name)
+}
+
+func RenderPixels(pixels []uint8) []uint8 {
+	// Writes the worlds in pixels, needed when the worlds were changed outside of UpdateGrid.
+	// pixels is reused when it has the right size
+	if len(pixels) != len(world1)*3 {
+		pixels = make([]uint8, len(world1)*3)
+	}
+	for index := range world1 {
+		pixels[index*3] = uint8(255 * world1[index])
+		pixels[index*3+1] = uint8(255 * world2[index])
+		pixels[index*3+2] = uint8(255 * world3[index])
+	}
+	return pixels
+}
+
+func FloatPixels(values []float32) []float32 {
+	// Writes the worlds as interleaved R,G,B floats, the format of opengl_utils.UpdateFloatTexture.
+	// values is reused when it has the right size
+	if len(values) != len(world1)*3 {
+		values = make([]float32, len(world1)*3)
+	}
+	for index := range world1 {
+		values[index*3] = float32(world1[index])
+		values[index*3+1] = float32(world2[index])
+		values[index*3+2] = float32(world3[index])
+	}
+	return values
+}
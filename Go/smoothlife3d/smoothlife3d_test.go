@@ -0,0 +1,342 @@
+package smoothlife3d
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"main/boundary"
+)
+
+// withBoundary sets the boundary b for the duration of the test.
+func withBoundary(t *testing.T, b boundary.Boundary) {
+	before := GetBoundary()
+	SetBoundary(b)
+	t.Cleanup(func() { SetBoundary(before) })
+}
+
+// convolved loads values in the three worlds, updates them once and returns the
+// outer convolution of world1 used by the update.
+func convolved(t *testing.T, values []float64, size int, radius float64) []float64 {
+	t.Helper()
+	LoadImageFields(values, values, values, size, size, radius)
+	UpdateGrid(make([]uint8, size*size*3))
+	outer, err := Field("outer1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return outer
+}
+
+// TestPaddedConvolutionMatchesDirect checks the FFT convolution of the padded
+// worlds against the sum over the kernel, with the cells beyond the edges given by
+// the boundary.
+func TestPaddedConvolutionMatchesDirect(t *testing.T) {
+	const size, radius = 32, 5.0
+	rng := rand.New(rand.NewSource(1))
+	values := make([]float64, size*size)
+	for i := range values {
+		values[i] = rng.Float64()
+	}
+	for _, b := range []boundary.Boundary{{Mode: boundary.Dead}, {Mode: boundary.Reflect}, {Mode: boundary.Fixed, Value: 0.3}} {
+		t.Run(b.String(), func(t *testing.T) {
+			withBoundary(t, b)
+			outer := convolved(t, values, size, radius)
+			at := func(x, y int) float64 {
+				x, insideX := b.Index(x, size)
+				y, insideY := b.Index(y, size)
+				if insideX && insideY {
+					return values[y*size+x]
+				}
+				if b.Mode == boundary.Fixed {
+					return b.Value
+				}
+				return 0
+			}
+			for y := 0; y < size; y++ {
+				for x := 0; x < size; x++ {
+					sum, total := 0.0, 0.0
+					for dy := -int(radius); dy <= int(radius); dy++ {
+						for dx := -int(radius); dx <= int(radius); dx++ {
+							dist := math.Sqrt(float64(dx*dx + dy*dy))
+							if dist <= radius {
+								weight := math.Exp(-0.5 * dist * dist / (radius * radius))
+								sum += weight * at(x+dx, y+dy)
+								total += weight
+							}
+						}
+					}
+					if want := sum / total; math.Abs(outer[y*size+x]-want) > 1e-9 {
+						t.Fatalf("cell (%d,%d): %g, want %g", x, y, outer[y*size+x], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestDeadBoundaryDoesNotWrap checks that the cells on an edge are not seen from
+// the opposite edge, which the circular FFT of the torus does.
+func TestDeadBoundaryDoesNotWrap(t *testing.T) {
+	const size, radius = 32, 5.0
+	values := make([]float64, size*size)
+	for y := 0; y < size; y++ {
+		values[y*size] = 1 // the first column
+	}
+	for x := 0; x < size; x++ {
+		values[x] = 1 // the first line
+	}
+	withBoundary(t, boundary.Boundary{Mode: boundary.Dead})
+	outer := convolved(t, values, size, radius)
+	// the cells further than the radius from the first line and column, the last
+	// ones included, only see dead cells
+	for y := int(radius) + 1; y < size; y++ {
+		for x := int(radius) + 1; x < size; x++ {
+			if math.Abs(outer[y*size+x]) > 1e-12 {
+				t.Errorf("cell (%d,%d) sees the opposite edge: %g", x, y, outer[y*size+x])
+			}
+		}
+	}
+	if outer[size+1] < 0.1 {
+		t.Errorf("cell (1,1) next to the edges: %g, want the edges in its neighbourhood", outer[size+1])
+	}
+}
+
+// TestGeneratorParams checks that the parameters out of their range are rejected,
+// that the extreme valid ones do not blow up the memory or the time, and that the
+// values of every generator stay in [0,1].
+func TestGeneratorParams(t *testing.T) {
+	for _, test := range []struct {
+		generator string
+		params    map[string]float64
+	}{
+		{"perlin", map[string]float64{"octaves": 0}},
+		{"value", map[string]float64{"octaves": -1}},
+		{"perlin", map[string]float64{"octaves": 17}},
+		{"discs", map[string]float64{"radius": 0}},
+		{"discs", map[string]float64{"count": -1}},
+		{"discs", map[string]float64{"count": 1 << 20}},
+		{"discs", map[string]float64{"count": 1 << 20, "radius": 1e9}},
+		{"gaussian", map[string]float64{"count": 1 << 20}},
+		{"gaussian", map[string]float64{"sigma": 0}},
+		{"stripes", map[string]float64{"period": 0}},
+		{"perlin", map[string]float64{"scale": math.Inf(1)}},
+		{"noise", map[string]float64{"threshold": math.NaN()}},
+	} {
+		if _, err := GenerateInitPixels(test.generator, test.params, 32, 32, 5); err == nil {
+			t.Errorf("%s with %v: no error", test.generator, test.params)
+		}
+	}
+
+	// the octaves and the scales finer than a cell give lattices of at most a cell each
+	for _, test := range []struct {
+		generator string
+		params    map[string]float64
+	}{
+		{"perlin", map[string]float64{"octaves": 16}},
+		{"value", map[string]float64{"octaves": 16, "scale": 1e-6}},
+		{"perlin", map[string]float64{"scale": 1e-6}},
+		{"symmetric", map[string]float64{"scale": 1e-6}},
+		{"discs", map[string]float64{"count": 60, "radius": 1e9}},
+	} {
+		if _, err := GenerateInitPixels(test.generator, test.params, 64, 64, 11); err != nil {
+			t.Errorf("%s with %v: %v", test.generator, test.params, err)
+		}
+	}
+
+	for _, name := range InitNames() {
+		if _, err := GenerateInitPixels(name, map[string]float64{"octaves": 3, "count": 4}, 32, 32, 5); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, world := range [][]float64{world1, world2, world3} {
+			for i, v := range world {
+				if math.IsNaN(v) || v < 0 || v > 1 {
+					t.Fatalf("%s: cell %d is %g", name, i, v)
+				}
+			}
+		}
+	}
+}
+
+// generated returns world1 of the generator name on a size x size grid with the
+// same pattern in the 3 worlds.
+func generated(t *testing.T, name string, params map[string]float64, size int) []float64 {
+	t.Helper()
+	params["mono"] = 1
+	if _, err := GenerateInitPixels(name, params, size, size, 11); err != nil {
+		t.Fatal(err)
+	}
+	return world1
+}
+
+// TestGeneratorStructures checks the shape of each family of generators.
+func TestGeneratorStructures(t *testing.T) {
+	const size = 64
+	at := func(world []float64, x, y int) float64 {
+		return world[modulo(y, size)*size+modulo(x, size)]
+	}
+	sum := func(world []float64) float64 {
+		total := 0.0
+		for _, v := range world {
+			total += v
+		}
+		return total
+	}
+
+	t.Run("noise", func(t *testing.T) {
+		if total := sum(generated(t, "noise", map[string]float64{"threshold": 0}, size)); total != 0 {
+			t.Errorf("threshold 0: sum %g, want an empty grid", total)
+		}
+		if mean := sum(generated(t, "noise", map[string]float64{}, size)) / (size * size); math.Abs(mean-0.5) > 0.05 {
+			t.Errorf("mean %g, want about 0.5", mean)
+		}
+	})
+
+	t.Run("discs", func(t *testing.T) {
+		// a single disc of radius 5 covers pi r^2 cells, its border antialiased
+		world := generated(t, "discs", map[string]float64{"count": 1, "radius": 5}, size)
+		if area := sum(world); math.Abs(area-math.Pi*25) > 2 {
+			t.Errorf("area %g, want about %g", area, math.Pi*25)
+		}
+		// the default radius is ra
+		world = generated(t, "discs", map[string]float64{"count": 1}, size)
+		if area := sum(world); math.Abs(area-math.Pi*11*11) > 4 {
+			t.Errorf("area %g with the default radius, want about %g", area, math.Pi*11*11)
+		}
+	})
+
+	t.Run("gaussian", func(t *testing.T) {
+		// a blob of sigma 4 sums to 2 pi sigma^2 and peaks near 1
+		world := generated(t, "gaussian", map[string]float64{"count": 1, "sigma": 4}, size)
+		if total := sum(world); math.Abs(total-2*math.Pi*16) > 1 {
+			t.Errorf("sum %g, want about %g", total, 2*math.Pi*16)
+		}
+		peak := 0.0
+		for _, v := range world {
+			peak = math.Max(peak, v)
+		}
+		if peak < 0.95 {
+			t.Errorf("peak %g, want about 1", peak)
+		}
+	})
+
+	t.Run("stripes", func(t *testing.T) {
+		// vertical stripes of period 8: the same on each line and every 8 columns
+		world := generated(t, "stripes", map[string]float64{"period": 8}, size)
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				if math.Abs(at(world, x, y)-at(world, x+8, y)) > 1e-9 || math.Abs(at(world, x, y)-at(world, x, 0)) > 1e-9 {
+					t.Fatalf("cell (%d,%d) is not on stripes of period 8", x, y)
+				}
+			}
+		}
+		if math.Abs(at(world, 0, 0)-at(world, 4, 0)) < 1e-3 && math.Abs(at(world, 0, 0)-at(world, 2, 0)) < 1e-3 {
+			t.Errorf("no stripe: the first line is constant")
+		}
+	})
+
+	t.Run("rings", func(t *testing.T) {
+		// the value only depends on the distance to the center
+		world := generated(t, "rings", map[string]float64{"period": 8}, size)
+		c := size / 2
+		for d := 0; d < c; d++ {
+			v := at(world, c+d, c)
+			for _, other := range []float64{at(world, c-d, c), at(world, c, c+d), at(world, c, c-d)} {
+				if math.Abs(other-v) > 1e-9 {
+					t.Fatalf("distance %d: %g and %g", d, v, other)
+				}
+			}
+		}
+		// and repeats every period
+		if math.Abs(at(world, c+3, c)-at(world, c+11, c)) > 1e-9 {
+			t.Errorf("rings not of period 8")
+		}
+	})
+
+	for _, name := range []string{"value", "perlin"} {
+		t.Run(name, func(t *testing.T) {
+			// a noise of scale 16 is smooth, also across the edges of the torus
+			world := generated(t, name, map[string]float64{"scale": 16}, size)
+			low, high := 1.0, 0.0
+			for y := 0; y < size; y++ {
+				for x := 0; x < size; x++ {
+					v := at(world, x, y)
+					low, high = math.Min(low, v), math.Max(high, v)
+					if math.Abs(v-at(world, x+1, y)) > 0.2 || math.Abs(v-at(world, x, y+1)) > 0.2 {
+						t.Fatalf("jump between (%d,%d) and its neighbours", x, y)
+					}
+				}
+			}
+			if high-low < 0.2 {
+				t.Errorf("values in [%g,%g], want a noise", low, high)
+			}
+		})
+	}
+
+	t.Run("symmetric", func(t *testing.T) {
+		// mirrored on the horizontal axis through the center
+		world := generated(t, "symmetric", map[string]float64{"threshold": 0, "order": 4}, size)
+		c := size / 2
+		for dy := 1; dy < c; dy++ {
+			for dx := -c; dx < c; dx++ {
+				if math.Abs(at(world, c+dx, c+dy)-at(world, c+dx, c-dy)) > 1e-9 {
+					t.Fatalf("(%d,%d) and (%d,%d) differ", c+dx, c+dy, c+dx, c-dy)
+				}
+			}
+		}
+	})
+}
+
+// TestSeedReplays checks that two runs with the same seed are bit identical after
+// some steps, from a random grid and from the generators drawing random numbers,
+// and that another seed gives other worlds.
+func TestSeedReplays(t *testing.T) {
+	const size, radius, steps = 32, 5.0, 5
+	inits := map[string]func() error{
+		"random": func() error {
+			GenerateRandomPixels(size, size, radius, 0.5)
+			return nil
+		},
+	}
+	for _, name := range []string{"noise", "discs", "perlin", "value"} {
+		inits[name] = func() error {
+			_, err := GenerateInitPixels(name, map[string]float64{"count": 6, "radius": 4}, size, size, radius)
+			return err
+		}
+	}
+	for name, init := range inits {
+		t.Run(name, func(t *testing.T) {
+			run := func(seed int64) [][]float64 {
+				SetSeed(seed)
+				if err := init(); err != nil {
+					t.Fatal(err)
+				}
+				pixels := make([]uint8, size*size*3)
+				for i := 0; i < steps; i++ {
+					pixels = UpdateGrid(pixels)
+				}
+				worlds := [][]float64{world1, world2, world3}
+				for i, world := range worlds {
+					worlds[i] = append([]float64(nil), world...)
+				}
+				return worlds
+			}
+			worlds, again := run(0), run(0)
+			for i := range worlds {
+				for j := range worlds[i] {
+					if math.Float64bits(worlds[i][j]) != math.Float64bits(again[i][j]) {
+						t.Fatalf("world%d, cell %d: %g then %g with the seed 0", i+1, j, worlds[i][j], again[i][j])
+					}
+				}
+			}
+			other := run(1)
+			same := true
+			for j := range worlds[0] {
+				same = same && worlds[0][j] == other[0][j]
+			}
+			if same {
+				t.Errorf("the seeds 0 and 1 give the same world1")
+			}
+		})
+	}
+}